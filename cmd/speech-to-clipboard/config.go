@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"speech-to-clipboard/internal/config"
+	"text/tabwriter"
+)
+
+// configUsage lists the config subcommands
+const configUsage = `Usage: speech-to-clipboard [flags] config <command>
+
+Commands:
+  show              print every setting with its effective value and where it was set
+  path              print the config file in use, or where one would be read from
+  set-key [--file]  save an API key, read from stdin, to a file only you can read
+
+Settings come from, in order of precedence: command-line flags, environment
+variables, the config file and built-in defaults.`
+
+// runConfig implements "config <command>" for inspecting the configuration
+func runConfig(args []string, opts config.Options) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, configUsage)
+		return fmt.Errorf("no config command given")
+	}
+
+	switch args[0] {
+	case "show":
+		cfg, err := config.LoadWithOptions(opts)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		return showConfig(os.Stdout, cfg)
+	case "path":
+		return printConfigPath(os.Stdout, opts)
+	case "set-key":
+		return setKey(args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(configUsage)
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, configUsage)
+	return fmt.Errorf("unknown config command %q", args[0])
+}
+
+// showConfig prints the effective settings as a table, secrets redacted
+func showConfig(w io.Writer, cfg *config.Config) error {
+	if cfg.ConfigFile != "" {
+		fmt.Fprintf(w, "Config file: %s\n\n", cfg.ConfigFile)
+	} else {
+		fmt.Fprintf(w, "Config file: none\n\n")
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range cfg.Settings() {
+		source := string(s.Source)
+		if s.Origin != "" && s.Source != config.SourceFile {
+			source += " (" + s.Origin + ")"
+		}
+		value := s.Value
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, value, source)
+	}
+	return tw.Flush()
+}
+
+// printConfigPath prints the file settings are read from. Without one it
+// prints where a config file would be picked up.
+func printConfigPath(w io.Writer, opts config.Options) error {
+	path, err := config.File(opts)
+	if err != nil {
+		return err
+	}
+	if path != "" {
+		_, err = fmt.Fprintln(w, path)
+		return err
+	}
+
+	dir, err := config.DefaultConfigDir()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "No config file; create %s (or config.yaml or config.json)\n", filepath.Join(dir, "config.toml"))
+	return err
+}
+
+// setKey reads an API key from stdin and saves it where Load finds it
+// without OPENAI_API_KEY. Typed input is hidden when stdin is a terminal.
+func setKey(args []string) error {
+	fs := flag.NewFlagSet("config set-key", flag.ContinueOnError)
+	path := fs.String("file", "", "save the key to `path` and read it with OPENAI_API_KEY_FILE")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		defaultPath, err := config.DefaultKeyFile()
+		if err != nil {
+			return err
+		}
+		*path = defaultPath
+	}
+
+	terminal := isTerminal(os.Stdin)
+	if terminal {
+		fmt.Fprint(os.Stderr, "API key: ")
+		if restore := hideInput(); restore != nil {
+			defer restore()
+		}
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if terminal {
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read API key: %w", err)
+	}
+
+	if err := config.SaveKey(*path, line); err != nil {
+		return err
+	}
+	fmt.Printf("API key saved to %s\n", *path)
+	return nil
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// hideInput turns off terminal echo with stty and returns a function that
+// turns it back on, or nil where stty is unavailable
+func hideInput() func() {
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = os.Stdin
+		return cmd.Run()
+	}
+	if err := stty("-echo"); err != nil {
+		return nil
+	}
+	return func() { stty("echo") }
+}
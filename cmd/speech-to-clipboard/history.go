@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"speech-to-clipboard/internal/config"
+	"speech-to-clipboard/pkg/audio"
+	"speech-to-clipboard/pkg/clipboard"
+	"speech-to-clipboard/pkg/history"
+	"speech-to-clipboard/pkg/pipeline"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// historyUsage lists the history subcommands
+const historyUsage = `Usage: speech-to-clipboard history <command> [args]
+
+Commands:
+  list [-n count]                 show recent recordings, newest first
+  show <id>                       print a recording's details and transcript
+  copy <id>                       copy a transcript to the clipboard
+  retranscribe [--clipboard] <id> transcribe a saved recording again
+
+IDs may be shortened to any unique prefix.`
+
+// runHistory implements "history <command>" for browsing saved recordings
+func runHistory(args []string, opts config.Options) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, historyUsage)
+		return fmt.Errorf("no history command given")
+	}
+
+	dir, err := config.HistoryDir(opts)
+	if err != nil {
+		return err
+	}
+	store, err := history.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "list":
+		return historyList(os.Stdout, store, args)
+	case "show":
+		return historyShow(os.Stdout, store, args)
+	case "copy":
+		return historyCopy(store, args, opts)
+	case "retranscribe":
+		return historyRetranscribe(store, args, opts)
+	case "help", "-h", "--help":
+		fmt.Println(historyUsage)
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, historyUsage)
+	return fmt.Errorf("unknown history command %q", command)
+}
+
+// historyList prints the newest entries as a table
+func historyList(w io.Writer, store *history.Store, args []string) error {
+	fs := flag.NewFlagSet("history list", flag.ContinueOnError)
+	count := fs.Int("n", 20, "show at most `count` recordings; 0 shows all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	entries, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No recordings in history.")
+		return nil
+	}
+	if *count > 0 && len(entries) > *count {
+		entries = entries[:*count]
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTIME\tDURATION\tTEXT")
+	for _, e := range entries {
+		text := e.Text
+		if e.Error != "" {
+			text = "(failed: " + e.Error + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%s\n", e.ID, e.Time.Format(time.DateTime), e.Duration.Round(100*time.Millisecond), summarize(text, 60))
+	}
+	return tw.Flush()
+}
+
+// historyShow prints everything stored about one entry
+func historyShow(w io.Writer, store *history.Store, args []string) error {
+	e, err := historyEntry(store, args)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", e.ID)
+	fmt.Fprintf(tw, "Time:\t%s\n", e.Time.Format(time.DateTime))
+	fmt.Fprintf(tw, "Duration:\t%v\n", e.Duration.Round(100*time.Millisecond))
+	if e.Model != "" {
+		fmt.Fprintf(tw, "Model:\t%s\n", e.Model)
+	}
+	if e.Language != "" {
+		fmt.Fprintf(tw, "Language:\t%s\n", e.Language)
+	}
+	if !e.Retranscribed.IsZero() {
+		fmt.Fprintf(tw, "Retranscribed:\t%s\n", e.Retranscribed.Format(time.DateTime))
+	}
+	if e.Error != "" {
+		fmt.Fprintf(tw, "Error:\t%s\n", e.Error)
+	}
+	fmt.Fprintf(tw, "Audio:\t%s\n", store.AudioPath(e.ID))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "\n%s\n", e.Text)
+	return err
+}
+
+// historyCopy puts an entry's transcript back on the clipboard
+func historyCopy(store *history.Store, args []string, opts config.Options) error {
+	e, err := historyEntry(store, args)
+	if err != nil {
+		return err
+	}
+	if e.Text == "" {
+		return fmt.Errorf("recording %s has no transcript; try history retranscribe", e.ID)
+	}
+
+	clipOpts, err := config.ClipboardOptions(opts)
+	if err != nil {
+		return err
+	}
+	clipMgr, err := clipboard.NewManagerWithOptions(clipOpts)
+	if err != nil {
+		return err
+	}
+	if err := clipMgr.Write(e.Text); err != nil {
+		return err
+	}
+	fmt.Printf("Copied transcript of %s to the clipboard.\n", e.ID)
+	return holdClipboard(clipMgr)
+}
+
+// historyRetranscribe runs a saved recording through the configured backend
+// again and replaces its transcript
+func historyRetranscribe(store *history.Store, args []string, opts config.Options) error {
+	fs := flag.NewFlagSet("history retranscribe", flag.ContinueOnError)
+	toClipboard := fs.Bool("clipboard", false, "copy the new transcript to the clipboard")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	e, err := historyEntry(store, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadWithOptions(opts)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	transcriber, err := newTranscriber(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize transcriber: %w", err)
+	}
+
+	samples, rate, err := store.Audio(e.ID)
+	if err != nil {
+		return err
+	}
+	samples = audio.Convert(samples, audio.Format{SampleRate: rate, Channels: 1}, audio.SampleRate)
+
+	text, err := pipeline.TranscribeRecording(context.Background(), transcriber, samples, newSplitConfig(cfg, newEncoder(cfg)))
+	if err != nil {
+		reportTranscribeError(err)
+		return fmt.Errorf("failed to transcribe %s: %w", e.ID, err)
+	}
+	text = newPostProcessor(cfg).Process(text)
+
+	e.Text = text
+	e.Error = ""
+	e.Model = modelName(cfg)
+	e.Language = cfg.Language
+	e.Retranscribed = time.Now()
+	if err := store.Update(e); err != nil {
+		return err
+	}
+
+	fmt.Println(text)
+	if *toClipboard {
+		clipMgr, err := clipboard.NewManagerWithOptions(cfg.Clipboard)
+		if err != nil {
+			return err
+		}
+		if err := clipMgr.Write(text); err != nil {
+			return err
+		}
+		return holdClipboard(clipMgr)
+	}
+	return nil
+}
+
+// historyEntry looks up the single entry named by args
+func historyEntry(store *history.Store, args []string) (*history.Entry, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected one recording ID, see history list")
+	}
+	return store.Get(args[0])
+}
+
+// summarize shortens text to one line of at most max characters
+func summarize(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > max {
+		return string(runes[:max-3]) + "..."
+	}
+	return text
+}
@@ -1,8 +1,7 @@
 package main
 
 import (
-	"bytes"
-	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -10,23 +9,70 @@ import (
 	"speech-to-clipboard/internal/config"
 	"speech-to-clipboard/pkg/audio"
 	"speech-to-clipboard/pkg/clipboard"
-	"speech-to-clipboard/pkg/stt"
+	"speech-to-clipboard/pkg/history"
+	"speech-to-clipboard/pkg/hotkey"
 	"syscall"
-	"time"
+	"text/tabwriter"
 )
 
 func main() {
+	listDevices := flag.Bool("list-devices", false, "list audio input devices and exit")
+	opts := config.Flags(flag.CommandLine)
+	flag.Parse()
+
+	if args := flag.Args(); len(args) > 0 {
+		switch args[0] {
+		case "transcribe":
+			if err := runTranscribe(args[1:], *opts); err != nil {
+				log.Fatal(err)
+			}
+		case "history":
+			if err := runHistory(args[1:], *opts); err != nil {
+				log.Fatal(err)
+			}
+		case "config":
+			if err := runConfig(args[1:], *opts); err != nil {
+				log.Fatal(err)
+			}
+		default:
+			log.Fatalf("Unknown command %q", args[0])
+		}
+		return
+	}
+
+	if *listDevices {
+		if err := printDevices(); err != nil {
+			log.Fatalf("Failed to list devices: %v", err)
+		}
+		return
+	}
+
 	fmt.Println("Speech-to-Clipboard Application")
 	fmt.Println("================================")
 
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.LoadWithOptions(*opts)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
 	// Initialize components
-	capturer, err := audio.NewCapturer()
+	vadCfg := audio.DefaultVADConfig()
+	vadCfg.EnergyThreshold = cfg.VADThreshold
+
+	overflow, err := audio.ParseOverflowPolicy(cfg.Overflow)
+	if err != nil {
+		log.Fatalf("Invalid overflow policy: %v", err)
+	}
+
+	capturer, err := audio.NewCapturerWithConfig(audio.CaptureConfig{
+		Device:      cfg.AudioDevice,
+		AutoStop:    cfg.AutoStop,
+		VAD:         vadCfg,
+		MaxDuration: cfg.MaxRecording,
+		Overflow:    overflow,
+		Input:       audio.Format{SampleRate: cfg.AudioSampleRate, Channels: cfg.AudioChannels},
+	})
 	if err != nil {
 		log.Fatalf("Failed to initialize audio capturer: %v", err)
 	}
@@ -36,8 +82,52 @@ func main() {
 		}
 	}()
 
-	transcriber := stt.NewWhisperTranscriber(cfg.OpenAIAPIKey)
-	clipMgr := clipboard.NewManager()
+	transcriber, err := newTranscriber(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize transcriber: %v", err)
+	}
+
+	a := &app{
+		cfg:         cfg,
+		capturer:    capturer,
+		transcriber: transcriber,
+		encoder:     newEncoder(cfg),
+		postProcess: newPostProcessor(cfg),
+		enter:       readLines(os.Stdin),
+	}
+	if cfg.HasOutput(config.OutputClipboard) {
+		if a.clipMgr, err = clipboard.NewManagerWithOptions(cfg.Clipboard); err != nil {
+			log.Fatalf("Failed to initialize clipboard: %v", err)
+		}
+		// A pending restore would die with the process
+		defer func() {
+			if err := a.clipMgr.Restore(); err != nil {
+				log.Printf("Error restoring clipboard: %v", err)
+			}
+		}()
+	}
+	if cfg.VAD {
+		a.vad = audio.NewVAD(vadCfg, audio.SampleRate)
+	}
+	if cfg.History {
+		if a.history, err = history.Open(cfg.HistoryDir); err != nil {
+			log.Printf("History disabled: %v", err)
+		}
+	}
+
+	var hk hotkey.Hotkey
+	if cfg.Hotkey != "" {
+		hk, _ = hotkey.Parse(cfg.Hotkey)
+		src, err := hotkey.NewSource(hk)
+		if err != nil {
+			log.Printf("Global hotkey unavailable, using ENTER only: %v", err)
+		} else {
+			defer src.Close()
+			a.hotkeys = src.Events()
+			a.hotkeyMode = cfg.HotkeyMode
+		}
+	}
+	a.sinks = newSinks(cfg, a.clipMgr, a.hotkeys != nil)
 
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -46,12 +136,33 @@ func main() {
 	fmt.Println("\nInstructions:")
 	fmt.Println("- Press ENTER to start recording")
 	fmt.Println("- Press ENTER again to stop recording and transcribe")
+	if a.hotkeys != nil {
+		if a.hotkeyMode == hotkey.PushToTalk {
+			fmt.Printf("- Or hold %s from any window while speaking\n", hk)
+		} else {
+			fmt.Printf("- Or press %s from any window to start and stop\n", hk)
+		}
+	}
+	if cfg.AutoStop > 0 {
+		fmt.Printf("- Recording also stops after %v of silence\n", cfg.AutoStop)
+	}
+	if cfg.Overflow == "stop" {
+		fmt.Printf("- Recordings are limited to %v\n", cfg.MaxRecording)
+	}
+	if a.clipMgr != nil && cfg.Clipboard.HistorySize > 0 {
+		fmt.Println("- Type h and ENTER to list recent transcripts, or a number to copy one again")
+	}
 	fmt.Println("- Press Ctrl+C to exit")
 	fmt.Println()
 
 	go func() {
 		<-sigChan
 		fmt.Println("\n\nShutting down...")
+		if a.clipMgr != nil {
+			if err := a.clipMgr.Restore(); err != nil {
+				log.Printf("Error restoring clipboard: %v", err)
+			}
+		}
 		if capturer.IsRecording() {
 			if err := capturer.Stop(); err != nil {
 				log.Printf("Error stopping capturer: %v", err)
@@ -63,68 +174,44 @@ func main() {
 	// Main loop
 	for {
 		fmt.Print("Press ENTER to start recording: ")
-		_, _ = fmt.Scanln()
-
-		fmt.Println("Recording... Press ENTER to stop")
-		if err := capturer.Start(); err != nil {
-			log.Printf("Error starting recording: %v", err)
-			continue
-		}
-
-		// Wait for user to press ENTER again
-		_, _ = fmt.Scanln()
-
-		fmt.Println("Stopping recording...")
-		if err := capturer.Stop(); err != nil {
-			log.Printf("Error stopping recording: %v", err)
-			continue
-		}
-
-		// Get audio data
-		audioData, err := capturer.GetAudioData()
-		if err != nil {
-			log.Printf("Error getting audio data: %v", err)
-			continue
-		}
-
-		if len(audioData) == 0 {
-			fmt.Println("No audio captured. Please try again.")
-			continue
+		if !a.waitForStart() {
+			fmt.Println()
+			return
 		}
 
-		fmt.Printf("Captured %d samples. Transcribing...\n", len(audioData))
-
-		// Convert to WAV format
-		wavBuf := new(bytes.Buffer)
-		if err := audio.SaveToWAV(audioData, wavBuf); err != nil {
-			log.Printf("Error converting to WAV: %v", err)
-			continue
-		}
-
-		// Transcribe
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		text, err := transcriber.Transcribe(ctx, wavBuf)
-		cancel()
-
-		if err != nil {
-			log.Printf("Error transcribing: %v", err)
-			continue
+		if sc, ok := capturer.(audio.StreamingCapturer); ok && cfg.Streaming {
+			a.runStreamingSession(sc)
+		} else {
+			a.runSession()
 		}
+	}
+}
 
-		if text == "" {
-			fmt.Println("No speech detected. Please try again.")
-			continue
-		}
+// printDevices writes the available input devices as a table
+func printDevices() error {
+	devices, err := audio.ListDevices()
+	if err != nil {
+		return err
+	}
 
-		fmt.Printf("\nTranscribed text: %s\n\n", text)
+	if len(devices) == 0 {
+		fmt.Println("No audio input devices found.")
+		return nil
+	}
 
-		// Copy to clipboard
-		if err := clipMgr.Write(text); err != nil {
-			log.Printf("Error writing to clipboard: %v", err)
-			continue
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tNAME\tCHANNELS\tSAMPLE RATE\tHOST API")
+	for _, d := range devices {
+		name := d.Name
+		if d.IsDefault {
+			name += " (default)"
 		}
-
-		fmt.Println("Text copied to clipboard! You can now paste it anywhere.")
-		fmt.Println()
+		fmt.Fprintf(w, "%d\t%s\t%d\t%.0f Hz\t%s\n", d.Index, name, d.MaxInputChannels, d.DefaultSampleRate, d.HostAPI)
+	}
+	if err := w.Flush(); err != nil {
+		return err
 	}
+
+	fmt.Println("\nSet AUDIO_DEVICE to an index or part of a name to choose one.")
+	return nil
 }
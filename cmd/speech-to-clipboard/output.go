@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"speech-to-clipboard/internal/config"
+	"speech-to-clipboard/pkg/clipboard"
+	"speech-to-clipboard/pkg/output"
+)
+
+// newSinks builds the outputs named in cfg.Outputs; clipMgr backs the
+// clipboard output. An output that cannot be set up is logged and left out.
+// Typing needs a global hotkey: without one the focused window is this
+// terminal, and the typed transcript would be read back as ENTER.
+func newSinks(cfg *config.Config, clipMgr clipboard.Manager, hotkey bool) output.Multi {
+	var sinks output.Multi
+	for _, name := range cfg.Outputs {
+		switch name {
+		case config.OutputClipboard:
+			sinks = append(sinks, output.NewClipboardSink(clipMgr))
+		case config.OutputType:
+			if !hotkey {
+				log.Print("Typing disabled: it needs a working STT_HOTKEY, or the transcript is typed into this terminal and starts a new recording")
+				continue
+			}
+			typer, err := output.NewTyper(cfg.TypeBackend)
+			if err != nil {
+				log.Printf("Typing disabled: %v", err)
+				continue
+			}
+			sinks = append(sinks, output.NewTypeSink(typer, cfg.TypeKey, cfg.TypeNewline))
+		case config.OutputStdout:
+			sinks = append(sinks, output.NewWriterSink(os.Stdout, cfg.OutputFormat))
+		case config.OutputFile:
+			sinks = append(sinks, output.NewFileSink(cfg.OutputFile, cfg.OutputFormat))
+		case config.OutputFIFO:
+			sinks = append(sinks, output.NewFIFOSink(cfg.OutputFIFO, cfg.OutputFormat))
+		case config.OutputWebhook:
+			sinks = append(sinks, output.NewWebhookSink(cfg.WebhookURL))
+		}
+	}
+	return sinks
+}
+
+// holdClipboard keeps a one-shot command running until the clipboard content
+// it replaced was restored, since a pending restore dies with the process.
+// Ctrl+C restores at once.
+func holdClipboard(clipMgr clipboard.Manager) error {
+	restored := clipMgr.Restored()
+	select {
+	case <-restored:
+		return nil
+	default:
+	}
+
+	fmt.Fprintln(os.Stderr, "Waiting to restore the previous clipboard content; press Ctrl+C to restore it now.")
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	select {
+	case <-restored:
+		return nil
+	case <-sigChan:
+		return clipMgr.Restore()
+	}
+}
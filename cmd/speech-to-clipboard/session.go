@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"speech-to-clipboard/internal/config"
+	"speech-to-clipboard/pkg/audio"
+	"speech-to-clipboard/pkg/clipboard"
+	"speech-to-clipboard/pkg/history"
+	"speech-to-clipboard/pkg/hotkey"
+	"speech-to-clipboard/pkg/output"
+	"speech-to-clipboard/pkg/pipeline"
+	"speech-to-clipboard/pkg/postprocess"
+	"speech-to-clipboard/pkg/stt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// app holds the components shared by every recording session
+type app struct {
+	cfg         *config.Config
+	capturer    audio.Capturer
+	transcriber stt.Transcriber
+	encoder     audio.Encoder
+	// clipMgr is nil when the clipboard is not among the outputs. The
+	// clipboard sink writes through it; the app uses it for recall and
+	// restore.
+	clipMgr clipboard.Manager
+	// sinks receive each transcript, the clipboard among them
+	sinks output.Multi
+	// postProcess cleans up each transcript before it is copied
+	postProcess postprocess.Processor
+	// vad trims silence and skips uploads without speech; nil when disabled
+	vad *audio.VAD
+	// enter receives each line typed, when the user presses ENTER
+	enter <-chan string
+	// hotkeys delivers global hotkey events; nil when no hotkey is registered
+	hotkeys    <-chan hotkey.Event
+	hotkeyMode hotkey.Mode
+	// history stores each recording and its transcript; nil when disabled
+	history *history.Store
+}
+
+// readLines sends every line read from r on the returned channel, which
+// is closed at end of input
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- strings.TrimSpace(scanner.Text())
+		}
+	}()
+	return lines
+}
+
+// waitForStart blocks until the user presses ENTER or a hotkey event asks
+// for a recording to start. Lines with a clipboard history command are
+// handled without starting a recording. It returns false once stdin has
+// ended and there is no hotkey left to start another recording.
+func (a *app) waitForStart() bool {
+	a.dropQueuedHotkeys()
+
+	for a.enter != nil || a.hotkeys != nil {
+		select {
+		case line, ok := <-a.enter:
+			if !ok {
+				a.enter = nil
+				continue
+			}
+			if a.clipboardCommand(line) {
+				fmt.Print("Press ENTER to start recording: ")
+				continue
+			}
+			return true
+		case e, ok := <-a.hotkeys:
+			if !ok {
+				a.hotkeys = nil
+				continue
+			}
+			if action := a.hotkeyMode.Action(e); action == hotkey.ActionStart || action == hotkey.ActionToggle {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dropQueuedHotkeys discards hotkey events that arrived while the last
+// recording was transcribed, so an old press does not start a new one
+func (a *app) dropQueuedHotkeys() {
+	for {
+		select {
+		case _, ok := <-a.hotkeys:
+			if !ok {
+				a.hotkeys = nil
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// waitForStop blocks until the user presses ENTER, a hotkey event asks for
+// the recording to stop, or the capturer detects that the speaker has finished
+func (a *app) waitForStop() {
+	var autoStopped <-chan struct{}
+	if ac, ok := a.capturer.(audio.AutoStopCapturer); ok {
+		autoStopped = ac.AutoStopped()
+	}
+
+	for {
+		select {
+		case _, ok := <-a.enter:
+			if ok {
+				return
+			}
+			// Without stdin only a hotkey or auto-stop can end the recording;
+			// with neither, end of input stops it
+			a.enter = nil
+			if a.hotkeys == nil && autoStopped == nil {
+				return
+			}
+		case e, ok := <-a.hotkeys:
+			if !ok {
+				a.hotkeys = nil
+				if a.enter == nil && autoStopped == nil {
+					return
+				}
+				continue
+			}
+			if action := a.hotkeyMode.Action(e); action == hotkey.ActionStop || action == hotkey.ActionToggle {
+				return
+			}
+		case <-autoStopped:
+			fmt.Println("Recording stopped automatically.")
+			return
+		}
+	}
+}
+
+// runSession records until ENTER, then transcribes the whole recording at once
+func (a *app) runSession() {
+	fmt.Println("Recording... Press ENTER to stop")
+	if err := a.capturer.Start(); err != nil {
+		log.Printf("Error starting recording: %v", err)
+		return
+	}
+
+	a.waitForStop()
+
+	fmt.Println("Stopping recording...")
+	if err := a.capturer.Stop(); err != nil {
+		log.Printf("Error stopping recording: %v", err)
+		return
+	}
+
+	// Get audio data
+	audioData, err := a.capturer.GetAudioData()
+	if err != nil {
+		log.Printf("Error getting audio data: %v", err)
+		return
+	}
+
+	if len(audioData) == 0 {
+		fmt.Println("No audio captured. Please try again.")
+		return
+	}
+
+	if a.vad != nil {
+		audioData = a.vad.TrimSilence(audioData)
+		if len(audioData) == 0 {
+			fmt.Println("No speech detected. Please try again.")
+			return
+		}
+	}
+
+	fmt.Printf("Captured %d samples. Transcribing...\n", len(audioData))
+
+	// Transcribe, in segments if the upload would be too large
+	splitCfg := newSplitConfig(a.cfg, a.encoder)
+	text, err := pipeline.TranscribeRecording(context.Background(), a.transcriber, audioData, splitCfg)
+	if err == nil {
+		text = a.postProcess.Process(text)
+	}
+	a.saveHistory(audioData, text, err)
+	if err != nil {
+		reportTranscribeError(err)
+		return
+	}
+
+	if text == "" {
+		fmt.Println("No speech detected. Please try again.")
+		return
+	}
+
+	fmt.Printf("\nTranscribed text: %s\n\n", text)
+	a.deliver(text)
+	fmt.Println()
+}
+
+// deliver sends a finished transcript to every sink at once. Failures are
+// logged; one broken destination does not stop the others.
+func (a *app) deliver(text string) {
+	if err := a.sinks.Send(text); err != nil {
+		log.Printf("Error delivering transcript: %v", err)
+		return
+	}
+	if a.clipMgr != nil {
+		fmt.Println("Text copied to clipboard! You can now paste it anywhere.")
+		a.printRestoreNotice()
+	}
+}
+
+// printRestoreNotice tells the user how long the transcript stays on the
+// clipboard when the previous content is restored
+func (a *app) printRestoreNotice() {
+	d, onPaste := a.cfg.Clipboard.RestoreAfter, a.cfg.Clipboard.RestoreOnPaste
+	switch {
+	case d > 0 && onPaste:
+		fmt.Printf("The previous clipboard content returns after the first paste, or in %v.\n", d)
+	case d > 0:
+		fmt.Printf("The previous clipboard content returns in %v.\n", d)
+	case onPaste:
+		fmt.Println("The previous clipboard content returns after the first paste.")
+	}
+}
+
+// runStreamingSession transcribes chunks while recording continues, printing
+// each piece and growing the clipboard content as the text arrives
+func (a *app) runStreamingSession(capturer audio.StreamingCapturer) {
+	opts := audio.DefaultChunkOptions()
+	opts.MaxDuration = a.cfg.ChunkDuration
+	if a.cfg.ChunkOnSilence {
+		opts.MinDuration = a.cfg.ChunkDuration / 4
+	}
+
+	fmt.Println("Recording (streaming)... Press ENTER to stop")
+	chunks, err := capturer.StartStreaming(opts)
+	if err != nil {
+		log.Printf("Error starting recording: %v", err)
+		return
+	}
+
+	if a.vad != nil {
+		chunks = speechOnly(chunks, a.vad)
+	}
+
+	streamCfg := pipeline.DefaultStreamConfig()
+	streamCfg.Concurrency = a.cfg.StreamConcurrency
+	streamCfg.Encoder = a.encoder
+	segments := pipeline.TranscribeStream(context.Background(), a.transcriber, chunks, streamCfg)
+
+	done := make(chan string)
+	go func() {
+		// parts holds the raw segments; the transcript is cleaned up as a
+		// whole so capitalization, replacements and dictation commands see
+		// across segment boundaries
+		var parts []string
+		var text string
+		for segment := range segments {
+			if segment.Err != nil {
+				reportTranscribeError(segment.Err)
+				continue
+			}
+			raw := strings.TrimSpace(segment.Text)
+			if raw == "" {
+				continue
+			}
+
+			parts = append(parts, raw)
+			fmt.Printf("[%d] %s\n", segment.Index+1, raw)
+			text = strings.TrimSpace(a.postProcess.Process(strings.Join(parts, " ")))
+			if text == "" {
+				continue
+			}
+			// Sinks that can show a transcript in progress, such as the
+			// clipboard, grow it in place
+			if err := a.sinks.Update(text); err != nil {
+				log.Printf("Error updating transcript: %v", err)
+			}
+		}
+		done <- text
+	}()
+
+	a.waitForStop()
+
+	fmt.Println("Stopping recording... finishing transcription")
+	if err := capturer.Stop(); err != nil {
+		log.Printf("Error stopping recording: %v", err)
+	}
+
+	text := <-done
+	if lost := capturer.Dropped(); lost > 0 {
+		log.Printf("Transcription fell behind and skipped %v of audio", lost.Round(time.Millisecond))
+	}
+	if audioData, err := capturer.GetAudioData(); err == nil && len(audioData) > 0 {
+		a.saveHistory(audioData, text, nil)
+	}
+
+	if text == "" {
+		fmt.Println("No speech detected. Please try again.")
+		return
+	}
+
+	fmt.Printf("\nTranscribed text: %s\n\n", text)
+	// Sinks get the whole transcript; typing it piece by piece would mix
+	// with whatever the user types in the meantime
+	a.deliver(text)
+	fmt.Println()
+}
+
+// clipboardCommand handles "h", which lists the recent transcripts, and a
+// number, which copies that transcript again. It reports whether line was
+// one of them.
+func (a *app) clipboardCommand(line string) bool {
+	if a.clipMgr == nil {
+		return false
+	}
+	if line == "h" || line == "history" {
+		recent := a.clipMgr.History()
+		if len(recent) == 0 {
+			fmt.Println("No transcripts yet.")
+		}
+		for i, text := range recent {
+			fmt.Printf("%2d  %s\n", i+1, summarize(text, 70))
+		}
+		return true
+	}
+
+	n, err := strconv.Atoi(line)
+	if err != nil {
+		return false
+	}
+	if err := a.clipMgr.Recall(n - 1); err != nil {
+		fmt.Println(err)
+		return true
+	}
+	fmt.Printf("Transcript %d copied to clipboard.\n", n)
+	return true
+}
+
+// saveHistory stores a recording with its transcript or transcription error,
+// then drops the oldest entries beyond the configured limit. Failures are
+// logged; they never interrupt the session.
+func (a *app) saveHistory(samples []int16, text string, transcribeErr error) {
+	if a.history == nil {
+		return
+	}
+
+	e := &history.Entry{
+		Model:    modelName(a.cfg),
+		Language: a.cfg.Language,
+		Text:     text,
+	}
+	if transcribeErr != nil {
+		e.Error = transcribeErr.Error()
+	}
+	if err := a.history.Save(e, samples, audio.SampleRate); err != nil {
+		log.Printf("Error saving history: %v", err)
+		return
+	}
+
+	if a.cfg.HistoryMaxEntries > 0 {
+		if err := a.history.Prune(a.cfg.HistoryMaxEntries); err != nil {
+			log.Printf("Error pruning history: %v", err)
+		}
+	}
+}
+
+// speechOnly forwards the chunks that contain speech, trimmed of silence
+func speechOnly(chunks <-chan []int16, vad *audio.VAD) <-chan []int16 {
+	out := make(chan []int16)
+	go func() {
+		defer close(out)
+
+		for chunk := range chunks {
+			if trimmed := vad.TrimSilence(chunk); len(trimmed) > 0 {
+				out <- trimmed
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"speech-to-clipboard/internal/config"
+	"speech-to-clipboard/pkg/audio"
+	"speech-to-clipboard/pkg/clipboard"
+	"speech-to-clipboard/pkg/pipeline"
+	"speech-to-clipboard/pkg/stt"
+	"strings"
+)
+
+// runTranscribe implements "transcribe [flags] <file...>": it transcribes
+// existing WAV or FLAC recordings with the configured backend
+func runTranscribe(args []string, opts config.Options) error {
+	fs := flag.NewFlagSet("transcribe", flag.ContinueOnError)
+	outputDir := fs.String("output-dir", "", "write each transcript to `dir`/<name>.txt instead of stdout")
+	toClipboard := fs.Bool("clipboard", false, "copy the transcripts to the clipboard as well")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: speech-to-clipboard transcribe [flags] <file...>")
+		fmt.Fprintln(fs.Output(), "\nTranscribes WAV or FLAC files with the configured backend.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		fs.Usage()
+		return fmt.Errorf("no files given")
+	}
+
+	cfg, err := config.LoadWithOptions(opts)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	transcriber, err := newTranscriber(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize transcriber: %w", err)
+	}
+	splitCfg := newSplitConfig(cfg, newEncoder(cfg))
+	postProcess := newPostProcessor(cfg)
+
+	if *outputDir != "" {
+		if err := os.MkdirAll(*outputDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	var texts []string
+	failed := 0
+	for _, path := range files {
+		text, err := transcribeFile(context.Background(), transcriber, path, splitCfg)
+		if err != nil {
+			log.Printf("%s: %v", path, err)
+			failed++
+			continue
+		}
+		text = postProcess.Process(text)
+		texts = append(texts, text)
+
+		if err := writeTranscript(os.Stdout, *outputDir, path, text, len(files) > 1); err != nil {
+			log.Printf("%s: %v", path, err)
+			failed++
+		}
+	}
+
+	if *toClipboard && len(texts) > 0 {
+		clipMgr, err := clipboard.NewManagerWithOptions(cfg.Clipboard)
+		if err != nil {
+			return err
+		}
+		if err := clipMgr.Write(strings.Join(texts, "\n\n")); err != nil {
+			return fmt.Errorf("failed to write to clipboard: %w", err)
+		}
+		if err := holdClipboard(clipMgr); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(files))
+	}
+	return nil
+}
+
+// transcribeFile decodes an audio file, converts it to the transcription
+// format and transcribes it, splitting long recordings as configured
+func transcribeFile(ctx context.Context, t stt.Transcriber, path string, splitCfg pipeline.SplitConfig) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	samples, format, err := audio.Decode(f)
+	if err != nil {
+		return "", err
+	}
+	if len(samples) == 0 {
+		return "", fmt.Errorf("file contains no audio")
+	}
+
+	samples = audio.Convert(samples, format, audio.SampleRate)
+	return pipeline.TranscribeRecording(ctx, t, samples, splitCfg)
+}
+
+// writeTranscript prints text to stdout, or writes it to a .txt file named
+// after the recording in outputDir. With several files on stdout each
+// transcript is preceded by its file name and followed by a blank line.
+func writeTranscript(stdout io.Writer, outputDir, path, text string, labelled bool) error {
+	if outputDir != "" {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + ".txt"
+		return os.WriteFile(filepath.Join(outputDir, name), []byte(text+"\n"), 0o644)
+	}
+
+	if labelled {
+		if _, err := fmt.Fprintf(stdout, "==> %s <==\n", path); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintln(stdout, text); err != nil {
+		return err
+	}
+	if labelled {
+		_, err := fmt.Fprintln(stdout)
+		return err
+	}
+	return nil
+}
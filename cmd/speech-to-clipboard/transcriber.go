@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"speech-to-clipboard/internal/config"
+	"speech-to-clipboard/pkg/audio"
+	"speech-to-clipboard/pkg/dictation"
+	"speech-to-clipboard/pkg/pipeline"
+	"speech-to-clipboard/pkg/postprocess"
+	"speech-to-clipboard/pkg/stt"
+)
+
+// newSplitConfig returns the settings for transcribing finished recordings.
+// The local backend has no upload limit, so recordings are never split.
+func newSplitConfig(cfg *config.Config, enc audio.Encoder) pipeline.SplitConfig {
+	splitCfg := pipeline.DefaultSplitConfig()
+	splitCfg.MaxBytes = cfg.MaxUploadMB << 20
+	splitCfg.Concurrency = cfg.SplitConcurrency
+	splitCfg.Encoder = enc
+	if cfg.Backend == config.BackendLocal {
+		splitCfg.MaxBytes = 0
+	}
+	return splitCfg
+}
+
+// newEncoder returns the upload encoder selected in the config. The local
+// backend always gets WAV, which every whisper.cpp build can read.
+func newEncoder(cfg *config.Config) audio.Encoder {
+	if cfg.Backend == config.BackendLocal {
+		return audio.WAVEncoder{}
+	}
+	enc, err := audio.NewEncoder(cfg.AudioEncoding)
+	if err != nil {
+		log.Printf("%v; using WAV", err)
+		return audio.WAVEncoder{}
+	}
+	return enc
+}
+
+// modelName describes the model a transcription came from: the API model
+// name, or the model file of the local backend
+func modelName(cfg *config.Config) string {
+	if cfg.Backend == config.BackendLocal {
+		return filepath.Base(cfg.ModelPath)
+	}
+	return cfg.Model
+}
+
+// newPostProcessor returns the cleanup applied to each transcript: spoken
+// dictation commands when enabled, then the configured post-processing chain
+func newPostProcessor(cfg *config.Config) postprocess.Processor {
+	chain := postprocess.New(cfg.PostProcess)
+	if cfg.Dictation {
+		table := dictation.ForLanguage(cfg.Language).With(cfg.DictationCommands...)
+		chain = append(postprocess.Chain{dictation.New(table)}, chain...)
+	}
+	return chain
+}
+
+// newTranscriber builds the transcription backend selected in the config
+func newTranscriber(cfg *config.Config) (stt.Transcriber, error) {
+	if cfg.Backend == config.BackendLocal {
+		return stt.NewLocalTranscriber(stt.TranscriberConfig{
+			Language:   cfg.Language,
+			Prompt:     cfg.Prompt,
+			ModelPath:  cfg.ModelPath,
+			BinaryPath: cfg.WhisperBinary,
+			Threads:    cfg.Threads,
+		})
+	}
+
+	whisper := stt.NewWhisperTranscriberWithConfig(stt.TranscriberConfig{
+		APIKey:         cfg.OpenAIAPIKey,
+		Model:          cfg.Model,
+		Language:       cfg.Language,
+		Prompt:         cfg.Prompt,
+		Temperature:    cfg.Temperature,
+		ResponseFormat: cfg.ResponseFormat,
+		BaseURL:        cfg.BaseURL,
+
+		Organization:    cfg.Organization,
+		Project:         cfg.Project,
+		Headers:         cfg.Headers,
+		AzureDeployment: cfg.AzureDeployment,
+		APIVersion:      cfg.AzureAPIVersion,
+	})
+
+	retryCfg := stt.DefaultRetryConfig()
+	retryCfg.MaxRetries = cfg.MaxRetries
+	return stt.NewRetryingTranscriber(whisper, retryCfg), nil
+}
+
+// reportTranscribeError logs a transcription failure with a hint for the
+// error classes a user can act on
+func reportTranscribeError(err error) {
+	log.Printf("Error transcribing: %v", err)
+
+	switch {
+	case errors.Is(err, stt.ErrAuth):
+		fmt.Println("The API key was rejected. Check OPENAI_API_KEY.")
+	case errors.Is(err, stt.ErrRateLimited):
+		fmt.Println("The API is rate limiting requests. Wait a moment and try again.")
+	case errors.Is(err, stt.ErrBadAudio):
+		fmt.Println("The API could not process this recording. Try a shorter or clearer recording.")
+	}
+}
@@ -2,35 +2,591 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"speech-to-clipboard/pkg/clipboard"
+	"speech-to-clipboard/pkg/dictation"
+	"speech-to-clipboard/pkg/hotkey"
+	"speech-to-clipboard/pkg/output"
+	"speech-to-clipboard/pkg/postprocess"
+)
+
+// Transcription backends selectable with STT_BACKEND
+const (
+	BackendOpenAI = "openai"
+	BackendLocal  = "local"
+)
+
+// Transcript destinations selectable with STT_OUTPUTS
+const (
+	OutputClipboard = "clipboard"
+	OutputType      = "type"
+	OutputStdout    = "stdout"
+	OutputFile      = "file"
+	OutputFIFO      = "fifo"
+	OutputWebhook   = "webhook"
 )
 
+// DefaultBaseURL is the OpenAI API root. An API key is only required when
+// transcription requests go there.
+const DefaultBaseURL = "https://api.openai.com/v1"
+
 // Config holds application configuration
 type Config struct {
-	OpenAIAPIKey string
-	Model        string
-	Language     string
+	OpenAIAPIKey   string
+	Model          string
+	Language       string
+	Prompt         string
+	Temperature    float64
+	ResponseFormat string
+	BaseURL        string
+
+	Organization    string
+	Project         string
+	Headers         map[string]string
+	AzureDeployment string
+	AzureAPIVersion string
+
+	MaxRetries int
+	// AudioEncoding is the upload format, wav or flac
+	AudioEncoding string
+	// MaxUploadMB splits recordings whose upload would be larger; 0 disables splitting
+	MaxUploadMB int
+	// SplitConcurrency above one transcribes split segments in parallel
+	SplitConcurrency int
+
+	Backend       string
+	ModelPath     string
+	WhisperBinary string
+	Threads       int
+
+	Streaming         bool
+	ChunkDuration     time.Duration
+	ChunkOnSilence    bool
+	StreamConcurrency int
+
+	VAD          bool
+	VADThreshold float64
+	AutoStop     time.Duration
+
+	// Hotkey is empty when recording is controlled with ENTER only
+	Hotkey     string
+	HotkeyMode hotkey.Mode
+
+	// AudioDevice selects the input by index or name; empty uses the system default
+	AudioDevice string
+	// AudioSampleRate is the capture rate, zero for the device's native rate;
+	// audio is resampled for transcription either way
+	AudioSampleRate int
+	AudioChannels   int
+
+	// MaxRecording caps how much audio is held in memory; Overflow is "stop"
+	// or "drop-oldest" and decides what happens once it is reached
+	MaxRecording time.Duration
+	Overflow     string
+
+	// History saves every recording and transcript to HistoryDir, keeping the
+	// newest HistoryMaxEntries of them; 0 keeps all. It is off by default,
+	// since dictation may be confidential.
+	History           bool
+	HistoryDir        string
+	HistoryMaxEntries int
+
+	// PostProcess selects the cleanup applied to transcripts before they
+	// reach the clipboard
+	PostProcess postprocess.Options
+
+	// Dictation interprets spoken commands such as "period" with the table
+	// for Language, changed by DictationCommands
+	Dictation         bool
+	DictationCommands []dictation.Command
+
+	// Clipboard decides whether transcripts replace, follow or precede the
+	// clipboard content, how many are kept for recall and whether the
+	// previous content comes back after a while
+	Clipboard clipboard.Options
+
+	// Outputs lists where finished transcripts go: OutputClipboard,
+	// OutputType, which types them into the focused window with TypeBackend
+	// and then presses TypeKey, if set, and the stdout, file, FIFO and
+	// webhook sinks
+	Outputs     []string
+	TypeBackend string
+	TypeKey     string
+	// TypeNewline is pressed for each line break while typing; empty types
+	// a space instead
+	TypeNewline string
+	// OutputFormat is how transcripts are written to stdout, OutputFile and
+	// OutputFIFO
+	OutputFormat output.Format
+	OutputFile   string
+	OutputFIFO   string
+	WebhookURL   string
+
+	// ConfigFile is the config file that was read, if any
+	ConfigFile string
+	settings   []Setting
+}
+
+// Settings returns every option with its effective value and where it was
+// set. Secrets are redacted.
+func (c *Config) Settings() []Setting {
+	return c.settings
 }
 
-// Load loads configuration from environment variables
+// Load loads configuration from environment variables and the default
+// config file
 func Load() (*Config, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is required")
+	return LoadWithOptions(Options{})
+}
+
+// LoadWithOptions loads configuration from, in order of precedence, the
+// flags in opts, environment variables, the config file and the defaults.
+// Validation errors name the flag, file key or variable that was set.
+func LoadWithOptions(opts Options) (*Config, error) {
+	l, err := newLayers(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := l.lookup("STT_BACKEND", BackendOpenAI)
+	modelPath := l.lookup("STT_MODEL_PATH", "")
+	switch backend {
+	case BackendOpenAI:
+	case BackendLocal:
+		if modelPath == "" {
+			return nil, fmt.Errorf("%s is required when %s is local", l.name("STT_MODEL_PATH"), l.name("STT_BACKEND"))
+		}
+	default:
+		return nil, fmt.Errorf("%s %q is not one of openai, local", l.name("STT_BACKEND"), backend)
+	}
+
+	baseURL := l.lookup("OPENAI_BASE_URL", DefaultBaseURL)
+	var apiKey string
+	if backend == BackendOpenAI {
+		if apiKey, err = l.apiKey(opts.KeySources); err != nil {
+			return nil, err
+		}
+	}
+	if apiKey == "" && backend == BackendOpenAI && strings.TrimRight(baseURL, "/") == DefaultBaseURL {
+		return nil, fmt.Errorf("an API key is required: set OPENAI_API_KEY, OPENAI_API_KEY_FILE or OPENAI_API_KEY_COMMAND, or run config set-key")
+	}
+
+	temperature, err := strconv.ParseFloat(l.lookup("STT_TEMPERATURE", "0"), 64)
+	if err != nil || temperature < 0 || temperature > 1 {
+		return nil, fmt.Errorf("%s must be a number between 0 and 1", l.name("STT_TEMPERATURE"))
+	}
+
+	responseFormat := l.lookup("STT_RESPONSE_FORMAT", "json")
+	switch responseFormat {
+	case "json", "text", "srt", "verbose_json", "vtt":
+	default:
+		return nil, fmt.Errorf("%s %q is not one of json, text, srt, verbose_json, vtt", l.name("STT_RESPONSE_FORMAT"), responseFormat)
+	}
+
+	headers, err := parseHeaders(l.lookup("STT_HEADERS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", l.name("STT_HEADERS"), err)
+	}
+
+	maxRetries, err := l.getInt("STT_MAX_RETRIES", 3)
+	if err != nil || maxRetries < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", l.name("STT_MAX_RETRIES"))
+	}
+
+	threads, err := l.getInt("STT_THREADS", 0)
+	if err != nil || threads < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", l.name("STT_THREADS"))
+	}
+
+	streaming, err := l.getBool("STT_STREAMING", false)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", l.name("STT_STREAMING"))
+	}
+
+	chunkSeconds, err := l.getInt("STT_CHUNK_SECONDS", 10)
+	if err != nil || chunkSeconds < 1 {
+		return nil, fmt.Errorf("%s must be a positive integer", l.name("STT_CHUNK_SECONDS"))
+	}
+
+	chunkOnSilence, err := l.getBool("STT_CHUNK_ON_SILENCE", false)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", l.name("STT_CHUNK_ON_SILENCE"))
+	}
+
+	streamConcurrency, err := l.getInt("STT_STREAM_CONCURRENCY", 2)
+	if err != nil || streamConcurrency < 1 {
+		return nil, fmt.Errorf("%s must be a positive integer", l.name("STT_STREAM_CONCURRENCY"))
+	}
+
+	vad, err := l.getBool("STT_VAD", false)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", l.name("STT_VAD"))
+	}
+
+	vadThreshold, err := strconv.ParseFloat(l.lookup("STT_VAD_THRESHOLD", "500"), 64)
+	if err != nil || vadThreshold <= 0 {
+		return nil, fmt.Errorf("%s must be a positive number", l.name("STT_VAD_THRESHOLD"))
+	}
+
+	autoStopMS, err := l.getInt("STT_AUTO_STOP_MS", 0)
+	if err != nil || autoStopMS < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", l.name("STT_AUTO_STOP_MS"))
+	}
+
+	hotkeySpec := l.lookup("STT_HOTKEY", "")
+	if hotkeySpec != "" {
+		if _, err := hotkey.Parse(hotkeySpec); err != nil {
+			return nil, fmt.Errorf("%s: %w", l.name("STT_HOTKEY"), err)
+		}
+	}
+
+	hotkeyMode, err := hotkey.ParseMode(l.lookup("STT_HOTKEY_MODE", string(hotkey.Toggle)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", l.name("STT_HOTKEY_MODE"), err)
+	}
+
+	maxRecordingSeconds, err := l.getInt("STT_MAX_RECORDING_SECONDS", 600)
+	if err != nil || maxRecordingSeconds <= 0 {
+		return nil, fmt.Errorf("%s must be a positive integer", l.name("STT_MAX_RECORDING_SECONDS"))
+	}
+
+	overflow := l.lookup("STT_OVERFLOW", "stop")
+	if overflow != "stop" && overflow != "drop-oldest" {
+		return nil, fmt.Errorf("%s must be stop or drop-oldest, got %q", l.name("STT_OVERFLOW"), overflow)
+	}
+
+	audioEncoding := l.lookup("STT_AUDIO_FORMAT", "wav")
+	if audioEncoding != "wav" && audioEncoding != "flac" {
+		return nil, fmt.Errorf("%s must be wav or flac, got %q", l.name("STT_AUDIO_FORMAT"), audioEncoding)
+	}
+
+	maxUploadMB, err := l.getInt("STT_MAX_UPLOAD_MB", 24)
+	if err != nil || maxUploadMB < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", l.name("STT_MAX_UPLOAD_MB"))
+	}
+
+	splitConcurrency, err := l.getInt("STT_SPLIT_CONCURRENCY", 1)
+	if err != nil || splitConcurrency < 1 {
+		return nil, fmt.Errorf("%s must be a positive integer", l.name("STT_SPLIT_CONCURRENCY"))
+	}
+
+	audioSampleRate, err := l.getInt("AUDIO_SAMPLE_RATE", 0)
+	if err != nil || audioSampleRate < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", l.name("AUDIO_SAMPLE_RATE"))
+	}
+
+	audioChannels, err := l.getInt("AUDIO_CHANNELS", 1)
+	if err != nil || audioChannels < 1 {
+		return nil, fmt.Errorf("%s must be a positive integer", l.name("AUDIO_CHANNELS"))
+	}
+
+	history, err := l.getBool("STT_HISTORY", false)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", l.name("STT_HISTORY"))
+	}
+
+	historyMaxEntries, err := l.getInt("STT_HISTORY_MAX_ENTRIES", 200)
+	if err != nil || historyMaxEntries < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", l.name("STT_HISTORY_MAX_ENTRIES"))
+	}
+
+	postProcess, err := l.postProcessOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	dictationEnabled, err := l.getBool("STT_DICTATION", false)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", l.name("STT_DICTATION"))
+	}
+
+	var dictationCommands []dictation.Command
+	if path := l.lookup("STT_DICTATION_COMMANDS_FILE", ""); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", l.name("STT_DICTATION_COMMANDS_FILE"), err)
+		}
+		if dictationCommands, err = dictation.ParseCommands(string(data)); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	clipboardOpts, err := l.clipboardOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	clipboardMode, err := clipboard.ParseMode(l.lookup("STT_CLIPBOARD_MODE", string(clipboard.Replace)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", l.name("STT_CLIPBOARD_MODE"), err)
+	}
+
+	clipboardHistory, err := l.getInt("STT_CLIPBOARD_HISTORY", clipboard.DefaultHistorySize)
+	if err != nil || clipboardHistory < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", l.name("STT_CLIPBOARD_HISTORY"))
+	}
+
+	outputs := splitList(l.lookup("STT_OUTPUTS", OutputClipboard))
+	for _, o := range outputs {
+		switch o {
+		case OutputClipboard, OutputType, OutputStdout, OutputFile, OutputFIFO, OutputWebhook:
+		default:
+			return nil, fmt.Errorf("%s: unknown output %q, want clipboard, type, stdout, file, fifo or webhook", l.name("STT_OUTPUTS"), o)
+		}
+	}
+	hasOutput := func(name string) bool { return slices.Contains(outputs, name) }
+
+	outputFormat, err := output.ParseFormat(l.lookup("STT_OUTPUT_FORMAT", string(output.FormatPlain)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", l.name("STT_OUTPUT_FORMAT"), err)
+	}
+
+	outputFile := l.lookup("STT_OUTPUT_FILE", "")
+	if outputFile == "" && hasOutput(OutputFile) {
+		return nil, fmt.Errorf("%s is required for the file output", l.name("STT_OUTPUT_FILE"))
+	}
+	outputFIFO := l.lookup("STT_OUTPUT_FIFO", "")
+	if outputFIFO == "" && hasOutput(OutputFIFO) {
+		return nil, fmt.Errorf("%s is required for the fifo output", l.name("STT_OUTPUT_FIFO"))
+	}
+
+	webhookURL := l.lookup("STT_WEBHOOK_URL", "")
+	if webhookURL == "" && hasOutput(OutputWebhook) {
+		return nil, fmt.Errorf("%s is required for the webhook output", l.name("STT_WEBHOOK_URL"))
+	}
+	if webhookURL != "" {
+		if u, err := url.Parse(webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%s must be an http or https URL, got %q", l.name("STT_WEBHOOK_URL"), webhookURL)
+		}
 	}
 
+	typeBackend := l.lookup("STT_TYPE_BACKEND", output.TyperAuto)
+	switch typeBackend {
+	case output.TyperAuto, output.TyperXdotool, output.TyperYdotool, output.TyperWtype:
+	default:
+		return nil, fmt.Errorf("%s must be auto, xdotool, ydotool or wtype, got %q", l.name("STT_TYPE_BACKEND"), typeBackend)
+	}
+
+	clipboardOpts.Mode = clipboardMode
+	clipboardOpts.Separator = postprocess.Unescape(l.lookup("STT_CLIPBOARD_SEPARATOR", `\n`))
+	clipboardOpts.HistorySize = clipboardHistory
+
 	cfg := &Config{
-		OpenAIAPIKey: apiKey,
-		Model:        getEnvOrDefault("STT_MODEL", "whisper-1"),
-		Language:     getEnvOrDefault("STT_LANGUAGE", "en"),
+		OpenAIAPIKey:   apiKey,
+		Model:          l.lookup("STT_MODEL", "whisper-1"),
+		Language:       l.lookup("STT_LANGUAGE", "en"),
+		Prompt:         l.lookup("STT_PROMPT", ""),
+		Temperature:    temperature,
+		ResponseFormat: responseFormat,
+		BaseURL:        baseURL,
+
+		Organization:    l.lookup("OPENAI_ORG_ID", ""),
+		Project:         l.lookup("OPENAI_PROJECT_ID", ""),
+		Headers:         headers,
+		AzureDeployment: l.lookup("AZURE_OPENAI_DEPLOYMENT", ""),
+		AzureAPIVersion: l.lookup("AZURE_OPENAI_API_VERSION", ""),
+
+		MaxRetries:    maxRetries,
+		AudioEncoding: audioEncoding,
+
+		MaxUploadMB:      maxUploadMB,
+		SplitConcurrency: splitConcurrency,
+
+		Backend:       backend,
+		ModelPath:     modelPath,
+		WhisperBinary: l.lookup("STT_WHISPER_BIN", ""),
+		Threads:       threads,
+
+		Streaming:         streaming,
+		ChunkDuration:     time.Duration(chunkSeconds) * time.Second,
+		ChunkOnSilence:    chunkOnSilence,
+		StreamConcurrency: streamConcurrency,
+
+		VAD:          vad,
+		VADThreshold: vadThreshold,
+		AutoStop:     time.Duration(autoStopMS) * time.Millisecond,
+
+		Hotkey:     hotkeySpec,
+		HotkeyMode: hotkeyMode,
+
+		AudioDevice:     l.lookup("AUDIO_DEVICE", ""),
+		AudioSampleRate: audioSampleRate,
+		AudioChannels:   audioChannels,
+
+		MaxRecording: time.Duration(maxRecordingSeconds) * time.Second,
+		Overflow:     overflow,
+
+		History:           history,
+		HistoryDir:        l.lookup("STT_HISTORY_DIR", defaultHistoryDir()),
+		HistoryMaxEntries: historyMaxEntries,
+
+		PostProcess: postProcess,
+
+		Dictation:         dictationEnabled,
+		DictationCommands: dictationCommands,
+
+		Clipboard: clipboardOpts,
+
+		Outputs:     outputs,
+		TypeBackend: typeBackend,
+		TypeKey:     l.lookup("STT_TYPE_KEY", ""),
+		TypeNewline: l.lookup("STT_TYPE_NEWLINE", ""),
+
+		OutputFormat: outputFormat,
+		OutputFile:   outputFile,
+		OutputFIFO:   outputFIFO,
+		WebhookURL:   webhookURL,
+
+		ConfigFile: l.filePath,
+		settings:   l.settings(),
 	}
 
 	return cfg, nil
 }
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// HistoryDir resolves only STT_HISTORY_DIR, so history can be browsed
+// without the settings transcription needs, such as an API key
+func HistoryDir(opts Options) (string, error) {
+	l, err := newLayers(opts)
+	if err != nil {
+		return "", err
+	}
+	return l.lookup("STT_HISTORY_DIR", defaultHistoryDir()), nil
+}
+
+// ClipboardOptions resolves only the settings that reach the clipboard, on
+// top of clipboard.DefaultOptions, for commands that copy without loading
+// the whole configuration
+func ClipboardOptions(opts Options) (clipboard.Options, error) {
+	l, err := newLayers(opts)
+	if err != nil {
+		return clipboard.Options{}, err
+	}
+	return l.clipboardOptions()
+}
+
+// clipboardOptions reads the clipboard backend and restore settings
+func (l *layers) clipboardOptions() (clipboard.Options, error) {
+	opts := clipboard.DefaultOptions()
+
+	var err error
+	if opts.Backend, err = clipboard.ParseBackend(l.lookup("STT_CLIPBOARD_BACKEND", string(clipboard.BackendAuto))); err != nil {
+		return opts, fmt.Errorf("%s: %w", l.name("STT_CLIPBOARD_BACKEND"), err)
+	}
+	if opts.OSC52Passthrough, err = clipboard.ParsePassthrough(l.lookup("STT_OSC52_PASSTHROUGH", string(clipboard.PassthroughAuto))); err != nil {
+		return opts, fmt.Errorf("%s: %w", l.name("STT_OSC52_PASSTHROUGH"), err)
+	}
+	opts.OSC52MaxBytes, err = l.getInt("STT_OSC52_MAX_BYTES", clipboard.DefaultOSC52MaxBytes)
+	if err != nil || opts.OSC52MaxBytes < 0 {
+		return opts, fmt.Errorf("%s must be a non-negative integer", l.name("STT_OSC52_MAX_BYTES"))
+	}
+
+	restoreMS, err := l.getInt("STT_CLIPBOARD_RESTORE_MS", 0)
+	if err != nil || restoreMS < 0 {
+		return opts, fmt.Errorf("%s must be a non-negative integer", l.name("STT_CLIPBOARD_RESTORE_MS"))
+	}
+	opts.RestoreAfter = time.Duration(restoreMS) * time.Millisecond
+	if opts.RestoreOnPaste, err = l.getBool("STT_CLIPBOARD_RESTORE_ON_PASTE", false); err != nil {
+		return opts, fmt.Errorf("%s must be true or false", l.name("STT_CLIPBOARD_RESTORE_ON_PASTE"))
+	}
+	return opts, nil
+}
+
+// defaultHistoryDir returns speech-to-clipboard/history under $XDG_DATA_HOME
+// or ~/.local/share
+func defaultHistoryDir() string {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "speech-to-clipboard", "history")
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "speech-to-clipboard", "history")
+}
+
+// postProcessOptions reads the transcript cleanup settings. Entries in
+// STT_REPLACEMENTS override those for the same phrase in STT_REPLACEMENTS_FILE.
+func (l *layers) postProcessOptions() (postprocess.Options, error) {
+	var opts postprocess.Options
+
+	if path := l.lookup("STT_REPLACEMENTS_FILE", ""); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return opts, fmt.Errorf("failed to read %s: %w", l.name("STT_REPLACEMENTS_FILE"), err)
+		}
+		replacements, err := postprocess.ParseReplacements(string(data))
+		if err != nil {
+			return opts, fmt.Errorf("%s: %w", path, err)
+		}
+		opts.Replacements = replacements
+	}
+	replacements, err := postprocess.ParseReplacements(l.lookup("STT_REPLACEMENTS", ""))
+	if err != nil {
+		return opts, fmt.Errorf("%s: %w", l.name("STT_REPLACEMENTS"), err)
+	}
+	opts.Replacements = append(opts.Replacements, replacements...)
+
+	if opts.RemoveFillers, err = l.getBool("STT_REMOVE_FILLERS", false); err != nil {
+		return opts, fmt.Errorf("%s must be true or false", l.name("STT_REMOVE_FILLERS"))
+	}
+	opts.Fillers = splitList(l.lookup("STT_FILLER_WORDS", ""))
+	if opts.AggressiveFillers, err = l.getBool("STT_AGGRESSIVE_FILLERS", false); err != nil {
+		return opts, fmt.Errorf("%s must be true or false", l.name("STT_AGGRESSIVE_FILLERS"))
+	}
+
+	if opts.MaskProfanity, err = l.getBool("STT_MASK_PROFANITY", false); err != nil {
+		return opts, fmt.Errorf("%s must be true or false", l.name("STT_MASK_PROFANITY"))
+	}
+	opts.ProfanityWords = splitList(l.lookup("STT_PROFANITY_WORDS", ""))
+
+	if opts.NormalizeWhitespace, err = l.getBool("STT_NORMALIZE_WHITESPACE", true); err != nil {
+		return opts, fmt.Errorf("%s must be true or false", l.name("STT_NORMALIZE_WHITESPACE"))
+	}
+	if opts.Case, err = postprocess.ParseCase(l.lookup("STT_CASE", "none")); err != nil {
+		return opts, fmt.Errorf("%s: %w", l.name("STT_CASE"), err)
+	}
+	return opts, nil
+}
+
+// HasOutput reports whether transcripts go to the named output
+func (c *Config) HasOutput(name string) bool {
+	return slices.Contains(c.Outputs, name)
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseHeaders parses a comma-separated list of Name=Value pairs
+func parseHeaders(value string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, want Name=Value", pair)
+		}
+		headers[name] = strings.TrimSpace(val)
 	}
-	return defaultValue
+	return headers, nil
 }
@@ -2,7 +2,17 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"speech-to-clipboard/pkg/clipboard"
+	"speech-to-clipboard/pkg/dictation"
+	"speech-to-clipboard/pkg/hotkey"
+	"speech-to-clipboard/pkg/output"
+	"speech-to-clipboard/pkg/postprocess"
 )
 
 func TestLoad_Success(t *testing.T) {
@@ -26,6 +36,14 @@ func TestLoad_Success(t *testing.T) {
 	if cfg.Language != "en" {
 		t.Errorf("Language = %v, want %v", cfg.Language, "en")
 	}
+
+	if cfg.MaxRetries != 3 {
+		t.Errorf("MaxRetries = %v, want %v", cfg.MaxRetries, 3)
+	}
+
+	if cfg.AudioDevice != "" {
+		t.Errorf("AudioDevice = %v, want empty", cfg.AudioDevice)
+	}
 }
 
 func TestLoad_CustomValues(t *testing.T) {
@@ -33,10 +51,12 @@ func TestLoad_CustomValues(t *testing.T) {
 	os.Setenv("OPENAI_API_KEY", "custom-key")
 	os.Setenv("STT_MODEL", "custom-model")
 	os.Setenv("STT_LANGUAGE", "es")
+	os.Setenv("AUDIO_DEVICE", "USB Headset")
 	defer func() {
 		os.Unsetenv("OPENAI_API_KEY")
 		os.Unsetenv("STT_MODEL")
 		os.Unsetenv("STT_LANGUAGE")
+		os.Unsetenv("AUDIO_DEVICE")
 	}()
 
 	cfg, err := Load()
@@ -55,6 +75,10 @@ func TestLoad_CustomValues(t *testing.T) {
 	if cfg.Language != "es" {
 		t.Errorf("Language = %v, want %v", cfg.Language, "es")
 	}
+
+	if cfg.AudioDevice != "USB Headset" {
+		t.Errorf("AudioDevice = %v, want %v", cfg.AudioDevice, "USB Headset")
+	}
 }
 
 func TestLoad_MissingAPIKey(t *testing.T) {
@@ -67,41 +91,736 @@ func TestLoad_MissingAPIKey(t *testing.T) {
 	}
 }
 
-func TestGetEnvOrDefault(t *testing.T) {
+func TestLoad_TranscriptionOptions(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	os.Setenv("STT_PROMPT", "Glossary: PortAudio, Whisper")
+	os.Setenv("STT_TEMPERATURE", "0.2")
+	os.Setenv("STT_RESPONSE_FORMAT", "text")
+	os.Setenv("OPENAI_BASE_URL", "http://localhost:8080/v1")
+	defer func() {
+		os.Unsetenv("OPENAI_API_KEY")
+		os.Unsetenv("STT_PROMPT")
+		os.Unsetenv("STT_TEMPERATURE")
+		os.Unsetenv("STT_RESPONSE_FORMAT")
+		os.Unsetenv("OPENAI_BASE_URL")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+
+	if cfg.Prompt != "Glossary: PortAudio, Whisper" {
+		t.Errorf("Prompt = %v, want %v", cfg.Prompt, "Glossary: PortAudio, Whisper")
+	}
+
+	if cfg.Temperature != 0.2 {
+		t.Errorf("Temperature = %v, want %v", cfg.Temperature, 0.2)
+	}
+
+	if cfg.ResponseFormat != "text" {
+		t.Errorf("ResponseFormat = %v, want %v", cfg.ResponseFormat, "text")
+	}
+
+	if cfg.BaseURL != "http://localhost:8080/v1" {
+		t.Errorf("BaseURL = %v, want %v", cfg.BaseURL, "http://localhost:8080/v1")
+	}
+}
+
+func TestLoad_InvalidTranscriptionOptions(t *testing.T) {
 	tests := []struct {
-		name         string
-		key          string
-		defaultValue string
-		envValue     string
-		want         string
+		name  string
+		key   string
+		value string
 	}{
-		{
-			name:         "use default when env not set",
-			key:          "TEST_VAR_NOT_SET",
-			defaultValue: "default",
-			envValue:     "",
-			want:         "default",
-		},
-		{
-			name:         "use env value when set",
-			key:          "TEST_VAR_SET",
-			defaultValue: "default",
-			envValue:     "custom",
-			want:         "custom",
-		},
+		{name: "temperature not a number", key: "STT_TEMPERATURE", value: "warm"},
+		{name: "temperature out of range", key: "STT_TEMPERATURE", value: "1.5"},
+		{name: "unknown response format", key: "STT_RESPONSE_FORMAT", value: "xml"},
+		{name: "retries not a number", key: "STT_MAX_RETRIES", value: "many"},
+		{name: "negative retries", key: "STT_MAX_RETRIES", value: "-1"},
+		{name: "unknown backend", key: "STT_BACKEND", value: "carrier-pigeon"},
+		{name: "local backend without model", key: "STT_BACKEND", value: "local"},
+		{name: "negative threads", key: "STT_THREADS", value: "-2"},
+		{name: "streaming not a bool", key: "STT_STREAMING", value: "sometimes"},
+		{name: "zero chunk seconds", key: "STT_CHUNK_SECONDS", value: "0"},
+		{name: "chunk on silence not a bool", key: "STT_CHUNK_ON_SILENCE", value: "maybe"},
+		{name: "zero stream concurrency", key: "STT_STREAM_CONCURRENCY", value: "0"},
+		{name: "vad not a bool", key: "STT_VAD", value: "yes please"},
+		{name: "zero vad threshold", key: "STT_VAD_THRESHOLD", value: "0"},
+		{name: "negative auto stop", key: "STT_AUTO_STOP_MS", value: "-100"},
+		{name: "negative upload limit", key: "STT_MAX_UPLOAD_MB", value: "-1"},
+		{name: "zero split concurrency", key: "STT_SPLIT_CONCURRENCY", value: "0"},
+		{name: "unknown audio format", key: "STT_AUDIO_FORMAT", value: "opus"},
+		{name: "negative sample rate", key: "AUDIO_SAMPLE_RATE", value: "-1"},
+		{name: "zero channels", key: "AUDIO_CHANNELS", value: "0"},
+		{name: "zero max recording", key: "STT_MAX_RECORDING_SECONDS", value: "0"},
+		{name: "invalid max recording", key: "STT_MAX_RECORDING_SECONDS", value: "long"},
+		{name: "unknown overflow policy", key: "STT_OVERFLOW", value: "wrap"},
+		{name: "invalid hotkey", key: "STT_HOTKEY", value: "ctrl+nope"},
+		{name: "invalid hotkey mode", key: "STT_HOTKEY_MODE", value: "hold"},
+		{name: "history not a bool", key: "STT_HISTORY", value: "always"},
+		{name: "negative history entries", key: "STT_HISTORY_MAX_ENTRIES", value: "-1"},
+		{name: "replacement without separator", key: "STT_REPLACEMENTS", value: "comma"},
+		{name: "missing replacements file", key: "STT_REPLACEMENTS_FILE", value: "/nonexistent/replacements.txt"},
+		{name: "remove fillers not a bool", key: "STT_REMOVE_FILLERS", value: "please"},
+		{name: "aggressive fillers not a bool", key: "STT_AGGRESSIVE_FILLERS", value: "very"},
+		{name: "mask profanity not a bool", key: "STT_MASK_PROFANITY", value: "kinda"},
+		{name: "normalize whitespace not a bool", key: "STT_NORMALIZE_WHITESPACE", value: "tidy"},
+		{name: "unknown case", key: "STT_CASE", value: "title"},
+		{name: "dictation not a bool", key: "STT_DICTATION", value: "on"},
+		{name: "unknown clipboard mode", key: "STT_CLIPBOARD_MODE", value: "insert"},
+		{name: "unknown clipboard backend", key: "STT_CLIPBOARD_BACKEND", value: "pbcopy"},
+		{name: "unknown OSC 52 passthrough", key: "STT_OSC52_PASSTHROUGH", value: "zellij"},
+		{name: "negative OSC 52 limit", key: "STT_OSC52_MAX_BYTES", value: "-1"},
+		{name: "negative clipboard history", key: "STT_CLIPBOARD_HISTORY", value: "-1"},
+		{name: "unknown output", key: "STT_OUTPUTS", value: "clipboard,printer"},
+		{name: "unknown typing backend", key: "STT_TYPE_BACKEND", value: "keyboard"},
+		{name: "unknown output format", key: "STT_OUTPUT_FORMAT", value: "csv"},
+		{name: "file output without a file", key: "STT_OUTPUTS", value: "clipboard,file"},
+		{name: "fifo output without a pipe", key: "STT_OUTPUTS", value: "fifo"},
+		{name: "webhook output without a URL", key: "STT_OUTPUTS", value: "webhook"},
+		{name: "webhook URL without a scheme", key: "STT_WEBHOOK_URL", value: "hooks.example.com/notes"},
+		{name: "negative clipboard restore delay", key: "STT_CLIPBOARD_RESTORE_MS", value: "-5"},
+		{name: "restore on paste not a bool", key: "STT_CLIPBOARD_RESTORE_ON_PASTE", value: "once"},
+		{name: "missing dictation commands file", key: "STT_DICTATION_COMMANDS_FILE", value: "/nonexistent/commands.txt"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.envValue != "" {
-				os.Setenv(tt.key, tt.envValue)
-				defer os.Unsetenv(tt.key)
+			os.Setenv("OPENAI_API_KEY", "test-api-key")
+			os.Setenv(tt.key, tt.value)
+			defer func() {
+				os.Unsetenv("OPENAI_API_KEY")
+				os.Unsetenv(tt.key)
+			}()
+
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() expected error for %s=%s, got nil", tt.key, tt.value)
 			}
+		})
+	}
+}
+
+func TestLoad_SelfHostedWithoutAPIKey(t *testing.T) {
+	os.Unsetenv("OPENAI_API_KEY")
+	os.Setenv("OPENAI_BASE_URL", "http://localhost:8000/v1")
+	defer os.Unsetenv("OPENAI_BASE_URL")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
 
-			got := getEnvOrDefault(tt.key, tt.defaultValue)
-			if got != tt.want {
-				t.Errorf("getEnvOrDefault() = %v, want %v", got, tt.want)
+	if cfg.OpenAIAPIKey != "" {
+		t.Errorf("OpenAIAPIKey = %v, want empty", cfg.OpenAIAPIKey)
+	}
+}
+
+func TestLoad_EndpointOptions(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	os.Setenv("OPENAI_ORG_ID", "org-1")
+	os.Setenv("OPENAI_PROJECT_ID", "proj-1")
+	os.Setenv("STT_HEADERS", "X-Team=audio, X-Env = dev")
+	os.Setenv("AZURE_OPENAI_DEPLOYMENT", "whisper")
+	os.Setenv("AZURE_OPENAI_API_VERSION", "2025-01-01")
+	defer func() {
+		os.Unsetenv("OPENAI_API_KEY")
+		os.Unsetenv("OPENAI_ORG_ID")
+		os.Unsetenv("OPENAI_PROJECT_ID")
+		os.Unsetenv("STT_HEADERS")
+		os.Unsetenv("AZURE_OPENAI_DEPLOYMENT")
+		os.Unsetenv("AZURE_OPENAI_API_VERSION")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+
+	if cfg.Organization != "org-1" {
+		t.Errorf("Organization = %v, want %v", cfg.Organization, "org-1")
+	}
+
+	if cfg.Project != "proj-1" {
+		t.Errorf("Project = %v, want %v", cfg.Project, "proj-1")
+	}
+
+	if cfg.Headers["X-Team"] != "audio" || cfg.Headers["X-Env"] != "dev" {
+		t.Errorf("Headers = %v, want X-Team=audio and X-Env=dev", cfg.Headers)
+	}
+
+	if cfg.AzureDeployment != "whisper" {
+		t.Errorf("AzureDeployment = %v, want %v", cfg.AzureDeployment, "whisper")
+	}
+
+	if cfg.AzureAPIVersion != "2025-01-01" {
+		t.Errorf("AzureAPIVersion = %v, want %v", cfg.AzureAPIVersion, "2025-01-01")
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: map[string]string{}},
+		{name: "single", value: "X-A=1", want: map[string]string{"X-A": "1"}},
+		{name: "multiple with spaces", value: " X-A = 1 , X-B=2,", want: map[string]string{"X-A": "1", "X-B": "2"}},
+		{name: "missing equals", value: "X-A", wantErr: true},
+		{name: "missing name", value: "=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHeaders(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseHeaders() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if len(got) != len(tt.want) {
+				t.Errorf("parseHeaders() = %v, want %v", got, tt.want)
+			}
+			for key, want := range tt.want {
+				if got[key] != want {
+					t.Errorf("parseHeaders()[%s] = %v, want %v", key, got[key], want)
+				}
 			}
 		})
 	}
 }
+
+func TestLoad_LocalBackend(t *testing.T) {
+	os.Unsetenv("OPENAI_API_KEY")
+	os.Setenv("STT_BACKEND", "local")
+	os.Setenv("STT_MODEL_PATH", "/models/ggml-base.en.bin")
+	os.Setenv("STT_WHISPER_BIN", "/opt/whisper.cpp/whisper-cli")
+	os.Setenv("STT_THREADS", "8")
+	defer func() {
+		os.Unsetenv("STT_BACKEND")
+		os.Unsetenv("STT_MODEL_PATH")
+		os.Unsetenv("STT_WHISPER_BIN")
+		os.Unsetenv("STT_THREADS")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+
+	if cfg.Backend != BackendLocal {
+		t.Errorf("Backend = %v, want %v", cfg.Backend, BackendLocal)
+	}
+
+	if cfg.ModelPath != "/models/ggml-base.en.bin" {
+		t.Errorf("ModelPath = %v, want %v", cfg.ModelPath, "/models/ggml-base.en.bin")
+	}
+
+	if cfg.WhisperBinary != "/opt/whisper.cpp/whisper-cli" {
+		t.Errorf("WhisperBinary = %v, want %v", cfg.WhisperBinary, "/opt/whisper.cpp/whisper-cli")
+	}
+
+	if cfg.Threads != 8 {
+		t.Errorf("Threads = %v, want %v", cfg.Threads, 8)
+	}
+}
+
+func TestLoad_Streaming(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	os.Setenv("STT_STREAMING", "true")
+	os.Setenv("STT_CHUNK_SECONDS", "5")
+	os.Setenv("STT_CHUNK_ON_SILENCE", "1")
+	os.Setenv("STT_STREAM_CONCURRENCY", "4")
+	defer func() {
+		os.Unsetenv("OPENAI_API_KEY")
+		os.Unsetenv("STT_STREAMING")
+		os.Unsetenv("STT_CHUNK_SECONDS")
+		os.Unsetenv("STT_CHUNK_ON_SILENCE")
+		os.Unsetenv("STT_STREAM_CONCURRENCY")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+
+	if !cfg.Streaming {
+		t.Error("Streaming = false, want true")
+	}
+
+	if cfg.ChunkDuration != 5*time.Second {
+		t.Errorf("ChunkDuration = %v, want %v", cfg.ChunkDuration, 5*time.Second)
+	}
+
+	if !cfg.ChunkOnSilence {
+		t.Error("ChunkOnSilence = false, want true")
+	}
+
+	if cfg.StreamConcurrency != 4 {
+		t.Errorf("StreamConcurrency = %v, want %v", cfg.StreamConcurrency, 4)
+	}
+}
+
+func TestLoad_VoiceActivity(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	os.Setenv("STT_VAD", "true")
+	os.Setenv("STT_VAD_THRESHOLD", "750")
+	os.Setenv("STT_AUTO_STOP_MS", "1500")
+	defer func() {
+		os.Unsetenv("OPENAI_API_KEY")
+		os.Unsetenv("STT_VAD")
+		os.Unsetenv("STT_VAD_THRESHOLD")
+		os.Unsetenv("STT_AUTO_STOP_MS")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+
+	if !cfg.VAD {
+		t.Error("VAD = false, want true")
+	}
+
+	if cfg.VADThreshold != 750 {
+		t.Errorf("VADThreshold = %v, want %v", cfg.VADThreshold, 750)
+	}
+
+	if cfg.AutoStop != 1500*time.Millisecond {
+		t.Errorf("AutoStop = %v, want %v", cfg.AutoStop, 1500*time.Millisecond)
+	}
+}
+
+func TestLoad_Hotkey(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	os.Setenv("STT_HOTKEY", "ctrl+alt+space")
+	os.Setenv("STT_HOTKEY_MODE", "push")
+	defer func() {
+		os.Unsetenv("OPENAI_API_KEY")
+		os.Unsetenv("STT_HOTKEY")
+		os.Unsetenv("STT_HOTKEY_MODE")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+
+	if cfg.Hotkey != "ctrl+alt+space" {
+		t.Errorf("Hotkey = %v, want %v", cfg.Hotkey, "ctrl+alt+space")
+	}
+
+	if cfg.HotkeyMode != hotkey.PushToTalk {
+		t.Errorf("HotkeyMode = %v, want %v", cfg.HotkeyMode, hotkey.PushToTalk)
+	}
+}
+
+func TestLoad_RecordingLimit(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if cfg.MaxRecording != 10*time.Minute {
+		t.Errorf("MaxRecording = %v, want %v", cfg.MaxRecording, 10*time.Minute)
+	}
+	if cfg.Overflow != "stop" {
+		t.Errorf("Overflow = %v, want %v", cfg.Overflow, "stop")
+	}
+
+	os.Setenv("STT_MAX_RECORDING_SECONDS", "90")
+	os.Setenv("STT_OVERFLOW", "drop-oldest")
+	defer func() {
+		os.Unsetenv("STT_MAX_RECORDING_SECONDS")
+		os.Unsetenv("STT_OVERFLOW")
+	}()
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if cfg.MaxRecording != 90*time.Second {
+		t.Errorf("MaxRecording = %v, want %v", cfg.MaxRecording, 90*time.Second)
+	}
+	if cfg.Overflow != "drop-oldest" {
+		t.Errorf("Overflow = %v, want %v", cfg.Overflow, "drop-oldest")
+	}
+}
+
+func TestLoad_AudioFormat(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if cfg.AudioSampleRate != 0 {
+		t.Errorf("AudioSampleRate = %v, want %v", cfg.AudioSampleRate, 0)
+	}
+	if cfg.AudioChannels != 1 {
+		t.Errorf("AudioChannels = %v, want %v", cfg.AudioChannels, 1)
+	}
+
+	os.Setenv("AUDIO_SAMPLE_RATE", "44100")
+	os.Setenv("AUDIO_CHANNELS", "2")
+	defer func() {
+		os.Unsetenv("AUDIO_SAMPLE_RATE")
+		os.Unsetenv("AUDIO_CHANNELS")
+	}()
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if cfg.AudioSampleRate != 44100 {
+		t.Errorf("AudioSampleRate = %v, want %v", cfg.AudioSampleRate, 44100)
+	}
+	if cfg.AudioChannels != 2 {
+		t.Errorf("AudioChannels = %v, want %v", cfg.AudioChannels, 2)
+	}
+}
+
+func TestLoad_AudioEncoding(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if cfg.AudioEncoding != "wav" {
+		t.Errorf("AudioEncoding = %v, want %v", cfg.AudioEncoding, "wav")
+	}
+
+	os.Setenv("STT_AUDIO_FORMAT", "flac")
+	defer os.Unsetenv("STT_AUDIO_FORMAT")
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if cfg.AudioEncoding != "flac" {
+		t.Errorf("AudioEncoding = %v, want %v", cfg.AudioEncoding, "flac")
+	}
+}
+
+func TestLoad_Splitting(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if cfg.MaxUploadMB != 24 {
+		t.Errorf("MaxUploadMB = %v, want %v", cfg.MaxUploadMB, 24)
+	}
+	if cfg.SplitConcurrency != 1 {
+		t.Errorf("SplitConcurrency = %v, want %v", cfg.SplitConcurrency, 1)
+	}
+
+	os.Setenv("STT_MAX_UPLOAD_MB", "0")
+	os.Setenv("STT_SPLIT_CONCURRENCY", "3")
+	defer func() {
+		os.Unsetenv("STT_MAX_UPLOAD_MB")
+		os.Unsetenv("STT_SPLIT_CONCURRENCY")
+	}()
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if cfg.MaxUploadMB != 0 {
+		t.Errorf("MaxUploadMB = %v, want %v", cfg.MaxUploadMB, 0)
+	}
+	if cfg.SplitConcurrency != 3 {
+		t.Errorf("SplitConcurrency = %v, want %v", cfg.SplitConcurrency, 3)
+	}
+}
+
+func TestLoad_History(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	os.Setenv("XDG_DATA_HOME", "/data")
+	defer func() {
+		os.Unsetenv("OPENAI_API_KEY")
+		os.Unsetenv("XDG_DATA_HOME")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if cfg.History {
+		t.Errorf("History = %v, want %v", cfg.History, false)
+	}
+	if cfg.HistoryDir != "/data/speech-to-clipboard/history" {
+		t.Errorf("HistoryDir = %v, want %v", cfg.HistoryDir, "/data/speech-to-clipboard/history")
+	}
+	if cfg.HistoryMaxEntries != 200 {
+		t.Errorf("HistoryMaxEntries = %v, want %v", cfg.HistoryMaxEntries, 200)
+	}
+
+	os.Setenv("STT_HISTORY", "true")
+	os.Setenv("STT_HISTORY_DIR", "/tmp/stt-history")
+	os.Setenv("STT_HISTORY_MAX_ENTRIES", "0")
+	defer func() {
+		os.Unsetenv("STT_HISTORY")
+		os.Unsetenv("STT_HISTORY_DIR")
+		os.Unsetenv("STT_HISTORY_MAX_ENTRIES")
+	}()
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if !cfg.History {
+		t.Errorf("History = %v, want %v", cfg.History, true)
+	}
+	if cfg.HistoryDir != "/tmp/stt-history" {
+		t.Errorf("HistoryDir = %v, want %v", cfg.HistoryDir, "/tmp/stt-history")
+	}
+	if cfg.HistoryMaxEntries != 0 {
+		t.Errorf("HistoryMaxEntries = %v, want %v", cfg.HistoryMaxEntries, 0)
+	}
+}
+
+func TestLoad_PostProcess(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	want := postprocess.Options{NormalizeWhitespace: true}
+	if !reflect.DeepEqual(cfg.PostProcess, want) {
+		t.Errorf("PostProcess = %+v, want %+v", cfg.PostProcess, want)
+	}
+
+	file := filepath.Join(t.TempDir(), "replacements.txt")
+	if err := os.WriteFile(file, []byte("# dictation\nnew line = \\n\ncomma = ,\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"STT_REPLACEMENTS_FILE":    file,
+		"STT_REPLACEMENTS":         "comma=;;period=.",
+		"STT_REMOVE_FILLERS":       "true",
+		"STT_FILLER_WORDS":         "um, like ,",
+		"STT_AGGRESSIVE_FILLERS":   "true",
+		"STT_MASK_PROFANITY":       "true",
+		"STT_PROFANITY_WORDS":      "heck",
+		"STT_NORMALIZE_WHITESPACE": "false",
+		"STT_CASE":                 "sentence",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	want = postprocess.Options{
+		Replacements: []postprocess.Replacement{
+			{From: "new line", To: "\n"},
+			{From: "comma", To: ","},
+			{From: "comma", To: ""},
+			{From: "period", To: "."},
+		},
+		RemoveFillers:     true,
+		Fillers:           []string{"um", "like"},
+		MaskProfanity:     true,
+		AggressiveFillers: true,
+		ProfanityWords:    []string{"heck"},
+		Case:              postprocess.CaseSentence,
+	}
+	if !reflect.DeepEqual(cfg.PostProcess, want) {
+		t.Errorf("PostProcess = %+v, want %+v", cfg.PostProcess, want)
+	}
+}
+
+func TestLoad_Dictation(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if cfg.Dictation || cfg.DictationCommands != nil {
+		t.Errorf("Dictation = %v with %v, want it off without commands", cfg.Dictation, cfg.DictationCommands)
+	}
+
+	file := filepath.Join(t.TempDir(), "commands.txt")
+	if err := os.WriteFile(file, []byte("period = <none>\nfull stop = . left cap\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("STT_DICTATION", "true")
+	os.Setenv("STT_DICTATION_COMMANDS_FILE", file)
+	defer func() {
+		os.Unsetenv("STT_DICTATION")
+		os.Unsetenv("STT_DICTATION_COMMANDS_FILE")
+	}()
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	want := []dictation.Command{
+		{Phrase: "period", Action: dictation.Remove},
+		{Phrase: "full stop", Text: ".", Attach: dictation.AttachLeft, Capitalize: true},
+	}
+	if !cfg.Dictation || !reflect.DeepEqual(cfg.DictationCommands, want) {
+		t.Errorf("Dictation = %v with %+v, want it on with %+v", cfg.Dictation, cfg.DictationCommands, want)
+	}
+
+	if err := os.WriteFile(file, []byte("period = . sticky\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Load() error = %v, want it to name the bad line", err)
+	}
+}
+
+func TestLoad_Clipboard(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if want := clipboard.DefaultOptions(); cfg.Clipboard != want {
+		t.Errorf("Clipboard = %+v, want %+v", cfg.Clipboard, want)
+	}
+
+	os.Setenv("STT_CLIPBOARD_BACKEND", "xsel")
+	os.Setenv("STT_OSC52_PASSTHROUGH", "screen")
+	os.Setenv("STT_OSC52_MAX_BYTES", "0")
+	os.Setenv("STT_CLIPBOARD_MODE", "append")
+	os.Setenv("STT_CLIPBOARD_SEPARATOR", `\n---\n`)
+	os.Setenv("STT_CLIPBOARD_HISTORY", "0")
+	os.Setenv("STT_CLIPBOARD_RESTORE_MS", "1500")
+	os.Setenv("STT_CLIPBOARD_RESTORE_ON_PASTE", "true")
+	defer func() {
+		os.Unsetenv("STT_CLIPBOARD_BACKEND")
+		os.Unsetenv("STT_OSC52_PASSTHROUGH")
+		os.Unsetenv("STT_OSC52_MAX_BYTES")
+		os.Unsetenv("STT_CLIPBOARD_MODE")
+		os.Unsetenv("STT_CLIPBOARD_SEPARATOR")
+		os.Unsetenv("STT_CLIPBOARD_HISTORY")
+		os.Unsetenv("STT_CLIPBOARD_RESTORE_MS")
+		os.Unsetenv("STT_CLIPBOARD_RESTORE_ON_PASTE")
+	}()
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	want := clipboard.Options{
+		Backend:          clipboard.BackendXsel,
+		Mode:             clipboard.Append,
+		Separator:        "\n---\n",
+		HistorySize:      0,
+		RestoreAfter:     1500 * time.Millisecond,
+		RestoreOnPaste:   true,
+		OSC52Passthrough: clipboard.PassthroughScreen,
+		OSC52MaxBytes:    0,
+	}
+	if cfg.Clipboard != want {
+		t.Errorf("Clipboard = %+v, want %+v", cfg.Clipboard, want)
+	}
+
+	opts, err := ClipboardOptions(Options{})
+	if err != nil {
+		t.Fatalf("ClipboardOptions() unexpected error = %v", err)
+	}
+	if opts.Backend != clipboard.BackendXsel || opts.OSC52Passthrough != clipboard.PassthroughScreen || opts.Mode != clipboard.Replace {
+		t.Errorf("ClipboardOptions() = %+v, want xsel and screen in replace mode", opts)
+	}
+	if opts.RestoreAfter != 1500*time.Millisecond || !opts.RestoreOnPaste {
+		t.Errorf("ClipboardOptions() = %+v, want the restore settings", opts)
+	}
+}
+
+func TestLoad_Outputs(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Outputs, []string{OutputClipboard}) || cfg.TypeBackend != "auto" || cfg.TypeKey != "" {
+		t.Errorf("Outputs = %v, TypeBackend = %v, TypeKey = %q, want clipboard only", cfg.Outputs, cfg.TypeBackend, cfg.TypeKey)
+	}
+
+	os.Setenv("STT_OUTPUTS", "type, clipboard")
+	os.Setenv("STT_TYPE_BACKEND", "wtype")
+	os.Setenv("STT_TYPE_KEY", "Return")
+	os.Setenv("STT_TYPE_NEWLINE", "shift+Return")
+	defer func() {
+		os.Unsetenv("STT_OUTPUTS")
+		os.Unsetenv("STT_TYPE_BACKEND")
+		os.Unsetenv("STT_TYPE_KEY")
+		os.Unsetenv("STT_TYPE_NEWLINE")
+	}()
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if !cfg.HasOutput(OutputType) || !cfg.HasOutput(OutputClipboard) {
+		t.Errorf("Outputs = %v, want type and clipboard", cfg.Outputs)
+	}
+	if cfg.TypeBackend != "wtype" || cfg.TypeKey != "Return" || cfg.TypeNewline != "shift+Return" {
+		t.Errorf("TypeBackend = %v, TypeKey = %v, TypeNewline = %v, want wtype, Return and shift+Return", cfg.TypeBackend, cfg.TypeKey, cfg.TypeNewline)
+	}
+
+	os.Setenv("STT_OUTPUTS", "type")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if cfg.HasOutput(OutputClipboard) {
+		t.Errorf("HasOutput(clipboard) = true for %v", cfg.Outputs)
+	}
+}
+
+func TestLoad_OutputSinks(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	os.Setenv("STT_OUTPUTS", "clipboard,stdout,file,fifo,webhook")
+	os.Setenv("STT_OUTPUT_FORMAT", "JSON")
+	os.Setenv("STT_OUTPUT_FILE", "/tmp/notes.txt")
+	os.Setenv("STT_OUTPUT_FIFO", "/tmp/transcripts")
+	os.Setenv("STT_WEBHOOK_URL", "https://hooks.example.com/notes")
+	defer func() {
+		os.Unsetenv("OPENAI_API_KEY")
+		os.Unsetenv("STT_OUTPUTS")
+		os.Unsetenv("STT_OUTPUT_FORMAT")
+		os.Unsetenv("STT_OUTPUT_FILE")
+		os.Unsetenv("STT_OUTPUT_FIFO")
+		os.Unsetenv("STT_WEBHOOK_URL")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	want := []string{OutputClipboard, OutputStdout, OutputFile, OutputFIFO, OutputWebhook}
+	if !reflect.DeepEqual(cfg.Outputs, want) {
+		t.Errorf("Outputs = %v, want %v", cfg.Outputs, want)
+	}
+	if cfg.OutputFormat != output.FormatJSON {
+		t.Errorf("OutputFormat = %v, want json", cfg.OutputFormat)
+	}
+	if cfg.OutputFile != "/tmp/notes.txt" || cfg.OutputFIFO != "/tmp/transcripts" || cfg.WebhookURL != "https://hooks.example.com/notes" {
+		t.Errorf("OutputFile = %v, OutputFIFO = %v, WebhookURL = %v", cfg.OutputFile, cfg.OutputFIFO, cfg.WebhookURL)
+	}
+}
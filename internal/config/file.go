@@ -0,0 +1,266 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// configFileNames are the names searched for in the config directory
+var configFileNames = []string{"config.toml", "config.yaml", "config.yml", "config.json"}
+
+// DefaultConfigDir returns speech-to-clipboard under $XDG_CONFIG_HOME, or
+// under ~/.config when that is unset
+func DefaultConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "speech-to-clipboard"), nil
+}
+
+// findConfigFile returns the config file in the default directory, or ""
+// when there is none
+func findConfigFile() (string, error) {
+	dir, err := DefaultConfigDir()
+	if err != nil {
+		// Without a home directory there is no default file to read
+		return "", nil
+	}
+
+	var found []string
+	for _, name := range configFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", nil
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("found several config files (%s); keep one", strings.Join(found, ", "))
+}
+
+// readConfigFile reads a flat JSON, TOML or YAML file, chosen by extension,
+// and returns its values keyed by environment variable name
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []fileEntry
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		entries, err = parseJSON(data)
+	case ".toml":
+		entries, err = parseLines(data, "=", parseTOMLValue)
+	case ".yaml", ".yml":
+		entries, err = parseLines(data, ":", parseYAMLValue)
+	default:
+		return nil, fmt.Errorf("config file %s: unknown format %q, want .toml, .yaml or .json", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	for _, e := range entries {
+		s, ok := settingForKey(e.key)
+		if !ok {
+			return nil, fmt.Errorf("config file %s: %sunknown setting %q", path, e.position(), e.key)
+		}
+		if _, dup := values[s.env]; dup {
+			return nil, fmt.Errorf("config file %s: %s%q is set more than once", path, e.position(), e.key)
+		}
+		values[s.env] = e.value
+	}
+	return values, nil
+}
+
+// fileEntry is one key and value read from a config file
+type fileEntry struct {
+	key   string
+	value string
+	// line is 0 for formats that do not track lines
+	line int
+}
+
+func (e fileEntry) position() string {
+	if e.line == 0 {
+		return ""
+	}
+	return fmt.Sprintf("line %d: ", e.line)
+}
+
+// parseJSON reads a JSON object whose values are strings, numbers or booleans
+func parseJSON(data []byte) ([]fileEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+
+	var entries []fileEntry
+	for key, raw := range obj {
+		var value string
+		switch v := raw.(type) {
+		case nil:
+			continue
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("%q must be a string, number or boolean", key)
+		}
+		entries = append(entries, fileEntry{key: key, value: value})
+	}
+	return entries, nil
+}
+
+// parseLines reads one "key<sep>value" pair per line, skipping blank lines
+// and # comments. Sections and nesting are not supported.
+func parseLines(data []byte, sep string, parseValue func(string) (string, error)) ([]fileEntry, error) {
+	var entries []fileEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("line %d: sections are not supported; put every setting at the top level", n)
+		}
+		if raw[0] == ' ' || raw[0] == '\t' {
+			return nil, fmt.Errorf("line %d: nested values are not supported; put every setting at the top level", n)
+		}
+
+		key, rest, ok := strings.Cut(line, sep)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key %s value", n, sep)
+		}
+		key = strings.TrimSpace(key)
+		if unquoted, err := strconv.Unquote(key); err == nil {
+			key = unquoted
+		}
+
+		value, err := parseValue(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", n, key, err)
+		}
+		entries = append(entries, fileEntry{key: key, value: value, line: n})
+	}
+	return entries, scanner.Err()
+}
+
+// parseTOMLValue reads a TOML string, number or boolean, followed by an
+// optional comment
+func parseTOMLValue(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		quoted, rest, err := cutQuoted(s, '"', false)
+		if err != nil {
+			return "", err
+		}
+		if err := checkComment(rest); err != nil {
+			return "", err
+		}
+		return strconv.Unquote(quoted)
+	case strings.HasPrefix(s, "'"):
+		// TOML literal strings have no escapes at all
+		quoted, rest, err := cutQuoted(s, '\'', false)
+		if err != nil {
+			return "", err
+		}
+		if err := checkComment(rest); err != nil {
+			return "", err
+		}
+		return quoted[1 : len(quoted)-1], nil
+	case strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{"):
+		return "", fmt.Errorf("arrays and tables are not supported")
+	}
+
+	value, _, _ := strings.Cut(s, "#")
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("missing value")
+	}
+	return value, nil
+}
+
+// parseYAMLValue reads a YAML scalar, plain or quoted, followed by an
+// optional comment
+func parseYAMLValue(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		quoted, rest, err := cutQuoted(s, '"', false)
+		if err != nil {
+			return "", err
+		}
+		if err := checkComment(rest); err != nil {
+			return "", err
+		}
+		return strconv.Unquote(quoted)
+	case strings.HasPrefix(s, "'"):
+		quoted, rest, err := cutQuoted(s, '\'', true)
+		if err != nil {
+			return "", err
+		}
+		if err := checkComment(rest); err != nil {
+			return "", err
+		}
+		// A doubled quote stands for one quote
+		return strings.ReplaceAll(quoted[1:len(quoted)-1], "''", "'"), nil
+	case strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") || strings.HasPrefix(s, "|") || strings.HasPrefix(s, ">"):
+		return "", fmt.Errorf("lists, maps and block scalars are not supported")
+	}
+
+	// A comment starts at " #"; a # inside a word is part of the value
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	} else if strings.HasPrefix(s, "#") {
+		s = ""
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// cutQuoted splits s, which starts with quote, after its closing quote.
+// Backslash escapes are skipped inside double quotes; with doubled set, as
+// in YAML single quotes, a doubled quote does not end the string.
+func cutQuoted(s string, quote byte, doubled bool) (quoted, rest string, err error) {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case doubled && s[i] == quote && i+1 < len(s) && s[i+1] == quote:
+			i++
+		case s[i] == quote:
+			return s[:i+1], s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+// checkComment reports anything but a comment after a quoted value
+func checkComment(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected %q after value", rest)
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMain points the default config location at an empty directory so a
+// developer's own config file cannot change test results
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "config-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadConfigFile(t *testing.T) {
+	want := map[string]string{
+		"STT_MODEL":         "gpt-4o-transcribe",
+		"STT_PROMPT":        `Say "hi" # not a comment`,
+		"STT_TEMPERATURE":   "0.2",
+		"STT_VAD":           "true",
+		"AUDIO_DEVICE":      "USB Headset",
+		"OPENAI_BASE_URL":   "http://localhost:8000/v1",
+		"STT_HOTKEY_MODE":   "push",
+		"STT_MAX_UPLOAD_MB": "10",
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "toml",
+			file: "config.toml",
+			content: `# speech-to-clipboard
+model = "gpt-4o-transcribe"
+prompt = "Say \"hi\" # not a comment"   # a comment
+temperature = 0.2
+vad = true
+audio_device = 'USB Headset'
+OPENAI_BASE_URL = "http://localhost:8000/v1"
+hotkey-mode = "push"
+"max_upload_mb" = 10
+`,
+		},
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: `---
+model: gpt-4o-transcribe
+prompt: 'Say "hi" # not a comment'
+temperature: 0.2 # a comment
+vad: true
+audio_device: USB Headset
+openai_base_url: "http://localhost:8000/v1"
+hotkey_mode: push
+max_upload_mb: 10
+`,
+		},
+		{
+			name: "json",
+			file: "config.json",
+			content: `{
+  "model": "gpt-4o-transcribe",
+  "prompt": "Say \"hi\" # not a comment",
+  "temperature": 0.2,
+  "vad": true,
+  "audio_device": "USB Headset",
+  "openai_base_url": "http://localhost:8000/v1",
+  "hotkey_mode": "push",
+  "max_upload_mb": 10,
+  "language": null
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readConfigFile(writeConfig(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatalf("readConfigFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("readConfigFile() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReadConfigFile_YAMLDoubledQuote(t *testing.T) {
+	got, err := readConfigFile(writeConfig(t, "c.yaml", "prompt: 'it''s'\n"))
+	if err != nil {
+		t.Fatalf("readConfigFile() error = %v", err)
+	}
+	if got["STT_PROMPT"] != "it's" {
+		t.Errorf("STT_PROMPT = %q, want %q", got["STT_PROMPT"], "it's")
+	}
+}
+
+func TestReadConfigFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantMsg string
+	}{
+		{name: "unknown toml key", file: "c.toml", content: "model = \"x\"\nmodle = \"y\"\n", wantMsg: `line 2: unknown setting "modle"`},
+		{name: "unknown json key", file: "c.json", content: `{"colour": "blue"}`, wantMsg: `unknown setting "colour"`},
+		{name: "duplicate key", file: "c.yaml", content: "model: a\nSTT_MODEL: b\n", wantMsg: "set more than once"},
+		{name: "toml section", file: "c.toml", content: "[audio]\ndevice = 1\n", wantMsg: "sections are not supported"},
+		{name: "yaml nesting", file: "c.yaml", content: "audio:\n  device: 1\n", wantMsg: "nested values"},
+		{name: "unterminated string", file: "c.toml", content: "prompt = \"oops\n", wantMsg: "line 1: prompt: unterminated string"},
+		{name: "escaped quote in toml literal", file: "c.toml", content: "prompt = 'it''s'\n", wantMsg: "line 1: prompt: unexpected"},
+		{name: "json object value", file: "c.json", content: `{"headers": {"X": "1"}}`, wantMsg: `"headers" must be a string`},
+		{name: "missing separator", file: "c.toml", content: "model\n", wantMsg: "expected key = value"},
+		{name: "unknown extension", file: "c.ini", content: "model=x\n", wantMsg: "unknown format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readConfigFile(writeConfig(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("readConfigFile() error = %v, want it to contain %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestLoadWithOptions_Precedence(t *testing.T) {
+	path := writeConfig(t, "config.toml", `
+openai_api_key = "sk-file-0123456789abcdef"
+model = "from-file"
+language = "de"
+prompt = "from file"
+`)
+	os.Setenv("STT_LANGUAGE", "fr")
+	os.Setenv("STT_PROMPT", "from env")
+	defer func() {
+		os.Unsetenv("STT_LANGUAGE")
+		os.Unsetenv("STT_PROMPT")
+	}()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := Flags(fs)
+	if err := fs.Parse([]string{"--config", path, "--prompt", "from flag"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	cfg, err := LoadWithOptions(*opts)
+	if err != nil {
+		t.Fatalf("LoadWithOptions() error = %v", err)
+	}
+
+	if cfg.OpenAIAPIKey != "sk-file-0123456789abcdef" {
+		t.Errorf("OpenAIAPIKey = %v, want the key from the file", cfg.OpenAIAPIKey)
+	}
+	if cfg.Model != "from-file" {
+		t.Errorf("Model = %v, want %v", cfg.Model, "from-file")
+	}
+	if cfg.Language != "fr" {
+		t.Errorf("Language = %v, want %v", cfg.Language, "fr")
+	}
+	if cfg.Prompt != "from flag" {
+		t.Errorf("Prompt = %v, want %v", cfg.Prompt, "from flag")
+	}
+	if cfg.ResponseFormat != "json" {
+		t.Errorf("ResponseFormat = %v, want %v", cfg.ResponseFormat, "json")
+	}
+	if cfg.ConfigFile != path {
+		t.Errorf("ConfigFile = %v, want %v", cfg.ConfigFile, path)
+	}
+
+	got := map[string]Setting{}
+	for _, s := range cfg.Settings() {
+		got[s.Env] = s
+	}
+	wantSources := map[string]Source{
+		"OPENAI_API_KEY":      SourceFile,
+		"STT_MODEL":           SourceFile,
+		"STT_LANGUAGE":        SourceEnv,
+		"STT_PROMPT":          SourceFlag,
+		"STT_RESPONSE_FORMAT": SourceDefault,
+	}
+	for env, want := range wantSources {
+		if got[env].Source != want {
+			t.Errorf("Settings() %s source = %v, want %v", env, got[env].Source, want)
+		}
+	}
+	if v := got["OPENAI_API_KEY"].Value; v != "****cdef" {
+		t.Errorf("Settings() API key = %q, want it redacted", v)
+	}
+	if len(got) != len(settings) {
+		t.Errorf("Settings() returned %d options, want %d", len(got), len(settings))
+	}
+}
+
+func TestLoadWithOptions_ErrorsNameTheSource(t *testing.T) {
+	os.Setenv("OPENAI_API_KEY", "test-api-key")
+	defer os.Unsetenv("OPENAI_API_KEY")
+
+	path := writeConfig(t, "config.yaml", "chunk_seconds: 0\n")
+
+	tests := []struct {
+		name    string
+		opts    Options
+		env     map[string]string
+		wantMsg string
+	}{
+		{name: "file", opts: Options{File: path}, wantMsg: "chunk_seconds in " + path},
+		{name: "env", opts: Options{File: path}, env: map[string]string{"STT_CHUNK_SECONDS": "-1"}, wantMsg: "STT_CHUNK_SECONDS must"},
+		{name: "flag", opts: Options{File: path, Flags: map[string]string{"STT_CHUNK_SECONDS": "x"}}, wantMsg: "--chunk-seconds must"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				os.Setenv(k, v)
+				defer os.Unsetenv(k)
+			}
+
+			_, err := LoadWithOptions(tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("LoadWithOptions() error = %v, want it to contain %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestLoad_DefaultConfigFile(t *testing.T) {
+	dir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "speech-to-clipboard")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tomlPath := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(tomlPath, []byte("openai_api_key = \"k\"\nmodel = \"m\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Model != "m" || cfg.ConfigFile != tomlPath {
+		t.Errorf("Load() model = %v from %v, want m from %v", cfg.Model, cfg.ConfigFile, tomlPath)
+	}
+
+	// Two candidates are ambiguous
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Load() with two config files error = nil, want error")
+	}
+}
+
+func TestFlags_SkipSecrets(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	Flags(fs)
+
+	if fs.Lookup("openai-api-key") != nil {
+		t.Error("Flags() registered a flag for the API key")
+	}
+	for _, name := range []string{"config", "model", "hotkey-mode", "audio-device", "history-dir"} {
+		if fs.Lookup(name) == nil {
+			t.Errorf("Flags() did not register --%s", name)
+		}
+	}
+}
+
+func TestFlags_Booleans(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := Flags(fs)
+
+	if err := fs.Parse([]string{"--streaming", "--vad=false", "--model", "whisper-1", "extra"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := map[string]string{"STT_STREAMING": "true", "STT_VAD": "false", "STT_MODEL": "whisper-1"}
+	if !reflect.DeepEqual(opts.Flags, want) {
+		t.Errorf("Flags = %v, want %v", opts.Flags, want)
+	}
+	if !reflect.DeepEqual(fs.Args(), []string{"extra"}) {
+		t.Errorf("Args() = %v, want [extra]", fs.Args())
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		secret string
+		want   string
+	}{
+		{secret: "", want: ""},
+		{secret: "short", want: "****"},
+		{secret: "sk-proj-0123456789abcdef", want: "****cdef"},
+	}
+
+	for _, tt := range tests {
+		if got := Redact(tt.secret); got != tt.want {
+			t.Errorf("Redact(%q) = %v, want %v", tt.secret, got, tt.want)
+		}
+	}
+}
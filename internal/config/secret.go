@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// KeySource supplies the API key from somewhere other than OPENAI_API_KEY
+type KeySource interface {
+	// Name describes the source in messages and in config show
+	Name() string
+	// Key returns the key, or "" when the source has none to offer
+	Key() (string, error)
+}
+
+// KeyFile reads the key from a file that other users cannot read
+type KeyFile struct {
+	Path string
+	// Optional sources report no key instead of failing when the file is missing
+	Optional bool
+}
+
+// Name returns the file path
+func (f KeyFile) Name() string {
+	return f.Path
+}
+
+// Key returns the trimmed contents of the file. World-readable files are
+// refused, since anyone on the machine could copy the key.
+func (f KeyFile) Key() (string, error) {
+	info, err := os.Stat(f.Path)
+	if errors.Is(err, os.ErrNotExist) && f.Optional {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := checkPrivate(f.Path, info); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("file is empty")
+	}
+	return key, nil
+}
+
+// KeyCommand runs a command, such as "pass show openai" or
+// "secret-tool lookup service openai", and uses its output as the key
+type KeyCommand struct {
+	Command string
+}
+
+// Name returns the command line
+func (c KeyCommand) Name() string {
+	return c.Command
+}
+
+// Key runs the command through the shell and returns its trimmed output
+func (c KeyCommand) Key() (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", c.Command)
+	} else {
+		cmd = exec.Command("sh", "-c", c.Command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+
+	// Password managers may print more after the first line
+	key, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("command printed nothing")
+	}
+	return key, nil
+}
+
+// DefaultKeyFile returns the file written by "config set-key"
+func DefaultKeyFile() (string, error) {
+	dir, err := DefaultConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "api_key"), nil
+}
+
+// SaveKey writes an API key to path, readable only by the current user
+func SaveKey(path, key string) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return fmt.Errorf("API key is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".api_key.tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp already restricts the file to its owner
+	if _, err := tmp.WriteString(key + "\n"); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+	return nil
+}
+
+// checkPrivate refuses files that any user on the machine can read. Windows
+// does not report permissions as mode bits, so nothing is checked there.
+func checkPrivate(path string, info os.FileInfo) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if info.Mode().Perm()&0o004 != 0 {
+		return fmt.Errorf("it contains an API key but is readable by every user; run chmod 600 %s", path)
+	}
+	return nil
+}
+
+// keySources returns the sources tried, in order, when OPENAI_API_KEY is
+// not set: OPENAI_API_KEY_FILE, OPENAI_API_KEY_COMMAND and then the file
+// written by "config set-key"
+func (l *layers) keySources() []KeySource {
+	var sources []KeySource
+	if path := l.lookup("OPENAI_API_KEY_FILE", ""); path != "" {
+		sources = append(sources, KeyFile{Path: path})
+	}
+	if command := l.lookup("OPENAI_API_KEY_COMMAND", ""); command != "" {
+		sources = append(sources, KeyCommand{Command: command})
+	}
+	if path, err := DefaultKeyFile(); err == nil {
+		sources = append(sources, KeyFile{Path: path, Optional: true})
+	}
+	return sources
+}
+
+// apiKey resolves the API key: OPENAI_API_KEY from the environment or the
+// config file, then each key source in turn. A source that fails stops the
+// search, so a misconfigured secret store is reported rather than skipped.
+func (l *layers) apiKey(sources []KeySource) (string, error) {
+	if key := l.lookup("OPENAI_API_KEY", ""); key != "" {
+		if s := l.resolved["OPENAI_API_KEY"]; s.Source == SourceFile {
+			info, err := os.Stat(l.filePath)
+			if err != nil {
+				return "", fmt.Errorf("failed to check config file: %w", err)
+			}
+			if err := checkPrivate(l.filePath, info); err != nil {
+				return "", fmt.Errorf("config file %s: %w", l.filePath, err)
+			}
+		}
+		return key, nil
+	}
+
+	if sources == nil {
+		sources = l.keySources()
+	}
+	for _, source := range sources {
+		key, err := source.Key()
+		if err != nil {
+			return "", fmt.Errorf("failed to read API key from %s: %w", source.Name(), err)
+		}
+		if key != "" {
+			l.resolved["OPENAI_API_KEY"] = Setting{
+				Key:    Key("OPENAI_API_KEY"),
+				Env:    "OPENAI_API_KEY",
+				Value:  key,
+				Source: SourceProvider,
+				Origin: source.Name(),
+			}
+			return key, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeKeySource returns a fixed key or error and counts its calls
+type fakeKeySource struct {
+	key   string
+	err   error
+	calls int
+}
+
+func (f *fakeKeySource) Name() string { return "fake" }
+
+func (f *fakeKeySource) Key() (string, error) {
+	f.calls++
+	return f.key, f.err
+}
+
+func TestLoadWithOptions_KeySources(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       string
+		sources   []*fakeKeySource
+		wantKey   string
+		wantErr   bool
+		wantCalls []int
+	}{
+		{
+			name:      "environment wins",
+			env:       "env-key",
+			sources:   []*fakeKeySource{{key: "provider-key"}},
+			wantKey:   "env-key",
+			wantCalls: []int{0},
+		},
+		{
+			name:      "first source with a key",
+			sources:   []*fakeKeySource{{}, {key: "second"}, {key: "third"}},
+			wantKey:   "second",
+			wantCalls: []int{1, 1, 0},
+		},
+		{
+			name:      "failing source stops the search",
+			sources:   []*fakeKeySource{{err: errors.New("locked")}, {key: "second"}},
+			wantErr:   true,
+			wantCalls: []int{1, 0},
+		},
+		{
+			name:      "no source has a key",
+			sources:   []*fakeKeySource{{}},
+			wantErr:   true,
+			wantCalls: []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				os.Setenv("OPENAI_API_KEY", tt.env)
+				defer os.Unsetenv("OPENAI_API_KEY")
+			}
+			var sources []KeySource
+			for _, s := range tt.sources {
+				sources = append(sources, s)
+			}
+
+			cfg, err := LoadWithOptions(Options{KeySources: sources})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.OpenAIAPIKey != tt.wantKey {
+				t.Errorf("OpenAIAPIKey = %v, want %v", cfg.OpenAIAPIKey, tt.wantKey)
+			}
+			for i, s := range tt.sources {
+				if s.calls != tt.wantCalls[i] {
+					t.Errorf("source %d called %d times, want %d", i, s.calls, tt.wantCalls[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadWithOptions_KeySourceShownAsProvider(t *testing.T) {
+	cfg, err := LoadWithOptions(Options{KeySources: []KeySource{&fakeKeySource{key: "sk-provider-0123456789"}}})
+	if err != nil {
+		t.Fatalf("LoadWithOptions() error = %v", err)
+	}
+
+	for _, s := range cfg.Settings() {
+		if s.Env != "OPENAI_API_KEY" {
+			continue
+		}
+		if s.Source != SourceProvider || s.Origin != "fake" || s.Value != "****6789" {
+			t.Errorf("Settings() API key = %+v, want redacted provider key from fake", s)
+		}
+	}
+}
+
+func TestLoadWithOptions_LocalBackendSkipsKeySources(t *testing.T) {
+	os.Setenv("STT_BACKEND", "local")
+	os.Setenv("STT_MODEL_PATH", "/models/ggml-base.en.bin")
+	defer func() {
+		os.Unsetenv("STT_BACKEND")
+		os.Unsetenv("STT_MODEL_PATH")
+	}()
+
+	source := &fakeKeySource{err: errors.New("should not be asked")}
+	if _, err := LoadWithOptions(Options{KeySources: []KeySource{source}}); err != nil {
+		t.Fatalf("LoadWithOptions() error = %v", err)
+	}
+	if source.calls != 0 {
+		t.Errorf("key source called %d times, want 0", source.calls)
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not mode bits on Windows")
+	}
+	dir := t.TempDir()
+
+	write := func(name, content string, mode os.FileMode) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		file    KeyFile
+		want    string
+		wantErr string
+	}{
+		{name: "private", file: KeyFile{Path: write("private", "sk-abc\n", 0o600)}, want: "sk-abc"},
+		{name: "group readable", file: KeyFile{Path: write("group", "sk-abc", 0o640)}, want: "sk-abc"},
+		{name: "world readable", file: KeyFile{Path: write("world", "sk-abc", 0o644)}, wantErr: "readable by every user"},
+		{name: "empty", file: KeyFile{Path: write("empty", "\n", 0o600)}, wantErr: "empty"},
+		{name: "missing", file: KeyFile{Path: filepath.Join(dir, "missing")}, wantErr: "no such file"},
+		{name: "missing optional", file: KeyFile{Path: filepath.Join(dir, "missing"), Optional: true}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.file.Key()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Key() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Key() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Key() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands run through sh")
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		want    string
+		wantErr bool
+	}{
+		{name: "first line", command: "printf 'sk-abc\\nlogin: me\\n'", want: "sk-abc"},
+		{name: "fails", command: "echo locked >&2; exit 1", wantErr: true},
+		{name: "no output", command: "true", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := KeyCommand{Command: tt.command}.Key()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Key() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Key() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_KeyFileAndCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("commands run through sh")
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("OPENAI_API_KEY_FILE", keyFile)
+	os.Setenv("OPENAI_API_KEY_COMMAND", "echo from-command")
+	defer func() {
+		os.Unsetenv("OPENAI_API_KEY_FILE")
+		os.Unsetenv("OPENAI_API_KEY_COMMAND")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.OpenAIAPIKey != "from-file" {
+		t.Errorf("OpenAIAPIKey = %v, want %v", cfg.OpenAIAPIKey, "from-file")
+	}
+
+	os.Unsetenv("OPENAI_API_KEY_FILE")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.OpenAIAPIKey != "from-command" {
+		t.Errorf("OpenAIAPIKey = %v, want %v", cfg.OpenAIAPIKey, "from-command")
+	}
+}
+
+func TestLoad_SavedKey(t *testing.T) {
+	path, err := DefaultKeyFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveKey(path, "  sk-saved\n"); err != nil {
+		t.Fatalf("SaveKey() error = %v", err)
+	}
+	defer os.Remove(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("SaveKey() mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.OpenAIAPIKey != "sk-saved" {
+		t.Errorf("OpenAIAPIKey = %v, want %v", cfg.OpenAIAPIKey, "sk-saved")
+	}
+
+	if err := SaveKey(path, " "); err == nil {
+		t.Error("SaveKey() with an empty key error = nil, want error")
+	}
+}
+
+func TestLoad_KeyInWorldReadableConfigFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not mode bits on Windows")
+	}
+
+	path := writeConfig(t, "config.toml", "openai_api_key = \"sk-abc\"\n")
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadWithOptions(Options{File: path}); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Errorf("LoadWithOptions() error = %v, want a permissions error", err)
+	}
+
+	// Without a key in it the file may be readable by anyone
+	if err := os.WriteFile(path, []byte("openai_base_url = \"http://localhost:8000/v1\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadWithOptions(Options{File: path}); err != nil {
+		t.Errorf("LoadWithOptions() error = %v", err)
+	}
+}
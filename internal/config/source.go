@@ -0,0 +1,282 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Source says where a setting's effective value came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+	// SourceProvider marks an API key read through a KeySource
+	SourceProvider Source = "provider"
+)
+
+// setting describes one configuration option. Its environment variable is
+// the canonical name; the file key and flag name are derived from it.
+type setting struct {
+	env   string
+	usage string
+	// secret values are redacted when shown and are never accepted as flags,
+	// which other users could read from the process list
+	secret bool
+	// boolean settings are flags that may be given without a value
+	boolean bool
+}
+
+// settings lists every option Load reads
+var settings = []setting{
+	{env: "OPENAI_API_KEY", usage: "OpenAI API key", secret: true},
+	{env: "OPENAI_API_KEY_FILE", usage: "file holding the API key, not readable by other users"},
+	{env: "OPENAI_API_KEY_COMMAND", usage: "command that prints the API key, e.g. pass show openai"},
+	{env: "STT_MODEL", usage: "transcription model"},
+	{env: "STT_LANGUAGE", usage: "spoken language as an ISO-639-1 code"},
+	{env: "STT_PROMPT", usage: "text that guides the model's style or vocabulary"},
+	{env: "STT_TEMPERATURE", usage: "sampling temperature between 0 and 1"},
+	{env: "STT_RESPONSE_FORMAT", usage: "json, text, srt, verbose_json or vtt"},
+	{env: "OPENAI_BASE_URL", usage: "API root URL"},
+	{env: "OPENAI_ORG_ID", usage: "OpenAI organization header"},
+	{env: "OPENAI_PROJECT_ID", usage: "OpenAI project header"},
+	{env: "STT_HEADERS", usage: "extra request headers as Name=Value,...", secret: true},
+	{env: "AZURE_OPENAI_DEPLOYMENT", usage: "Azure OpenAI deployment name"},
+	{env: "AZURE_OPENAI_API_VERSION", usage: "Azure OpenAI api-version"},
+	{env: "STT_MAX_RETRIES", usage: "retries for failed requests"},
+	{env: "STT_AUDIO_FORMAT", usage: "upload encoding, wav or flac"},
+	{env: "STT_MAX_UPLOAD_MB", usage: "split recordings larger than this; 0 disables"},
+	{env: "STT_SPLIT_CONCURRENCY", usage: "split segments transcribed at once"},
+	{env: "STT_BACKEND", usage: "openai or local"},
+	{env: "STT_MODEL_PATH", usage: "GGML model file for the local backend"},
+	{env: "STT_WHISPER_BIN", usage: "whisper.cpp command line tool"},
+	{env: "STT_THREADS", usage: "CPU threads for local transcription"},
+	{env: "STT_STREAMING", usage: "transcribe in chunks while recording", boolean: true},
+	{env: "STT_CHUNK_SECONDS", usage: "longest streamed chunk in seconds"},
+	{env: "STT_CHUNK_ON_SILENCE", usage: "cut streamed chunks at pauses", boolean: true},
+	{env: "STT_STREAM_CONCURRENCY", usage: "streamed chunks transcribed at once"},
+	{env: "STT_VAD", usage: "trim silence and skip uploads without speech", boolean: true},
+	{env: "STT_VAD_THRESHOLD", usage: "RMS level that counts as speech"},
+	{env: "STT_AUTO_STOP_MS", usage: "stop after this much silence; 0 disables"},
+	{env: "STT_HOTKEY", usage: "global hotkey such as ctrl+alt+space"},
+	{env: "STT_HOTKEY_MODE", usage: "toggle or push"},
+	{env: "AUDIO_DEVICE", usage: "input device index or name"},
+	{env: "AUDIO_SAMPLE_RATE", usage: "capture rate in Hz; 0 uses the device rate"},
+	{env: "AUDIO_CHANNELS", usage: "channels to capture"},
+	{env: "STT_MAX_RECORDING_SECONDS", usage: "longest recording kept in memory"},
+	{env: "STT_OVERFLOW", usage: "stop or drop-oldest at the recording limit"},
+	{env: "STT_HISTORY", usage: "save recordings and transcripts", boolean: true},
+	{env: "STT_HISTORY_DIR", usage: "directory for saved recordings"},
+	{env: "STT_HISTORY_MAX_ENTRIES", usage: "recordings kept in history; 0 keeps all"},
+	{env: "STT_REPLACEMENTS", usage: "phrase=text replacements separated by semicolons"},
+	{env: "STT_REPLACEMENTS_FILE", usage: "file with one phrase=text replacement per line"},
+	{env: "STT_REMOVE_FILLERS", usage: "remove filler words such as um and uh", boolean: true},
+	{env: "STT_FILLER_WORDS", usage: "comma-separated filler words to remove"},
+	{env: "STT_AGGRESSIVE_FILLERS", usage: "also remove er, ah and mm, which are sometimes words", boolean: true},
+	{env: "STT_MASK_PROFANITY", usage: "mask swear words with asterisks", boolean: true},
+	{env: "STT_PROFANITY_WORDS", usage: "comma-separated words to mask"},
+	{env: "STT_NORMALIZE_WHITESPACE", usage: "collapse spaces and trim the transcript", boolean: true},
+	{env: "STT_CASE", usage: "capitalization: sentence, lower, upper or none"},
+	{env: "STT_DICTATION", usage: "interpret spoken commands such as \"period\" and \"new line\"", boolean: true},
+	{env: "STT_DICTATION_COMMANDS_FILE", usage: "file adding to or changing the dictation commands"},
+	{env: "STT_CLIPBOARD_MODE", usage: "replace, append to or prepend to the clipboard content"},
+	{env: "STT_CLIPBOARD_BACKEND", usage: "clipboard tool: auto, wl-clipboard, xclip, xsel, tmux, osc52 or system"},
+	{env: "STT_OSC52_PASSTHROUGH", usage: "wrap OSC 52 for a multiplexer: auto, none, tmux or screen"},
+	{env: "STT_OSC52_MAX_BYTES", usage: "largest transcript copied with OSC 52; 0 removes the limit"},
+	{env: "STT_CLIPBOARD_SEPARATOR", usage: "text between appended or prepended transcripts"},
+	{env: "STT_CLIPBOARD_HISTORY", usage: "transcripts kept for recall at the prompt"},
+	{env: "STT_CLIPBOARD_RESTORE_MS", usage: "restore the previous clipboard content after this long; 0 disables"},
+	{env: "STT_CLIPBOARD_RESTORE_ON_PASTE", usage: "restore the previous clipboard content once the transcript was pasted", boolean: true},
+	{env: "STT_OUTPUTS", usage: "comma-separated transcript destinations: clipboard, type, stdout, file, fifo, webhook"},
+	{env: "STT_TYPE_BACKEND", usage: "typing tool: auto, xdotool, ydotool or wtype"},
+	{env: "STT_TYPE_KEY", usage: "key pressed after typing a transcript, such as Return"},
+	{env: "STT_TYPE_NEWLINE", usage: "key pressed for each line break when typing, such as shift+Return; empty types a space"},
+	{env: "STT_OUTPUT_FORMAT", usage: "format of the stdout, file and fifo outputs: plain or json"},
+	{env: "STT_OUTPUT_FILE", usage: "file the file output appends transcripts to"},
+	{env: "STT_OUTPUT_FIFO", usage: "named pipe the fifo output writes transcripts to"},
+	{env: "STT_WEBHOOK_URL", usage: "URL the webhook output posts transcripts to"},
+}
+
+// Key returns the config file key for an environment variable: lower case,
+// without the STT_ prefix
+func Key(env string) string {
+	return strings.ToLower(strings.TrimPrefix(env, "STT_"))
+}
+
+// flagName returns the command-line flag for an environment variable
+func flagName(env string) string {
+	return strings.ReplaceAll(Key(env), "_", "-")
+}
+
+// settingForKey finds the setting named by a config file key. Keys are
+// matched ignoring case and the difference between dashes and underscores,
+// and environment variable names are accepted too.
+func settingForKey(key string) (setting, bool) {
+	norm := strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, s := range settings {
+		if norm == Key(s.env) || norm == strings.ToLower(s.env) {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// Options holds configuration that does not come from the environment
+type Options struct {
+	// File is the config file to read; empty searches the default locations
+	File string
+	// Flags maps environment variable names to values given on the command line
+	Flags map[string]string
+	// KeySources replace the sources tried when OPENAI_API_KEY is unset;
+	// nil uses OPENAI_API_KEY_FILE, OPENAI_API_KEY_COMMAND and DefaultKeyFile
+	KeySources []KeySource
+}
+
+// Flags registers a command-line flag for every setting, plus --config for
+// the config file, and returns the options they fill in once fs is parsed
+func Flags(fs *flag.FlagSet) *Options {
+	opts := &Options{Flags: map[string]string{}}
+	fs.Func("config", "read settings from `file` instead of the default location", func(v string) error {
+		opts.File = v
+		return nil
+	})
+
+	for _, s := range settings {
+		if s.secret {
+			continue
+		}
+		env := s.env
+		set := func(v string) error {
+			opts.Flags[env] = v
+			return nil
+		}
+		if s.boolean {
+			fs.BoolFunc(flagName(env), s.usage+" (overrides "+env+")", set)
+		} else {
+			fs.Func(flagName(env), s.usage+" (overrides "+env+")", set)
+		}
+	}
+	return opts
+}
+
+// Setting is the effective value of one option and where it came from
+type Setting struct {
+	Key    string
+	Env    string
+	Value  string
+	Source Source
+	// Origin names the file or flag a value came from
+	Origin string
+}
+
+// layers resolves settings from, in order of precedence, command-line
+// flags, the environment, the config file and the defaults given by Load.
+// It records every lookup so the effective configuration can be shown.
+type layers struct {
+	flags    map[string]string
+	file     map[string]string
+	filePath string
+
+	resolved map[string]Setting
+}
+
+// File returns the config file selected by opts: the --config flag, then
+// STT_CONFIG, then the first file found in DefaultConfigDir. It returns ""
+// when there is none.
+func File(opts Options) (string, error) {
+	if opts.File != "" {
+		return opts.File, nil
+	}
+	if path := os.Getenv("STT_CONFIG"); path != "" {
+		return path, nil
+	}
+	return findConfigFile()
+}
+
+// newLayers reads the config file selected by opts
+func newLayers(opts Options) (*layers, error) {
+	l := &layers{flags: opts.Flags, resolved: map[string]Setting{}}
+
+	path, err := File(opts)
+	if err != nil || path == "" {
+		return l, err
+	}
+
+	values, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l.file, l.filePath = values, path
+	return l, nil
+}
+
+// lookup returns the effective value of a setting, or def when none of the
+// layers sets it
+func (l *layers) lookup(env, def string) string {
+	s := Setting{Key: Key(env), Env: env, Value: def, Source: SourceDefault}
+	if v, ok := l.flags[env]; ok {
+		s.Value, s.Source, s.Origin = v, SourceFlag, "--"+flagName(env)
+	} else if v := os.Getenv(env); v != "" {
+		s.Value, s.Source, s.Origin = v, SourceEnv, env
+	} else if v := l.file[env]; v != "" {
+		s.Value, s.Source, s.Origin = v, SourceFile, l.filePath
+	}
+
+	l.resolved[env] = s
+	return s.Value
+}
+
+// name describes a setting for error messages by where its value was set,
+// so a mistake can be found: the flag, the file key or the variable
+func (l *layers) name(env string) string {
+	switch s := l.resolved[env]; s.Source {
+	case SourceFlag:
+		return s.Origin
+	case SourceFile:
+		return fmt.Sprintf("%s in %s", s.Key, s.Origin)
+	}
+	return env
+}
+
+func (l *layers) getInt(env string, def int) (int, error) {
+	return strconv.Atoi(l.lookup(env, strconv.Itoa(def)))
+}
+
+func (l *layers) getBool(env string, def bool) (bool, error) {
+	return strconv.ParseBool(l.lookup(env, strconv.FormatBool(def)))
+}
+
+// settings returns every setting in registry order, with secret values
+// redacted. Settings Load did not need are resolved without a default.
+func (l *layers) settings() []Setting {
+	out := make([]Setting, 0, len(settings))
+	for _, s := range settings {
+		r, ok := l.resolved[s.env]
+		if !ok {
+			l.lookup(s.env, "")
+			r = l.resolved[s.env]
+		}
+		if s.secret {
+			r.Value = Redact(r.Value)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Redact hides a secret, keeping the last four characters of long values
+// so keys can be told apart
+func Redact(secret string) string {
+	switch {
+	case secret == "":
+		return ""
+	case len(secret) >= 16:
+		return "****" + secret[len(secret)-4:]
+	}
+	return "****"
+}
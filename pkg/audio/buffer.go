@@ -0,0 +1,115 @@
+package audio
+
+import (
+	"fmt"
+	"sync"
+)
+
+// OverflowPolicy decides what happens when a recording reaches its size limit
+type OverflowPolicy int
+
+const (
+	// OverflowStop keeps the start of the recording, discards further audio
+	// and asks the caller to stop
+	OverflowStop OverflowPolicy = iota
+	// OverflowDropOldest keeps recording and discards the oldest audio
+	OverflowDropOldest
+)
+
+func (p OverflowPolicy) String() string {
+	if p == OverflowDropOldest {
+		return "drop-oldest"
+	}
+	return "stop"
+}
+
+// ParseOverflowPolicy reads a policy name as printed by String
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch name {
+	case "stop":
+		return OverflowStop, nil
+	case "drop-oldest":
+		return OverflowDropOldest, nil
+	}
+	return 0, fmt.Errorf("invalid overflow policy %q, want stop or drop-oldest", name)
+}
+
+// ringBuffer stores samples up to a fixed capacity. It is safe for one
+// writer (the audio callback) and concurrent readers. Memory grows with the
+// recording and never exceeds capacity; once full it either rejects writes or
+// wraps around, depending on the policy.
+type ringBuffer struct {
+	mu       sync.Mutex
+	data     []int16
+	start    int
+	capacity int
+	policy   OverflowPolicy
+}
+
+// newRingBuffer creates a buffer holding at most capacity samples; a
+// capacity of zero or less means unbounded
+func newRingBuffer(capacity int, policy OverflowPolicy) *ringBuffer {
+	return &ringBuffer{capacity: capacity, policy: policy}
+}
+
+// Write stores samples. Under OverflowStop it returns false once the buffer
+// is full, after which further samples are discarded.
+func (r *ringBuffer) Write(samples []int16) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.capacity <= 0 {
+		r.data = append(r.data, samples...)
+		return true
+	}
+
+	if n := min(r.capacity-len(r.data), len(samples)); n > 0 {
+		r.data = append(r.data, samples[:n]...)
+		samples = samples[n:]
+	}
+
+	if len(r.data) < r.capacity {
+		return true
+	}
+	if r.policy == OverflowStop {
+		return false
+	}
+
+	// Overwrite the oldest samples, keeping only the newest capacity of them
+	if len(samples) > r.capacity {
+		samples = samples[len(samples)-r.capacity:]
+	}
+	for len(samples) > 0 {
+		n := copy(r.data[r.start:], samples)
+		r.start = (r.start + n) % r.capacity
+		samples = samples[n:]
+	}
+	return true
+}
+
+// Snapshot returns a copy of the stored samples, oldest first
+func (r *ringBuffer) Snapshot() []int16 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]int16, len(r.data))
+	n := copy(out, r.data[r.start:])
+	copy(out[n:], r.data[:r.start])
+	return out
+}
+
+// Len returns the number of stored samples
+func (r *ringBuffer) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.data)
+}
+
+// Reset empties the buffer and releases its memory
+func (r *ringBuffer) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.data = nil
+	r.start = 0
+}
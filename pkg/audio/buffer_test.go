@@ -0,0 +1,119 @@
+package audio
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRingBuffer_Unbounded(t *testing.T) {
+	r := newRingBuffer(0, OverflowStop)
+	for i := 0; i < 100; i++ {
+		if !r.Write([]int16{int16(i)}) {
+			t.Fatal("Write() rejected samples in an unbounded buffer")
+		}
+	}
+
+	if r.Len() != 100 {
+		t.Errorf("Len() = %d, want 100", r.Len())
+	}
+}
+
+func TestRingBuffer_OverflowStop(t *testing.T) {
+	r := newRingBuffer(5, OverflowStop)
+
+	if !r.Write([]int16{1, 2, 3}) {
+		t.Fatal("Write() rejected samples below capacity")
+	}
+
+	if r.Write([]int16{4, 5, 6, 7}) {
+		t.Error("Write() accepted samples past capacity")
+	}
+
+	if got := fmt.Sprint(r.Snapshot()); got != "[1 2 3 4 5]" {
+		t.Errorf("Snapshot() = %v, want [1 2 3 4 5]", got)
+	}
+
+	if r.Write([]int16{8}) {
+		t.Error("Write() accepted samples into a full buffer")
+	}
+}
+
+func TestRingBuffer_DropOldest(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes [][]int16
+		want   string
+	}{
+		{name: "below capacity", writes: [][]int16{{1, 2}, {3}}, want: "[1 2 3]"},
+		{name: "wraps once", writes: [][]int16{{1, 2, 3}, {4, 5, 6, 7}}, want: "[3 4 5 6 7]"},
+		{name: "wraps many times", writes: [][]int16{{1, 2, 3, 4, 5}, {6, 7}, {8, 9}, {10}}, want: "[6 7 8 9 10]"},
+		{name: "write larger than capacity", writes: [][]int16{{1}, {2, 3, 4, 5, 6, 7, 8, 9}}, want: "[5 6 7 8 9]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRingBuffer(5, OverflowDropOldest)
+			for _, w := range tt.writes {
+				if !r.Write(w) {
+					t.Fatal("Write() rejected samples under drop-oldest")
+				}
+			}
+
+			if got := fmt.Sprint(r.Snapshot()); got != tt.want {
+				t.Errorf("Snapshot() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRingBuffer_Reset(t *testing.T) {
+	r := newRingBuffer(3, OverflowDropOldest)
+	r.Write([]int16{1, 2, 3, 4})
+	r.Reset()
+
+	if r.Len() != 0 {
+		t.Errorf("Len() after Reset = %d, want 0", r.Len())
+	}
+
+	r.Write([]int16{9})
+	if got := fmt.Sprint(r.Snapshot()); got != "[9]" {
+		t.Errorf("Snapshot() = %v, want [9]", got)
+	}
+}
+
+func TestRingBuffer_ConcurrentAccess(t *testing.T) {
+	r := newRingBuffer(1000, OverflowDropOldest)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			r.Write(constantFrame(17, int16(i)))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			if n := len(r.Snapshot()); n > 1000 {
+				t.Errorf("Snapshot() length = %d, want at most 1000", n)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for _, p := range []OverflowPolicy{OverflowStop, OverflowDropOldest} {
+		got, err := ParseOverflowPolicy(p.String())
+		if err != nil || got != p {
+			t.Errorf("ParseOverflowPolicy(%q) = %v, %v", p.String(), got, err)
+		}
+	}
+
+	if _, err := ParseOverflowPolicy("explode"); err == nil {
+		t.Error("ParseOverflowPolicy(\"explode\") expected error, got nil")
+	}
+}
@@ -3,6 +3,11 @@ package audio
 import (
 	"fmt"
 	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"speech-to-clipboard/pkg/audio/wav"
 
 	"github.com/gordonklaus/portaudio"
 )
@@ -13,6 +18,32 @@ const (
 	Channels        = 1
 )
 
+// streamFrameBuffer is how many callback frames may queue up for the chunker
+// and silence monitor before new frames are dropped; about a minute of audio
+// at the defaults. Dropped frames are counted, see StreamingCapturer.Dropped.
+const streamFrameBuffer = 1024
+
+// DefaultMaxDuration bounds a recording when CaptureConfig.MaxDuration is zero
+const DefaultMaxDuration = 10 * time.Minute
+
+// CaptureConfig holds optional capture behaviour
+type CaptureConfig struct {
+	// Device selects the input by index or name (see SelectDevice); empty uses the system default
+	Device string
+	// AutoStop ends a recording after this much silence following speech; zero disables it
+	AutoStop time.Duration
+	// VAD configures the speech detector used for AutoStop
+	VAD VADConfig
+	// MaxDuration limits how much audio is kept in memory; zero means DefaultMaxDuration
+	MaxDuration time.Duration
+	// Overflow decides what happens when MaxDuration is reached
+	Overflow OverflowPolicy
+	// Input is the format requested from the device. A zero SampleRate uses
+	// the device's native rate and zero Channels means mono. Audio is always
+	// converted to TargetFormat before it is stored.
+	Input Format
+}
+
 // Capturer handles microphone audio capture
 type Capturer interface {
 	Start() error
@@ -21,50 +52,281 @@ type Capturer interface {
 	IsRecording() bool
 }
 
+// StreamingCapturer is a Capturer that can hand out audio while recording
+type StreamingCapturer interface {
+	Capturer
+	// StartStreaming begins recording and returns a channel of chunks cut
+	// according to opts. The remaining audio is flushed and the channel closed
+	// after Stop. GetAudioData still returns the full recording.
+	StartStreaming(opts ChunkOptions) (<-chan []int16, error)
+	// Dropped returns how much audio of the current or last recording never
+	// reached the chunks because their reader fell behind
+	Dropped() time.Duration
+}
+
+// AutoStopCapturer is a Capturer that can decide by itself that a recording
+// should end
+type AutoStopCapturer interface {
+	Capturer
+	// AutoStopped returns a channel closed when the current recording has
+	// been silent for the configured time after speech, or has filled its
+	// buffer under OverflowStop. The caller still has to call Stop. The
+	// channel is nil when neither can happen.
+	AutoStopped() <-chan struct{}
+}
+
+// inputStream is the part of *portaudio.Stream the capturer uses. Stop must
+// not return while the callback is still running.
+type inputStream interface {
+	Start() error
+	Stop() error
+	Close() error
+}
+
 type portAudioCapturer struct {
-	stream    *portaudio.Stream
-	buffer    []int16
-	recording bool
+	cfg    CaptureConfig
+	device *portaudio.DeviceInfo
+	input  Format
+	open   func(callback func(in []int16)) (inputStream, error)
+	buffer *ringBuffer
+	// dropped counts the samples left out of frames; the callback updates it
+	dropped atomic.Int64
+
+	// mu guards the fields below. The audio callback never takes it, so Stop
+	// can hold it while waiting for the callback to return.
+	mu          sync.Mutex
+	stream      inputStream
+	flush       func()
+	recording   bool
+	frames      chan []int16
+	autoStopped chan struct{}
 }
 
 // NewCapturer creates a new audio capturer
 func NewCapturer() (Capturer, error) {
+	return NewCapturerWithConfig(CaptureConfig{})
+}
+
+// NewCapturerWithConfig creates an audio capturer with optional behaviour enabled
+func NewCapturerWithConfig(cfg CaptureConfig) (Capturer, error) {
 	if err := portaudio.Initialize(); err != nil {
 		return nil, fmt.Errorf("failed to initialize portaudio: %w", err)
 	}
 
+	var device *portaudio.DeviceInfo
+	var err error
+	if cfg.Device != "" {
+		device, err = findDevice(cfg.Device)
+	} else if device, err = portaudio.DefaultInputDevice(); err != nil {
+		err = fmt.Errorf("failed to find default input device: %w", err)
+	}
+	if err != nil {
+		_ = portaudio.Terminate()
+		return nil, err
+	}
+
+	input := cfg.Input
+	if input.SampleRate == 0 {
+		input.SampleRate = int(device.DefaultSampleRate)
+	}
+	if input.Channels == 0 {
+		input.Channels = 1
+	}
+	if err := input.Validate(); err != nil {
+		_ = portaudio.Terminate()
+		return nil, fmt.Errorf("unsupported input format: %w", err)
+	}
+
+	c := newCapturer(cfg, input)
+	c.device = device
+	c.open = c.openStream
+	return c, nil
+}
+
+// newCapturer sets up a capturer for audio arriving in the input format
+// without touching portaudio; the caller provides c.open
+func newCapturer(cfg CaptureConfig, input Format) *portAudioCapturer {
+	if cfg.MaxDuration <= 0 {
+		cfg.MaxDuration = DefaultMaxDuration
+	}
+
 	return &portAudioCapturer{
-		buffer:    make([]int16, 0),
-		recording: false,
-	}, nil
+		cfg:    cfg,
+		input:  input,
+		buffer: newRingBuffer(durationToSamples(cfg.MaxDuration, SampleRate)*Channels, cfg.Overflow),
+	}
+}
+
+// findDevice resolves a device selector to a portaudio device handle
+func findDevice(selector string) (*portaudio.DeviceInfo, error) {
+	devices, handles, err := inputDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := SelectDevice(devices, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, d := range devices {
+		if d.Index == selected.Index {
+			return handles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("device %d disappeared", selected.Index)
 }
 
 // Start begins capturing audio from the microphone
 func (c *portAudioCapturer) Start() error {
+	_, err := c.start(nil)
+	return err
+}
+
+// StartStreaming begins capturing audio and delivers it in chunks while recording
+func (c *portAudioCapturer) StartStreaming(opts ChunkOptions) (<-chan []int16, error) {
+	return c.start(&opts)
+}
+
+// AutoStopped returns a channel closed once the recording should end
+func (c *portAudioCapturer) AutoStopped() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.autoStopped
+}
+
+// start opens the input stream. When streaming or auto-stop is enabled every
+// callback buffer is also copied, without blocking the audio thread, to a
+// goroutine that feeds the chunker and silence monitor.
+func (c *portAudioCapturer) start(chunkOpts *ChunkOptions) (<-chan []int16, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.recording {
-		return fmt.Errorf("already recording")
+		return nil, fmt.Errorf("already recording")
+	}
+
+	c.buffer.Reset()
+	c.dropped.Store(0)
+
+	var frames chan []int16
+	var chunks chan []int16
+	if chunkOpts != nil || c.cfg.AutoStop > 0 {
+		frames = make(chan []int16, streamFrameBuffer)
+	}
+	if chunkOpts != nil {
+		chunks = make(chan []int16)
 	}
 
-	c.buffer = make([]int16, 0)
+	var autoStopped chan struct{}
+	var once sync.Once
+	signalStop := func() {}
+	if c.cfg.AutoStop > 0 || c.cfg.Overflow == OverflowStop {
+		autoStopped = make(chan struct{})
+		signalStop = func() { once.Do(func() { close(autoStopped) }) }
+	}
+
+	buffer := c.buffer
+	conv := newConverter(c.input, SampleRate)
+	store := func(samples []int16) {
+		if !buffer.Write(samples) {
+			signalStop()
+			return
+		}
+		if frames != nil && len(samples) > 0 {
+			frame := make([]int16, len(samples))
+			copy(frame, samples)
+			select {
+			case frames <- frame:
+			default:
+				c.dropped.Add(int64(len(frame)))
+			}
+		}
+	}
 
-	stream, err := portaudio.OpenDefaultStream(Channels, 0, float64(SampleRate), FramesPerBuffer, func(in []int16) {
-		c.buffer = append(c.buffer, in...)
+	stream, err := c.open(func(in []int16) {
+		store(conv.Process(in))
 	})
 	if err != nil {
-		return fmt.Errorf("failed to open stream: %w", err)
+		return nil, fmt.Errorf("failed to open stream: %w", err)
 	}
 
-	c.stream = stream
-	if err := c.stream.Start(); err != nil {
-		return fmt.Errorf("failed to start stream: %w", err)
+	if err := stream.Start(); err != nil {
+		_ = stream.Close()
+		return nil, fmt.Errorf("failed to start stream: %w", err)
 	}
 
+	if frames != nil {
+		go c.watch(frames, chunkOpts, chunks, signalStop)
+	}
+
+	c.stream = stream
+	c.flush = func() { store(conv.Flush()) }
+	c.frames = frames
+	c.autoStopped = autoStopped
 	c.recording = true
-	return nil
+	return chunks, nil
+}
+
+// Dropped returns the duration of the samples the watcher never saw
+func (c *portAudioCapturer) Dropped() time.Duration {
+	return time.Duration(c.dropped.Load()) * time.Second / SampleRate
+}
+
+// openStream opens the input device in its capture format. The buffer size
+// scales with the rate so callbacks arrive as often as at SampleRate.
+func (c *portAudioCapturer) openStream(callback func(in []int16)) (inputStream, error) {
+	params := portaudio.StreamParameters{
+		Input: portaudio.StreamDeviceParameters{
+			Device:   c.device,
+			Channels: c.input.Channels,
+			Latency:  c.device.DefaultLowInputLatency,
+		},
+		SampleRate:      float64(c.input.SampleRate),
+		FramesPerBuffer: FramesPerBuffer * c.input.SampleRate / SampleRate,
+	}
+	return portaudio.OpenStream(params, callback)
+}
+
+// watch consumes copied frames until Stop closes the frames channel. It cuts
+// chunks when chunks is non-nil and calls signalStop when auto-stop is
+// enabled and the silence monitor fires.
+func (c *portAudioCapturer) watch(frames <-chan []int16, chunkOpts *ChunkOptions, chunks chan<- []int16, signalStop func()) {
+	var chunker *Chunker
+	if chunks != nil {
+		defer close(chunks)
+		chunker = NewChunker(*chunkOpts, SampleRate)
+	}
+
+	var monitor *SilenceMonitor
+	if c.cfg.AutoStop > 0 {
+		monitor = NewSilenceMonitor(NewVAD(c.cfg.VAD, SampleRate), c.cfg.AutoStop, SampleRate)
+	}
+
+	for frame := range frames {
+		if monitor != nil && monitor.Push(frame) {
+			signalStop()
+			monitor = nil
+		}
+		if chunker != nil {
+			for _, chunk := range chunker.Push(frame) {
+				chunks <- chunk
+			}
+		}
+	}
+
+	if chunker != nil {
+		if rest := chunker.Flush(); rest != nil {
+			chunks <- rest
+		}
+	}
 }
 
 // Stop stops capturing audio
 func (c *portAudioCapturer) Stop() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if !c.recording {
 		return fmt.Errorf("not recording")
 	}
@@ -73,25 +335,38 @@ func (c *portAudioCapturer) Stop() error {
 		return fmt.Errorf("failed to stop stream: %w", err)
 	}
 
+	// The callback no longer runs, so the resampler tail can be stored and
+	// the watcher can flush and finish
+	c.flush()
+	if c.frames != nil {
+		close(c.frames)
+		c.frames = nil
+	}
+
+	c.recording = false
 	if err := c.stream.Close(); err != nil {
 		return fmt.Errorf("failed to close stream: %w", err)
 	}
 
-	c.recording = false
 	return nil
 }
 
-// GetAudioData returns the captured audio data
+// GetAudioData returns a copy of the captured audio data
 func (c *portAudioCapturer) GetAudioData() ([]int16, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.recording {
 		return nil, fmt.Errorf("still recording, call Stop() first")
 	}
 
-	return c.buffer, nil
+	return c.buffer.Snapshot(), nil
 }
 
 // IsRecording returns whether the capturer is currently recording
 func (c *portAudioCapturer) IsRecording() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return c.recording
 }
 
@@ -102,55 +377,23 @@ func Cleanup() error {
 
 // SaveToWAV saves the audio buffer to a WAV file
 func SaveToWAV(data []int16, writer io.Writer) error {
-	// WAV header
-	dataSize := len(data) * 2 // 2 bytes per int16
-	fileSize := 36 + dataSize
-
-	header := make([]byte, 44)
-
-	// RIFF chunk
-	copy(header[0:4], "RIFF")
-	writeInt32(header[4:8], uint32(fileSize))
-	copy(header[8:12], "WAVE")
-
-	// fmt chunk
-	copy(header[12:16], "fmt ")
-	writeInt32(header[16:20], 16)        // fmt chunk size
-	writeInt16(header[20:22], 1)         // PCM format
-	writeInt16(header[22:24], Channels)  // channels
-	writeInt32(header[24:28], SampleRate) // sample rate
-	writeInt32(header[28:32], SampleRate*Channels*2) // byte rate
-	writeInt16(header[32:34], Channels*2) // block align
-	writeInt16(header[34:36], 16)        // bits per sample
-
-	// data chunk
-	copy(header[36:40], "data")
-	writeInt32(header[40:44], uint32(dataSize))
-
-	if _, err := writer.Write(header); err != nil {
+	return SaveToWAVWithFormat(data, TargetFormat(), writer)
+}
+
+// SaveToWAVWithFormat saves interleaved samples in the given format to a WAV file
+func SaveToWAVWithFormat(data []int16, format Format, writer io.Writer) error {
+	if err := format.Validate(); err != nil {
 		return err
 	}
 
-	// Write audio data
-	buf := make([]byte, 2)
-	for _, sample := range data {
-		writeInt16(buf, uint16(sample))
-		if _, err := writer.Write(buf); err != nil {
-			return err
-		}
+	f, err := wav.NewInt16(data, format.SampleRate, format.Channels, wav.PCM16)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	return f.Encode(writer)
 }
 
 func writeInt16(b []byte, v uint16) {
 	b[0] = byte(v)
 	b[1] = byte(v >> 8)
 }
-
-func writeInt32(b []byte, v uint32) {
-	b[0] = byte(v)
-	b[1] = byte(v >> 8)
-	b[2] = byte(v >> 16)
-	b[3] = byte(v >> 24)
-}
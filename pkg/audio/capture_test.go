@@ -2,7 +2,9 @@ package audio
 
 import (
 	"bytes"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestWriteInt16(t *testing.T) {
@@ -40,41 +42,6 @@ func TestWriteInt16(t *testing.T) {
 	}
 }
 
-func TestWriteInt32(t *testing.T) {
-	tests := []struct {
-		name  string
-		value uint32
-		want  []byte
-	}{
-		{
-			name:  "write zero",
-			value: 0,
-			want:  []byte{0x00, 0x00, 0x00, 0x00},
-		},
-		{
-			name:  "write small value",
-			value: 256,
-			want:  []byte{0x00, 0x01, 0x00, 0x00},
-		},
-		{
-			name:  "write sample rate",
-			value: 16000,
-			want:  []byte{0x80, 0x3E, 0x00, 0x00},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			buf := make([]byte, 4)
-			writeInt32(buf, tt.value)
-
-			if !bytes.Equal(buf, tt.want) {
-				t.Errorf("writeInt32() = %v, want %v", buf, tt.want)
-			}
-		})
-	}
-}
-
 func TestSaveToWAV(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -147,3 +114,315 @@ func TestConstants(t *testing.T) {
 		t.Errorf("FramesPerBuffer = %d, want 1024", FramesPerBuffer)
 	}
 }
+
+// fakeStream feeds frames to the capture callback from its own goroutine, the
+// way portaudio does, then idles until stopped
+type fakeStream struct {
+	callback func([]int16)
+	frames   [][]int16
+	done     chan struct{}
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func (s *fakeStream) Start() error {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for _, frame := range s.frames {
+			select {
+			case <-s.stop:
+				return
+			default:
+				s.callback(frame)
+			}
+		}
+		close(s.done)
+		<-s.stop
+	}()
+	return nil
+}
+
+func (s *fakeStream) Stop() error {
+	close(s.stop)
+	s.wg.Wait()
+	return nil
+}
+
+func (s *fakeStream) Close() error { return nil }
+
+func newTestCapturer(cfg CaptureConfig, frames [][]int16) (*portAudioCapturer, *fakeStream) {
+	return newTestCapturerWithInput(cfg, TargetFormat(), frames)
+}
+
+func newTestCapturerWithInput(cfg CaptureConfig, input Format, frames [][]int16) (*portAudioCapturer, *fakeStream) {
+	stream := &fakeStream{frames: frames}
+	c := newCapturer(cfg, input)
+	c.open = func(callback func([]int16)) (inputStream, error) {
+		stream.callback = callback
+		return stream, nil
+	}
+	return c, stream
+}
+
+func framesOf(count, size int, value int16) [][]int16 {
+	frames := make([][]int16, count)
+	for i := range frames {
+		frames[i] = constantFrame(size, value)
+		frames[i][0] = int16(i)
+	}
+	return frames
+}
+
+func TestCapturer_StartStop(t *testing.T) {
+	c, stream := newTestCapturer(CaptureConfig{}, framesOf(4, 100, 7))
+
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if !c.IsRecording() {
+		t.Error("IsRecording() = false, want true")
+	}
+	if err := c.Start(); err == nil {
+		t.Error("Start() while recording error = nil, want error")
+	}
+	if _, err := c.GetAudioData(); err == nil {
+		t.Error("GetAudioData() while recording error = nil, want error")
+	}
+
+	<-stream.done
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if c.IsRecording() {
+		t.Error("IsRecording() = true, want false")
+	}
+
+	data, err := c.GetAudioData()
+	if err != nil {
+		t.Fatalf("GetAudioData() error = %v", err)
+	}
+	if len(data) != 400 {
+		t.Errorf("len(GetAudioData()) = %d, want 400", len(data))
+	}
+	if err := c.Stop(); err == nil {
+		t.Error("Stop() when not recording error = nil, want error")
+	}
+}
+
+func TestCapturer_OverflowStop(t *testing.T) {
+	cfg := CaptureConfig{MaxDuration: 25 * time.Millisecond, Overflow: OverflowStop} // 400 samples
+	c, stream := newTestCapturer(cfg, framesOf(10, 100, 7))
+
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	stopped := c.AutoStopped()
+	if stopped == nil {
+		t.Fatal("AutoStopped() = nil, want channel")
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("AutoStopped() not closed after buffer filled")
+	}
+	<-stream.done
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	data, _ := c.GetAudioData()
+	if len(data) != 400 {
+		t.Fatalf("len(GetAudioData()) = %d, want 400", len(data))
+	}
+	if data[0] != 0 || data[300] != 3 {
+		t.Errorf("GetAudioData() kept frames starting %d and %d, want the first ones", data[0], data[300])
+	}
+}
+
+func TestCapturer_OverflowDropOldest(t *testing.T) {
+	cfg := CaptureConfig{MaxDuration: 25 * time.Millisecond, Overflow: OverflowDropOldest}
+	c, stream := newTestCapturer(cfg, framesOf(10, 100, 7))
+
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if c.AutoStopped() != nil {
+		t.Error("AutoStopped() != nil, want nil without auto-stop or stop policy")
+	}
+	<-stream.done
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	data, _ := c.GetAudioData()
+	if len(data) != 400 {
+		t.Fatalf("len(GetAudioData()) = %d, want 400", len(data))
+	}
+	if data[0] != 6 || data[300] != 9 {
+		t.Errorf("GetAudioData() kept frames starting %d and %d, want 6 and 9", data[0], data[300])
+	}
+}
+
+func TestCapturer_ConcurrentAccess(t *testing.T) {
+	c, stream := newTestCapturer(CaptureConfig{}, framesOf(200, 64, 7))
+
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = c.IsRecording()
+				_, _ = c.GetAudioData()
+				_ = c.AutoStopped()
+			}
+		}()
+	}
+	<-stream.done
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	wg.Wait()
+
+	data, _ := c.GetAudioData()
+	if len(data) != 200*64 {
+		t.Errorf("len(GetAudioData()) = %d, want %d", len(data), 200*64)
+	}
+}
+
+func TestCapturer_Streaming(t *testing.T) {
+	c, stream := newTestCapturer(CaptureConfig{}, framesOf(8, 1000, 7))
+
+	chunks, err := c.StartStreaming(ChunkOptions{MaxDuration: 250 * time.Millisecond}) // 4000 samples
+	if err != nil {
+		t.Fatalf("StartStreaming() error = %v", err)
+	}
+
+	var total int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for chunk := range chunks {
+			total += len(chunk)
+		}
+	}()
+
+	<-stream.done
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	<-done
+
+	if total != 8000 {
+		t.Errorf("streamed %d samples, want 8000", total)
+	}
+}
+
+func TestCapturer_StreamingCountsDropped(t *testing.T) {
+	frames := framesOf(streamFrameBuffer+200, 100, 7)
+	c, stream := newTestCapturer(CaptureConfig{}, frames)
+
+	chunks, err := c.StartStreaming(ChunkOptions{MaxDuration: 250 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("StartStreaming() error = %v", err)
+	}
+
+	// Nothing reads the chunks until every frame was delivered, so the
+	// queue fills up
+	<-stream.done
+	var total int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for chunk := range chunks {
+			total += len(chunk)
+		}
+	}()
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	<-done
+
+	dropped := int(c.Dropped() * SampleRate / time.Second)
+	if dropped == 0 {
+		t.Error("Dropped() = 0, want the frames that did not fit the queue")
+	}
+	if want := len(frames) * 100; total+dropped != want {
+		t.Errorf("streamed %d + dropped %d samples, want %d", total, dropped, want)
+	}
+}
+
+func TestCapturer_ConvertsInputFormat(t *testing.T) {
+	// One second of 48 kHz stereo in 100 callbacks
+	frames := make([][]int16, 100)
+	for i := range frames {
+		frames[i] = constantFrame(960, 1000)
+	}
+	c, stream := newTestCapturerWithInput(CaptureConfig{}, Format{SampleRate: 48000, Channels: 2}, frames)
+
+	if err := c.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	<-stream.done
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	data, _ := c.GetAudioData()
+	if len(data) != SampleRate {
+		t.Errorf("len(GetAudioData()) = %d, want %d", len(data), SampleRate)
+	}
+	if mid := data[len(data)/2]; mid != 1000 {
+		t.Errorf("GetAudioData()[mid] = %d, want 1000", mid)
+	}
+}
+
+func TestSaveToWAVWithFormat(t *testing.T) {
+	var buf bytes.Buffer
+	data := []int16{1, -1, 2, -2}
+	if err := SaveToWAVWithFormat(data, Format{SampleRate: 44100, Channels: 2}, &buf); err != nil {
+		t.Fatalf("SaveToWAVWithFormat() error = %v", err)
+	}
+
+	header := buf.Bytes()
+	if got := int(header[22]) | int(header[23])<<8; got != 2 {
+		t.Errorf("channels = %d, want 2", got)
+	}
+	if got := int(header[24]) | int(header[25])<<8 | int(header[26])<<16; got != 44100 {
+		t.Errorf("sample rate = %d, want 44100", got)
+	}
+	if got := int(header[28]) | int(header[29])<<8 | int(header[30])<<16; got != 44100*4 {
+		t.Errorf("byte rate = %d, want %d", got, 44100*4)
+	}
+	if got := int(header[32]); got != 4 {
+		t.Errorf("block align = %d, want 4", got)
+	}
+	if buf.Len() != 44+len(data)*2 {
+		t.Errorf("file size = %d, want %d", buf.Len(), 44+len(data)*2)
+	}
+
+	tests := []struct {
+		name   string
+		data   []int16
+		format Format
+	}{
+		{name: "zero rate", data: data, format: Format{Channels: 1}},
+		{name: "zero channels", data: data, format: Format{SampleRate: 16000}},
+		{name: "partial frame", data: []int16{1, 2, 3}, format: Format{SampleRate: 16000, Channels: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SaveToWAVWithFormat(tt.data, tt.format, &bytes.Buffer{}); err == nil {
+				t.Error("SaveToWAVWithFormat() error = nil, want error")
+			}
+		})
+	}
+}
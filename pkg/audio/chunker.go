@@ -0,0 +1,148 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// ChunkOptions controls how live audio is split into chunks while recording
+type ChunkOptions struct {
+	// MaxDuration is the longest chunk emitted; a chunk is cut here if no silence was found
+	MaxDuration time.Duration
+	// MinDuration is the shortest chunk that may be cut at a pause; zero disables silence cuts
+	MinDuration time.Duration
+	// SilenceDuration is how long the signal must stay quiet to count as a pause
+	SilenceDuration time.Duration
+	// SilenceThreshold is the RMS amplitude below which a frame is considered quiet
+	SilenceThreshold float64
+}
+
+// DefaultChunkOptions returns fixed 10 second chunks without silence cuts
+func DefaultChunkOptions() ChunkOptions {
+	return ChunkOptions{
+		MaxDuration:      10 * time.Second,
+		SilenceDuration:  500 * time.Millisecond,
+		SilenceThreshold: 500,
+	}
+}
+
+// Chunker accumulates frames and cuts them into chunks of bounded length,
+// preferring to cut at pauses when MinDuration is set
+type Chunker struct {
+	opts           ChunkOptions
+	maxSamples     int
+	minSamples     int
+	silenceSamples int
+	buf            []int16
+	silentRun      int
+}
+
+// NewChunker creates a chunker for audio at the given sample rate
+func NewChunker(opts ChunkOptions, sampleRate int) *Chunker {
+	return &Chunker{
+		opts:           opts,
+		maxSamples:     durationToSamples(opts.MaxDuration, sampleRate),
+		minSamples:     durationToSamples(opts.MinDuration, sampleRate),
+		silenceSamples: durationToSamples(opts.SilenceDuration, sampleRate),
+	}
+}
+
+// Push adds a frame and returns any chunks completed by it
+func (c *Chunker) Push(frame []int16) [][]int16 {
+	var chunks [][]int16
+
+	c.buf = append(c.buf, frame...)
+	if rms(frame) < c.opts.SilenceThreshold {
+		c.silentRun += len(frame)
+	} else {
+		c.silentRun = 0
+	}
+
+	if c.minSamples > 0 && len(c.buf) >= c.minSamples && c.silentRun >= c.silenceSamples {
+		chunks = append(chunks, c.take(len(c.buf)))
+	}
+
+	for c.maxSamples > 0 && len(c.buf) >= c.maxSamples {
+		chunks = append(chunks, c.take(c.maxSamples))
+	}
+
+	return chunks
+}
+
+// Flush returns whatever audio is buffered, or nil if there is none
+func (c *Chunker) Flush() []int16 {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	return c.take(len(c.buf))
+}
+
+func (c *Chunker) take(n int) []int16 {
+	chunk := make([]int16, n)
+	copy(chunk, c.buf[:n])
+	c.buf = append(c.buf[:0], c.buf[n:]...)
+	c.silentRun = 0
+	return chunk
+}
+
+func durationToSamples(d time.Duration, sampleRate int) int {
+	return int(d * time.Duration(sampleRate) / time.Second)
+}
+
+// rms returns the root mean square amplitude of a frame
+func rms(frame []int16) float64 {
+	if len(frame) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, s := range frame {
+		v := float64(s)
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(len(frame)))
+}
+
+// splitWindow is the span over which SplitAtSilence compares loudness
+const splitWindow = 20 * time.Millisecond
+
+// SplitAtSilence divides a recording into pieces of at most maxSamples. Each
+// cut is placed in the quietest moment of the second half of the allowed
+// length, so words are rarely cut in two. A recording that fits, or a
+// maxSamples of zero, gives a single piece.
+func SplitAtSilence(samples []int16, maxSamples, sampleRate int) [][]int16 {
+	if maxSamples <= 0 || len(samples) <= maxSamples {
+		return [][]int16{samples}
+	}
+
+	window := durationToSamples(splitWindow, sampleRate)
+	if window < 1 {
+		window = 1
+	}
+
+	var pieces [][]int16
+	for len(samples) > maxSamples {
+		cut := quietestPoint(samples[:maxSamples], maxSamples/2, window)
+		pieces = append(pieces, samples[:cut])
+		samples = samples[cut:]
+	}
+	return append(pieces, samples)
+}
+
+// quietestPoint returns the middle of the quietest window starting at or
+// after from, preferring later windows on ties. Without room for a window it
+// returns len(samples).
+func quietestPoint(samples []int16, from, window int) int {
+	hop := window / 2
+	if hop < 1 {
+		hop = 1
+	}
+
+	best, bestLevel := len(samples), math.Inf(1)
+	for start := from; start+window <= len(samples); start += hop {
+		if level := rms(samples[start : start+window]); level <= bestLevel {
+			best, bestLevel = start+window/2, level
+		}
+	}
+	return best
+}
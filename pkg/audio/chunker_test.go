@@ -0,0 +1,185 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+func constantFrame(n int, value int16) []int16 {
+	frame := make([]int16, n)
+	for i := range frame {
+		frame[i] = value
+	}
+	return frame
+}
+
+func TestChunker_FixedSize(t *testing.T) {
+	c := NewChunker(ChunkOptions{MaxDuration: time.Second}, 100)
+
+	var chunks [][]int16
+	for i := 0; i < 5; i++ {
+		chunks = append(chunks, c.Push(constantFrame(45, 1000))...)
+	}
+
+	if len(chunks) != 2 {
+		t.Fatalf("Push() produced %d chunks, want 2", len(chunks))
+	}
+
+	for i, chunk := range chunks {
+		if len(chunk) != 100 {
+			t.Errorf("chunk %d length = %d, want 100", i, len(chunk))
+		}
+	}
+
+	rest := c.Flush()
+	if len(rest) != 25 {
+		t.Errorf("Flush() length = %d, want 25", len(rest))
+	}
+
+	if c.Flush() != nil {
+		t.Error("second Flush() returned audio, want nil")
+	}
+}
+
+func TestChunker_SilenceDelimited(t *testing.T) {
+	c := NewChunker(ChunkOptions{
+		MaxDuration:      10 * time.Second,
+		MinDuration:      time.Second,
+		SilenceDuration:  200 * time.Millisecond,
+		SilenceThreshold: 100,
+	}, 100)
+
+	// A pause before MinDuration must not cut
+	if chunks := c.Push(constantFrame(50, 1000)); len(chunks) != 0 {
+		t.Fatalf("speech produced %d chunks, want 0", len(chunks))
+	}
+	if chunks := c.Push(constantFrame(30, 0)); len(chunks) != 0 {
+		t.Fatalf("early pause produced %d chunks, want 0", len(chunks))
+	}
+	if chunks := c.Push(constantFrame(50, 1000)); len(chunks) != 0 {
+		t.Fatalf("speech produced %d chunks, want 0", len(chunks))
+	}
+
+	// A long enough pause after MinDuration cuts the whole buffer
+	if chunks := c.Push(constantFrame(10, 0)); len(chunks) != 0 {
+		t.Fatalf("short pause produced %d chunks, want 0", len(chunks))
+	}
+	chunks := c.Push(constantFrame(10, 0))
+	if len(chunks) != 1 {
+		t.Fatalf("pause produced %d chunks, want 1", len(chunks))
+	}
+
+	if len(chunks[0]) != 150 {
+		t.Errorf("chunk length = %d, want 150", len(chunks[0]))
+	}
+
+	if c.Flush() != nil {
+		t.Error("Flush() after cut returned audio, want nil")
+	}
+}
+
+func TestChunker_ChunksAreCopies(t *testing.T) {
+	c := NewChunker(ChunkOptions{MaxDuration: time.Second}, 10)
+
+	frame := constantFrame(10, 7)
+	chunks := c.Push(frame)
+	if len(chunks) != 1 {
+		t.Fatalf("Push() produced %d chunks, want 1", len(chunks))
+	}
+
+	frame[0] = 99
+	c.Push(constantFrame(5, 1))
+	if chunks[0][0] != 7 {
+		t.Errorf("chunk changed after later pushes: got %d, want 7", chunks[0][0])
+	}
+}
+
+func TestRMS(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []int16
+		want  float64
+	}{
+		{name: "empty", frame: nil, want: 0},
+		{name: "silence", frame: []int16{0, 0, 0}, want: 0},
+		{name: "constant", frame: []int16{300, -300, 300, -300}, want: 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rms(tt.frame); got != tt.want {
+				t.Errorf("rms() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAtSilence(t *testing.T) {
+	loud := constantFrame(1600, 5000) // 100 ms
+	quiet := constantFrame(800, 0)    // 50 ms
+
+	// loud 300 ms, pause, loud 300 ms, pause, loud 300 ms
+	var recording []int16
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			recording = append(recording, loud...)
+		}
+		if i < 2 {
+			recording = append(recording, quiet...)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		maxSamples int
+		wantPieces int
+	}{
+		{name: "fits", maxSamples: len(recording), wantPieces: 1},
+		{name: "disabled", maxSamples: 0, wantPieces: 1},
+		{name: "split at pauses", maxSamples: 8000, wantPieces: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pieces := SplitAtSilence(recording, tt.maxSamples, 16000)
+			if len(pieces) != tt.wantPieces {
+				t.Fatalf("len(SplitAtSilence()) = %d, want %d", len(pieces), tt.wantPieces)
+			}
+
+			var total int
+			for i, piece := range pieces {
+				if tt.maxSamples > 0 && len(piece) > tt.maxSamples {
+					t.Errorf("piece %d has %d samples, want at most %d", i, len(piece), tt.maxSamples)
+				}
+				total += len(piece)
+			}
+			if total != len(recording) {
+				t.Errorf("pieces hold %d samples, want %d", total, len(recording))
+			}
+		})
+	}
+
+	// Each cut must land inside a pause
+	pieces := SplitAtSilence(recording, 8000, 16000)
+	for i, piece := range pieces[:len(pieces)-1] {
+		if last := piece[len(piece)-1]; last != 0 {
+			t.Errorf("piece %d ends with sample %d, want a cut in silence", i, last)
+		}
+	}
+}
+
+func TestSplitAtSilence_NoPause(t *testing.T) {
+	recording := constantFrame(10000, 3000)
+	pieces := SplitAtSilence(recording, 4000, 16000)
+
+	var total int
+	for i, piece := range pieces {
+		if len(piece) > 4000 {
+			t.Errorf("piece %d has %d samples, want at most 4000", i, len(piece))
+		}
+		total += len(piece)
+	}
+	if total != len(recording) {
+		t.Errorf("pieces hold %d samples, want %d", total, len(recording))
+	}
+}
@@ -0,0 +1,38 @@
+package audio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"speech-to-clipboard/pkg/audio/wav"
+)
+
+// Decode reads a WAV or FLAC file, recognised by its leading bytes, and
+// returns its samples as interleaved 16-bit PCM along with their format
+func Decode(r io.Reader) ([]int16, Format, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, Format{}, fmt.Errorf("failed to read audio header: %w", err)
+	}
+
+	switch string(magic) {
+	case "RIFF":
+		return DecodeWAV(br)
+	case "fLaC":
+		return DecodeFLAC(br)
+	}
+	return nil, Format{}, fmt.Errorf("unrecognised audio format, want WAV or FLAC")
+}
+
+// DecodeWAV reads a RIFF WAV file holding 8, 16, 24 or 32-bit integer PCM or
+// 32 or 64-bit float samples and converts them to 16-bit PCM. Chunks other
+// than "fmt " and "data" are skipped.
+func DecodeWAV(r io.Reader) ([]int16, Format, error) {
+	f, err := wav.Decode(r)
+	if err != nil {
+		return nil, Format{}, err
+	}
+	return f.Int16(), Format{SampleRate: f.Format.SampleRate, Channels: f.Format.Channels}, nil
+}
@@ -0,0 +1,230 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// buildWAV assembles a WAV file from a format chunk body, sample bytes and
+// optional extra chunks placed before the data
+func buildWAV(fmtBody, data []byte, extra ...[]byte) []byte {
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	writeChunk := func(id string, payload []byte) {
+		body.WriteString(id)
+		binary.Write(&body, binary.LittleEndian, uint32(len(payload)))
+		body.Write(payload)
+		if len(payload)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	writeChunk("fmt ", fmtBody)
+	for _, chunk := range extra {
+		writeChunk(string(chunk[:4]), chunk[4:])
+	}
+	writeChunk("data", data)
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+// WAV format tags
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+func fmtChunk(tag uint16, channels, rate, bits int) []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint16(b[0:], tag)
+	binary.LittleEndian.PutUint16(b[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(b[4:], uint32(rate))
+	binary.LittleEndian.PutUint32(b[8:], uint32(rate*channels*bits/8))
+	binary.LittleEndian.PutUint16(b[12:], uint16(channels*bits/8))
+	binary.LittleEndian.PutUint16(b[14:], uint16(bits))
+	return b
+}
+
+func TestDecodeWAV(t *testing.T) {
+	extensible := append(fmtChunk(wavFormatExtensible, 1, 16000, 24), make([]byte, 24)...)
+	binary.LittleEndian.PutUint16(extensible[16:], 22)
+	binary.LittleEndian.PutUint16(extensible[24:], wavFormatPCM)
+
+	float32Data := make([]byte, 12)
+	for i, v := range []float32{0.5, -1, 2} {
+		binary.LittleEndian.PutUint32(float32Data[4*i:], math.Float32bits(v))
+	}
+
+	tests := []struct {
+		name       string
+		file       []byte
+		want       []int16
+		wantFormat Format
+	}{
+		{
+			name:       "16-bit stereo",
+			file:       buildWAV(fmtChunk(wavFormatPCM, 2, 44100, 16), []byte{0x01, 0x00, 0xFF, 0xFF}),
+			want:       []int16{1, -1},
+			wantFormat: Format{SampleRate: 44100, Channels: 2},
+		},
+		{
+			name:       "8-bit unsigned",
+			file:       buildWAV(fmtChunk(wavFormatPCM, 1, 8000, 8), []byte{128, 255, 0}),
+			want:       []int16{0, 127 << 8, -128 << 8},
+			wantFormat: Format{SampleRate: 8000, Channels: 1},
+		},
+		{
+			name:       "24-bit extensible",
+			file:       buildWAV(extensible, []byte{0xFF, 0x34, 0x12, 0x00, 0x00, 0x80}),
+			want:       []int16{0x1234, math.MinInt16},
+			wantFormat: Format{SampleRate: 16000, Channels: 1},
+		},
+		{
+			name:       "32-bit float clipped",
+			file:       buildWAV(fmtChunk(wavFormatFloat, 1, 48000, 32), float32Data),
+			want:       []int16{16384, -32768, 32767},
+			wantFormat: Format{SampleRate: 48000, Channels: 1},
+		},
+		{
+			name:       "unknown chunks skipped",
+			file:       buildWAV(fmtChunk(wavFormatPCM, 1, 16000, 16), []byte{0x02, 0x00}, []byte("LISTodd")),
+			want:       []int16{2},
+			wantFormat: Format{SampleRate: 16000, Channels: 1},
+		},
+		{
+			name:       "partial frame dropped",
+			file:       buildWAV(fmtChunk(wavFormatPCM, 2, 16000, 16), []byte{1, 0, 2, 0, 3}),
+			want:       []int16{1, 2},
+			wantFormat: Format{SampleRate: 16000, Channels: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, format, err := DecodeWAV(bytes.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("DecodeWAV() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeWAV() = %v, want %v", got, tt.want)
+			}
+			if format != tt.wantFormat {
+				t.Errorf("DecodeWAV() format = %v, want %v", format, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestDecodeWAV_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{name: "not riff", file: []byte("OggS\x00\x00\x00\x00WAVE")},
+		{name: "truncated", file: []byte("RIFF")},
+		{name: "no data chunk", file: buildWAV(fmtChunk(wavFormatPCM, 1, 16000, 16), nil)[:36]},
+		{name: "compressed encoding", file: buildWAV(fmtChunk(2, 1, 16000, 4), []byte{0, 0})},
+		{name: "zero channels", file: buildWAV(fmtChunk(wavFormatPCM, 0, 16000, 16), []byte{0, 0})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := DecodeWAV(bytes.NewReader(tt.file)); err == nil {
+				t.Error("DecodeWAV() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestDecodeWAV_RoundTrip(t *testing.T) {
+	data := sine(300, 22050, 2*1000, 9000)
+	format := Format{SampleRate: 22050, Channels: 2}
+
+	var buf bytes.Buffer
+	if err := SaveToWAVWithFormat(data, format, &buf); err != nil {
+		t.Fatalf("SaveToWAVWithFormat() error = %v", err)
+	}
+
+	got, gotFormat, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if gotFormat != format {
+		t.Errorf("Decode() format = %v, want %v", gotFormat, format)
+	}
+	if !reflect.DeepEqual(got, data) {
+		t.Error("Decode() samples differ from the encoded ones")
+	}
+}
+
+func TestDecodeFLAC_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	noise := make([]int16, 2*9000)
+	for i := range noise {
+		noise[i] = int16(rng.Intn(65536) - 32768)
+	}
+	speech := sine(300, 16000, 50000, 8000)
+	for i := range speech {
+		speech[i] += int16(rng.Intn(400) - 200)
+	}
+
+	tests := []struct {
+		name   string
+		data   []int16
+		format Format
+	}{
+		{name: "speech over many frames", data: sine(200, 16000, 16000*40, 12000), format: TargetFormat()},
+		{name: "noisy tone", data: speech, format: TargetFormat()},
+		{name: "noise stereo", data: noise, format: Format{SampleRate: 44100, Channels: 2}},
+		{name: "silence", data: make([]int16, 5000), format: TargetFormat()},
+		{name: "single sample", data: []int16{5}, format: TargetFormat()},
+		{name: "uncommon rate", data: []int16{5, -3, 7}, format: Format{SampleRate: 12345, Channels: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (FLACEncoder{}).Encode(tt.data, tt.format, &buf); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			got, format, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if format != tt.format {
+				t.Errorf("Decode() format = %v, want %v", format, tt.format)
+			}
+			if !reflect.DeepEqual(got, tt.data) {
+				t.Error("Decode() samples differ from the encoded ones")
+			}
+		})
+	}
+}
+
+func TestDecodeFLAC_Corrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (FLACEncoder{}).Encode(sine(440, 16000, 10000, 8000), TargetFormat(), &buf); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	file := buf.Bytes()
+	file[len(file)/2] ^= 0x10
+	if _, _, err := DecodeFLAC(bytes.NewReader(file)); err == nil {
+		t.Error("DecodeFLAC() error = nil, want checksum error")
+	}
+}
+
+func TestDecode_UnknownFormat(t *testing.T) {
+	if _, _, err := Decode(bytes.NewReader([]byte("ID3\x04 mp3 data"))); err == nil {
+		t.Error("Decode() error = nil, want error")
+	}
+}
@@ -0,0 +1,110 @@
+package audio
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gordonklaus/portaudio"
+)
+
+// DeviceInfo describes an audio input device
+type DeviceInfo struct {
+	Index             int
+	Name              string
+	HostAPI           string
+	MaxInputChannels  int
+	DefaultSampleRate float64
+	IsDefault         bool
+}
+
+// ListDevices returns the devices that can record audio
+func ListDevices() ([]DeviceInfo, error) {
+	if err := portaudio.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize portaudio: %w", err)
+	}
+	defer portaudio.Terminate()
+
+	devices, _, err := inputDevices()
+	return devices, err
+}
+
+// inputDevices lists input devices along with the portaudio handles they
+// were built from, in the same order. portaudio must be initialized.
+func inputDevices() ([]DeviceInfo, []*portaudio.DeviceInfo, error) {
+	all, err := portaudio.Devices()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	var defaultIndex = -1
+	if def, err := portaudio.DefaultInputDevice(); err == nil && def != nil {
+		defaultIndex = def.Index
+	}
+
+	var devices []DeviceInfo
+	var handles []*portaudio.DeviceInfo
+	for _, d := range all {
+		if d.MaxInputChannels < 1 {
+			continue
+		}
+
+		info := DeviceInfo{
+			Index:             d.Index,
+			Name:              d.Name,
+			MaxInputChannels:  d.MaxInputChannels,
+			DefaultSampleRate: d.DefaultSampleRate,
+			IsDefault:         d.Index == defaultIndex,
+		}
+		if d.HostApi != nil {
+			info.HostAPI = d.HostApi.Name
+		}
+		devices = append(devices, info)
+		handles = append(handles, d)
+	}
+	return devices, handles, nil
+}
+
+// SelectDevice picks a device by index or by name. A numeric selector must
+// match an index exactly. Otherwise an exact, case-insensitive name match
+// wins, followed by a unique name substring match.
+func SelectDevice(devices []DeviceInfo, selector string) (DeviceInfo, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return DeviceInfo{}, fmt.Errorf("empty device selector")
+	}
+
+	if index, err := strconv.Atoi(selector); err == nil {
+		for _, d := range devices {
+			if d.Index == index {
+				return d, nil
+			}
+		}
+		return DeviceInfo{}, fmt.Errorf("no input device with index %d", index)
+	}
+
+	needle := strings.ToLower(selector)
+	var matches []DeviceInfo
+	for _, d := range devices {
+		name := strings.ToLower(d.Name)
+		if name == needle {
+			return d, nil
+		}
+		if strings.Contains(name, needle) {
+			matches = append(matches, d)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return DeviceInfo{}, fmt.Errorf("no input device matches %q", selector)
+	case 1:
+		return matches[0], nil
+	}
+
+	names := make([]string, len(matches))
+	for i, d := range matches {
+		names[i] = fmt.Sprintf("%d: %s", d.Index, d.Name)
+	}
+	return DeviceInfo{}, fmt.Errorf("device %q is ambiguous, it matches %s", selector, strings.Join(names, ", "))
+}
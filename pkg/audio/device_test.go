@@ -0,0 +1,50 @@
+package audio
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectDevice(t *testing.T) {
+	devices := []DeviceInfo{
+		{Index: 0, Name: "Built-in Microphone", MaxInputChannels: 2, IsDefault: true},
+		{Index: 3, Name: "USB Headset Mic", MaxInputChannels: 1},
+		{Index: 4, Name: "USB Headset Mic (Monitor)", MaxInputChannels: 2},
+		{Index: 7, Name: "Blue Yeti", MaxInputChannels: 2},
+	}
+
+	tests := []struct {
+		name      string
+		selector  string
+		wantIndex int
+		wantErr   string
+	}{
+		{name: "by index", selector: "7", wantIndex: 7},
+		{name: "unknown index", selector: "5", wantErr: "no input device with index 5"},
+		{name: "unique substring", selector: "yeti", wantIndex: 7},
+		{name: "exact name beats substring", selector: "usb headset mic", wantIndex: 3},
+		{name: "ambiguous substring", selector: "headset", wantErr: "ambiguous"},
+		{name: "no match", selector: "webcam", wantErr: "no input device matches"},
+		{name: "empty", selector: " ", wantErr: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectDevice(devices, tt.selector)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("SelectDevice() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("SelectDevice() unexpected error = %v", err)
+			}
+
+			if got.Index != tt.wantIndex {
+				t.Errorf("SelectDevice() index = %d, want %d", got.Index, tt.wantIndex)
+			}
+		})
+	}
+}
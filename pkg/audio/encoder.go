@@ -0,0 +1,48 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+)
+
+// Encoder converts captured samples to a file format for upload
+type Encoder interface {
+	// Encode writes interleaved samples in the given format to w
+	Encode(data []int16, format Format, w io.Writer) error
+	// Extension is the file name extension without the dot, such as "wav"
+	Extension() string
+	// ContentType is the MIME type of the encoded file
+	ContentType() string
+}
+
+// Encoder names accepted by NewEncoder
+const (
+	EncodingWAV  = "wav"
+	EncodingFLAC = "flac"
+)
+
+// NewEncoder returns the encoder for a format name. Opus is not offered:
+// there is no pure-Go Opus encoder and the build avoids further C libraries.
+func NewEncoder(name string) (Encoder, error) {
+	switch name {
+	case EncodingWAV:
+		return WAVEncoder{}, nil
+	case EncodingFLAC:
+		return FLACEncoder{}, nil
+	}
+	return nil, fmt.Errorf("unsupported audio encoding %q, want wav or flac", name)
+}
+
+// WAVEncoder writes uncompressed 16-bit PCM WAV files
+type WAVEncoder struct{}
+
+// Encode writes a WAV file
+func (WAVEncoder) Encode(data []int16, format Format, w io.Writer) error {
+	return SaveToWAVWithFormat(data, format, w)
+}
+
+// Extension returns "wav"
+func (WAVEncoder) Extension() string { return EncodingWAV }
+
+// ContentType returns "audio/wav"
+func (WAVEncoder) ContentType() string { return "audio/wav" }
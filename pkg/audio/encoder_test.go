@@ -0,0 +1,149 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestNewEncoder(t *testing.T) {
+	tests := []struct {
+		name            string
+		wantExtension   string
+		wantContentType string
+		wantErr         bool
+	}{
+		{name: "wav", wantExtension: "wav", wantContentType: "audio/wav"},
+		{name: "flac", wantExtension: "flac", wantContentType: "audio/flac"},
+		{name: "opus", wantErr: true},
+		{name: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := NewEncoder(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewEncoder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if enc.Extension() != tt.wantExtension {
+				t.Errorf("Extension() = %v, want %v", enc.Extension(), tt.wantExtension)
+			}
+			if enc.ContentType() != tt.wantContentType {
+				t.Errorf("ContentType() = %v, want %v", enc.ContentType(), tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestFLACEncoder_StreamInfo(t *testing.T) {
+	data := sine(440, 44100, 2*10000, 8000) // 10000 stereo frames
+	var buf bytes.Buffer
+	if err := (FLACEncoder{}).Encode(data, Format{SampleRate: 44100, Channels: 2}, &buf); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	out := buf.Bytes()
+	if string(out[:4]) != "fLaC" {
+		t.Fatalf("marker = %q, want fLaC", out[:4])
+	}
+	if out[4] != 0x80 || out[7] != 34 {
+		t.Errorf("metadata header = % x, want last STREAMINFO of 34 bytes", out[4:8])
+	}
+
+	info := out[8:42]
+	if got := binary.BigEndian.Uint16(info[2:4]); got != flacBlockSize {
+		t.Errorf("max block size = %d, want %d", got, flacBlockSize)
+	}
+	packed := binary.BigEndian.Uint64(info[10:18])
+	if rate := packed >> 44; rate != 44100 {
+		t.Errorf("sample rate = %d, want 44100", rate)
+	}
+	if channels := (packed>>41)&0x7 + 1; channels != 2 {
+		t.Errorf("channels = %d, want 2", channels)
+	}
+	if bps := (packed>>36)&0x1F + 1; bps != 16 {
+		t.Errorf("bits per sample = %d, want 16", bps)
+	}
+	if total := packed & (1<<36 - 1); total != 10000 {
+		t.Errorf("total samples = %d, want 10000", total)
+	}
+
+	// The first frame starts right after STREAMINFO with the sync code
+	if out[42] != 0xFF || out[43] != 0xF8 {
+		t.Errorf("frame sync = % x, want ff f8", out[42:44])
+	}
+}
+
+func TestFLACEncoder_Compresses(t *testing.T) {
+	data := sine(220, 16000, 16000*5, 6000)
+	for i := range data {
+		data[i] += int16(i%7 - 3) // a little noise keeps it from being trivial
+	}
+
+	var flac, wav bytes.Buffer
+	if err := (FLACEncoder{}).Encode(data, TargetFormat(), &flac); err != nil {
+		t.Fatalf("FLACEncoder.Encode() error = %v", err)
+	}
+	if err := (WAVEncoder{}).Encode(data, TargetFormat(), &wav); err != nil {
+		t.Fatalf("WAVEncoder.Encode() error = %v", err)
+	}
+
+	if flac.Len() >= wav.Len()*3/4 {
+		t.Errorf("FLAC size = %d, want well under WAV size %d", flac.Len(), wav.Len())
+	}
+}
+
+func TestFLACEncoder_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []int16
+		format Format
+	}{
+		{name: "zero rate", data: []int16{1}, format: Format{Channels: 1}},
+		{name: "too many channels", data: make([]int16, 9), format: Format{SampleRate: 16000, Channels: 9}},
+		{name: "partial frame", data: []int16{1, 2, 3}, format: Format{SampleRate: 16000, Channels: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (FLACEncoder{}).Encode(tt.data, tt.format, &bytes.Buffer{}); err == nil {
+				t.Error("Encode() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestWriteFLACNumber(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want []byte
+	}{
+		{n: 0, want: []byte{0x00}},
+		{n: 0x7F, want: []byte{0x7F}},
+		{n: 0x80, want: []byte{0xC2, 0x80}},
+		{n: 0x7FF, want: []byte{0xDF, 0xBF}},
+		{n: 0x800, want: []byte{0xE0, 0xA0, 0x80}},
+	}
+
+	for _, tt := range tests {
+		bw := &bitWriter{}
+		writeFLACNumber(bw, tt.n)
+		if got := bw.bytes(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("writeFLACNumber(%#x) = % x, want % x", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFLACChecksums(t *testing.T) {
+	check := []byte("123456789")
+	if got := crc8(check); got != 0xF4 {
+		t.Errorf("crc8() = %#x, want 0xf4", got)
+	}
+	if got := crc16(check); got != 0xFEE8 {
+		t.Errorf("crc16() = %#x, want 0xfee8", got)
+	}
+}
@@ -0,0 +1,417 @@
+package audio
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io"
+)
+
+const (
+	// flacBlockSize is the number of samples per channel in each frame
+	flacBlockSize = 4096
+	// flacMaxFixedOrder is the highest fixed predictor FLAC defines
+	flacMaxFixedOrder = 4
+	// flacMaxPartitionOrder limits how finely residuals are split for Rice coding
+	flacMaxPartitionOrder = 8
+	// flacMaxRiceParam is the largest parameter a 4-bit Rice code can carry;
+	// 15 is reserved as the escape code
+	flacMaxRiceParam = 14
+)
+
+// FLACEncoder writes lossless FLAC files using fixed linear predictors and
+// Rice-coded residuals. Speech typically shrinks to about half the WAV size.
+type FLACEncoder struct{}
+
+// Extension returns "flac"
+func (FLACEncoder) Extension() string { return EncodingFLAC }
+
+// ContentType returns "audio/flac"
+func (FLACEncoder) ContentType() string { return "audio/flac" }
+
+// Encode writes a FLAC stream with a STREAMINFO block followed by one frame
+// per flacBlockSize samples
+func (FLACEncoder) Encode(data []int16, format Format, w io.Writer) error {
+	if err := format.Validate(); err != nil {
+		return err
+	}
+	if format.Channels > 8 {
+		return fmt.Errorf("FLAC supports at most 8 channels, got %d", format.Channels)
+	}
+	if format.SampleRate >= 1<<20 {
+		return fmt.Errorf("FLAC does not support a sample rate of %d", format.SampleRate)
+	}
+	if len(data)%format.Channels != 0 {
+		return fmt.Errorf("%d samples do not fill %d-channel frames", len(data), format.Channels)
+	}
+
+	if _, err := w.Write(flacStreamInfo(data, format)); err != nil {
+		return err
+	}
+
+	frames := len(data) / format.Channels
+	channel := make([]int32, flacBlockSize)
+	for frame, start := 0, 0; start < frames; frame, start = frame+1, start+flacBlockSize {
+		n := frames - start
+		if n > flacBlockSize {
+			n = flacBlockSize
+		}
+
+		bw := &bitWriter{}
+		writeFLACFrameHeader(bw, frame, n, format)
+		for ch := 0; ch < format.Channels; ch++ {
+			for i := 0; i < n; i++ {
+				channel[i] = int32(data[(start+i)*format.Channels+ch])
+			}
+			writeFLACSubframe(bw, channel[:n])
+		}
+		bw.align()
+		bw.writeBits(uint64(crc16(bw.bytes())), 16)
+
+		if _, err := w.Write(bw.bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flacStreamInfo builds the "fLaC" marker and the mandatory STREAMINFO block
+func flacStreamInfo(data []int16, format Format) []byte {
+	bw := &bitWriter{}
+	bw.writeBytes([]byte("fLaC"))
+
+	// Metadata block header: last block, type 0 (STREAMINFO), 34 bytes
+	bw.writeBits(1, 1)
+	bw.writeBits(0, 7)
+	bw.writeBits(34, 24)
+
+	// Only the last block may be shorter, and it does not count here
+	frames := len(data) / format.Channels
+	bw.writeBits(flacBlockSize, 16)
+	bw.writeBits(flacBlockSize, 16)
+	bw.writeBits(0, 24) // minimum frame size unknown
+	bw.writeBits(0, 24) // maximum frame size unknown
+	bw.writeBits(uint64(format.SampleRate), 20)
+	bw.writeBits(uint64(format.Channels-1), 3)
+	bw.writeBits(16-1, 5)
+	bw.writeBits(uint64(frames), 36)
+
+	// The signature covers the samples as interleaved little-endian bytes
+	raw := make([]byte, 2*len(data))
+	for i, s := range data {
+		writeInt16(raw[2*i:], uint16(s))
+	}
+	sum := md5.Sum(raw)
+	bw.writeBytes(sum[:])
+
+	return bw.bytes()
+}
+
+// flacSampleRateCodes maps rates with a frame header code of their own;
+// anything else refers back to STREAMINFO
+var flacSampleRateCodes = map[int]uint64{
+	88200: 1, 176400: 2, 192000: 3, 8000: 4, 16000: 5, 22050: 6,
+	24000: 7, 32000: 8, 44100: 9, 48000: 10, 96000: 11,
+}
+
+func writeFLACFrameHeader(bw *bitWriter, frame, blockSize int, format Format) {
+	start := len(bw.buf)
+
+	bw.writeBits(0x3FFE, 14) // sync code
+	bw.writeBits(0, 1)       // reserved
+	bw.writeBits(0, 1)       // fixed block size, frames are numbered
+
+	blockCode := uint64(0xC) // 4096
+	if blockSize != flacBlockSize {
+		blockCode = 0x7 // 16-bit size-1 follows the frame number
+	}
+	bw.writeBits(blockCode, 4)
+	bw.writeBits(flacSampleRateCodes[format.SampleRate], 4)
+	bw.writeBits(uint64(format.Channels-1), 4) // independent channels
+	bw.writeBits(0x4, 3)                       // 16 bits per sample
+	bw.writeBits(0, 1)                         // reserved
+	writeFLACNumber(bw, uint64(frame))
+	if blockCode == 0x7 {
+		bw.writeBits(uint64(blockSize-1), 16)
+	}
+
+	bw.writeBits(uint64(crc8(bw.buf[start:])), 8)
+}
+
+// writeFLACNumber writes a frame number in FLAC's UTF-8-like variable length coding
+func writeFLACNumber(bw *bitWriter, n uint64) {
+	if n < 0x80 {
+		bw.writeBits(n, 8)
+		return
+	}
+
+	// Count the 6-bit continuation bytes needed after the leading byte
+	extra := 1
+	for n >= 1<<(6*extra+6-extra) {
+		extra++
+	}
+	lead := uint64(0xFF<<(7-extra)) & 0xFF
+	bw.writeBits(lead|n>>(6*extra), 8)
+	for i := extra - 1; i >= 0; i-- {
+		bw.writeBits(0x80|(n>>(6*i))&0x3F, 8)
+	}
+}
+
+// writeFLACSubframe picks the smallest of the constant, verbatim and fixed
+// predictor encodings for one channel of a frame
+func writeFLACSubframe(bw *bitWriter, samples []int32) {
+	constant := true
+	for _, s := range samples[1:] {
+		if s != samples[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		bw.writeBits(0, 1) // padding
+		bw.writeBits(0, 6) // SUBFRAME_CONSTANT
+		bw.writeBits(0, 1) // no wasted bits
+		bw.writeSigned(int64(samples[0]), 16)
+		return
+	}
+
+	bestOrder, bestPlan := -1, ricePlan{}
+	bestBits := 16 * len(samples) // verbatim
+	residual := make([]int32, len(samples))
+	for order := 0; order <= flacMaxFixedOrder && order < len(samples); order++ {
+		fixedResidual(samples, order, residual)
+		plan := planRice(residual[order:], len(samples), order)
+		if size := 16*order + plan.bits; size < bestBits {
+			bestOrder, bestPlan, bestBits = order, plan, size
+		}
+	}
+
+	bw.writeBits(0, 1)
+	if bestOrder < 0 {
+		bw.writeBits(1, 6) // SUBFRAME_VERBATIM
+		bw.writeBits(0, 1)
+		for _, s := range samples {
+			bw.writeSigned(int64(s), 16)
+		}
+		return
+	}
+
+	bw.writeBits(uint64(0x8|bestOrder), 6) // SUBFRAME_FIXED
+	bw.writeBits(0, 1)
+	for _, s := range samples[:bestOrder] {
+		bw.writeSigned(int64(s), 16)
+	}
+	fixedResidual(samples, bestOrder, residual)
+	writeRice(bw, residual[bestOrder:], len(samples), bestOrder, bestPlan)
+}
+
+// fixedResidual stores in residual[order:] the prediction error of FLAC's
+// fixed polynomial predictor of the given order
+func fixedResidual(x []int32, order int, residual []int32) {
+	for i := order; i < len(x); i++ {
+		switch order {
+		case 0:
+			residual[i] = x[i]
+		case 1:
+			residual[i] = x[i] - x[i-1]
+		case 2:
+			residual[i] = x[i] - 2*x[i-1] + x[i-2]
+		case 3:
+			residual[i] = x[i] - 3*x[i-1] + 3*x[i-2] - x[i-3]
+		case 4:
+			residual[i] = x[i] - 4*x[i-1] + 6*x[i-2] - 4*x[i-3] + x[i-4]
+		}
+	}
+}
+
+// ricePlan is a partition order and a Rice parameter per partition
+type ricePlan struct {
+	order  int
+	params []int
+	bits   int
+}
+
+// partitionBounds returns the residual indexes of partition p. The first
+// partition is shorter by the predictor order because warm-up samples carry
+// no residual.
+func partitionBounds(p, partitionOrder, blockSize, predictorOrder int) (int, int) {
+	size := blockSize >> partitionOrder
+	start, end := p*size-predictorOrder, (p+1)*size-predictorOrder
+	if p == 0 {
+		start = 0
+	}
+	return start, end
+}
+
+// planRice chooses the partition order and parameters that minimise the
+// estimated size of residual, the residuals of a block of blockSize samples
+// after order warm-up samples
+func planRice(residual []int32, blockSize, order int) ricePlan {
+	maxOrder := 0
+	for maxOrder < flacMaxPartitionOrder && blockSize%(2<<maxOrder) == 0 && blockSize>>(maxOrder+1) > order {
+		maxOrder++
+	}
+
+	// Sums of zigzag-encoded residuals at the finest partitioning; coarser
+	// partitions add neighbouring sums
+	sums := make([]uint64, 1<<maxOrder)
+	for p := range sums {
+		start, end := partitionBounds(p, maxOrder, blockSize, order)
+		for _, r := range residual[start:end] {
+			sums[p] += uint64(zigzag(r))
+		}
+	}
+
+	var best ricePlan
+	for po := maxOrder; po >= 0; po-- {
+		plan := ricePlan{order: po, params: make([]int, 1<<po), bits: 2 + 4}
+		for p := range plan.params {
+			start, end := partitionBounds(p, po, blockSize, order)
+			k, size := bestRiceParam(sums[p], end-start)
+			plan.params[p] = k
+			plan.bits += 4 + size
+		}
+		if best.params == nil || plan.bits < best.bits {
+			best = plan
+		}
+
+		if po > 0 {
+			for p := range sums[:1<<(po-1)] {
+				sums[p] = sums[2*p] + sums[2*p+1]
+			}
+		}
+	}
+	return best
+}
+
+// bestRiceParam estimates the cheapest Rice parameter for n values whose
+// zigzag encodings add up to sum
+func bestRiceParam(sum uint64, n int) (int, int) {
+	bestK, bestBits := 0, -1
+	for k := 0; k <= flacMaxRiceParam; k++ {
+		size := uint64(n)*uint64(k+1) + sum>>k
+		if bestBits < 0 || size < uint64(bestBits) {
+			bestK, bestBits = k, int(size)
+		}
+	}
+	return bestK, bestBits
+}
+
+func writeRice(bw *bitWriter, residual []int32, blockSize, order int, plan ricePlan) {
+	bw.writeBits(0, 2) // 4-bit Rice parameters
+	bw.writeBits(uint64(plan.order), 4)
+	for p, k := range plan.params {
+		bw.writeBits(uint64(k), 4)
+		start, end := partitionBounds(p, plan.order, blockSize, order)
+		for _, r := range residual[start:end] {
+			u := uint64(zigzag(r))
+			bw.writeUnary(u >> k)
+			bw.writeBits(u&(1<<k-1), k)
+		}
+	}
+}
+
+func zigzag(r int32) uint32 {
+	return uint32(r<<1) ^ uint32(r>>31)
+}
+
+// bitWriter packs values most significant bit first
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits int
+}
+
+func (bw *bitWriter) writeBits(v uint64, n int) {
+	for n > 0 {
+		take := n
+		if free := 64 - bw.nbits; take > free {
+			take = free
+		}
+		n -= take
+		chunk := (v >> n) & (1<<take - 1)
+		if take == 64 {
+			bw.acc = chunk
+		} else {
+			bw.acc = bw.acc<<take | chunk
+		}
+		bw.nbits += take
+		for bw.nbits >= 8 {
+			bw.nbits -= 8
+			bw.buf = append(bw.buf, byte(bw.acc>>bw.nbits))
+		}
+	}
+}
+
+func (bw *bitWriter) writeSigned(v int64, n int) {
+	bw.writeBits(uint64(v)&(1<<n-1), n)
+}
+
+// writeUnary writes n zero bits followed by a one
+func (bw *bitWriter) writeUnary(n uint64) {
+	for n >= 32 {
+		bw.writeBits(0, 32)
+		n -= 32
+	}
+	bw.writeBits(1, int(n)+1)
+}
+
+func (bw *bitWriter) writeBytes(b []byte) {
+	for _, c := range b {
+		bw.writeBits(uint64(c), 8)
+	}
+}
+
+// align pads with zero bits to the next byte boundary
+func (bw *bitWriter) align() {
+	if bw.nbits > 0 {
+		bw.writeBits(0, 8-bw.nbits)
+	}
+}
+
+// bytes returns the completed bytes; a partial byte is not included
+func (bw *bitWriter) bytes() []byte {
+	return bw.buf
+}
+
+// crc8 is the FLAC frame header checksum (polynomial x^8 + x^2 + x + 1)
+func crc8(data []byte) uint8 {
+	var crc uint8
+	for _, b := range data {
+		crc = crc8Update(crc, b)
+	}
+	return crc
+}
+
+func crc8Update(crc uint8, b byte) uint8 {
+	crc ^= b
+	for i := 0; i < 8; i++ {
+		if crc&0x80 != 0 {
+			crc = crc<<1 ^ 0x07
+		} else {
+			crc <<= 1
+		}
+	}
+	return crc
+}
+
+// crc16 is the FLAC frame checksum (polynomial x^16 + x^15 + x^2 + 1)
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc = crc16Update(crc, b)
+	}
+	return crc
+}
+
+func crc16Update(crc uint16, b byte) uint16 {
+	crc ^= uint16(b) << 8
+	for i := 0; i < 8; i++ {
+		if crc&0x8000 != 0 {
+			crc = crc<<1 ^ 0x8005
+		} else {
+			crc <<= 1
+		}
+	}
+	return crc
+}
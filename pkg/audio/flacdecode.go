@@ -0,0 +1,548 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DecodeFLAC reads a FLAC stream and returns its samples scaled to 16 bits,
+// interleaved, along with the stream's format. The STREAMINFO signature is
+// checked when present.
+func DecodeFLAC(r io.Reader) ([]int16, Format, error) {
+	br := &bitReader{r: bufio.NewReader(r)}
+
+	marker := make([]byte, 4)
+	if _, err := io.ReadFull(br.r, marker); err != nil {
+		return nil, Format{}, fmt.Errorf("failed to read FLAC marker: %w", err)
+	}
+	if string(marker) != "fLaC" {
+		return nil, Format{}, fmt.Errorf("not a FLAC stream")
+	}
+
+	info, err := readFLACMetadata(br)
+	if err != nil {
+		return nil, Format{}, err
+	}
+
+	var out []int16
+	for frame := 0; ; frame++ {
+		samples, err := readFLACFrame(br, info)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, Format{}, fmt.Errorf("frame %d: %w", frame, err)
+		}
+		out = append(out, samples...)
+	}
+
+	format := Format{SampleRate: info.sampleRate, Channels: info.channels}
+	if info.totalSamples > 0 && uint64(len(out)/info.channels) != info.totalSamples {
+		return nil, Format{}, fmt.Errorf("FLAC stream has %d samples, header says %d", len(out)/info.channels, info.totalSamples)
+	}
+	if info.bitsPerSample == 16 && info.md5 != [16]byte{} {
+		raw := make([]byte, 2*len(out))
+		for i, s := range out {
+			writeInt16(raw[2*i:], uint16(s))
+		}
+		if md5.Sum(raw) != info.md5 {
+			return nil, Format{}, fmt.Errorf("FLAC signature mismatch")
+		}
+	}
+	return out, format, nil
+}
+
+type flacInfo struct {
+	sampleRate    int
+	channels      int
+	bitsPerSample int
+	totalSamples  uint64
+	md5           [16]byte
+}
+
+// readFLACMetadata reads STREAMINFO and skips the other metadata blocks
+func readFLACMetadata(br *bitReader) (flacInfo, error) {
+	var info flacInfo
+	seenInfo := false
+	for {
+		last, err := br.readBits(1)
+		if err != nil {
+			return info, fmt.Errorf("failed to read FLAC metadata: %w", err)
+		}
+		kind, _ := br.readBits(7)
+		length, err := br.readBits(24)
+		if err != nil {
+			return info, fmt.Errorf("failed to read FLAC metadata: %w", err)
+		}
+
+		block := make([]byte, length)
+		if _, err := io.ReadFull(br.r, block); err != nil {
+			return info, fmt.Errorf("failed to read FLAC metadata: %w", err)
+		}
+
+		if kind == 0 {
+			if length < 34 {
+				return info, fmt.Errorf("short STREAMINFO block")
+			}
+			ib := &bitReader{r: bufio.NewReader(bytes.NewReader(block))}
+			ib.readBits(16 + 16 + 24 + 24) // block and frame size bounds
+			rate, _ := ib.readBits(20)
+			channels, _ := ib.readBits(3)
+			bps, _ := ib.readBits(5)
+			total, _ := ib.readBits(36)
+			info = flacInfo{
+				sampleRate:    int(rate),
+				channels:      int(channels) + 1,
+				bitsPerSample: int(bps) + 1,
+				totalSamples:  total,
+			}
+			copy(info.md5[:], block[18:34])
+			seenInfo = true
+		}
+
+		if last == 1 {
+			break
+		}
+	}
+
+	if !seenInfo {
+		return info, fmt.Errorf("FLAC stream has no STREAMINFO block")
+	}
+	if info.bitsPerSample < 4 || info.bitsPerSample > 32 {
+		return info, fmt.Errorf("unsupported FLAC sample size %d", info.bitsPerSample)
+	}
+	return info, nil
+}
+
+var flacBlockSizes = [16]int{0, 192, 576, 1152, 2304, 4608, 0, 0, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768}
+
+var flacSampleRates = [12]int{0, 88200, 176400, 192000, 8000, 16000, 22050, 24000, 32000, 44100, 48000, 96000}
+
+var flacSampleSizes = [8]int{0, 8, 12, 0, 16, 20, 24, 32}
+
+// readFLACFrame decodes one frame into interleaved 16-bit samples. It returns
+// io.EOF at a clean end of stream.
+func readFLACFrame(br *bitReader, info flacInfo) ([]int16, error) {
+	br.startCRC()
+
+	sync, err := br.readBits(14)
+	if err != nil {
+		return nil, io.EOF
+	}
+	if sync != 0x3FFE {
+		return nil, fmt.Errorf("lost frame sync")
+	}
+	br.readBits(1) // reserved
+	br.readBits(1) // blocking strategy
+
+	blockCode, _ := br.readBits(4)
+	rateCode, _ := br.readBits(4)
+	channelCode, _ := br.readBits(4)
+	sizeCode, _ := br.readBits(3)
+	br.readBits(1)
+	if err := skipFLACNumber(br); err != nil {
+		return nil, err
+	}
+
+	blockSize := flacBlockSizes[blockCode]
+	switch blockCode {
+	case 0:
+		return nil, fmt.Errorf("reserved block size")
+	case 6:
+		v, _ := br.readBits(8)
+		blockSize = int(v) + 1
+	case 7:
+		v, _ := br.readBits(16)
+		blockSize = int(v) + 1
+	}
+
+	switch rateCode {
+	case 12:
+		br.readBits(8)
+	case 13, 14:
+		br.readBits(16)
+	case 15:
+		return nil, fmt.Errorf("invalid sample rate code")
+	}
+
+	bps := flacSampleSizes[sizeCode]
+	if sizeCode == 0 {
+		bps = info.bitsPerSample
+	}
+	if bps == 0 {
+		return nil, fmt.Errorf("reserved sample size")
+	}
+
+	headerCRC := br.crc8()
+	crc, err := br.readBits(8)
+	if err != nil {
+		return nil, err
+	}
+	if uint8(crc) != headerCRC {
+		return nil, fmt.Errorf("frame header checksum mismatch")
+	}
+
+	channels := int(channelCode) + 1
+	if channelCode >= 8 {
+		if channelCode > 10 {
+			return nil, fmt.Errorf("reserved channel assignment")
+		}
+		channels = 2
+	}
+	if channels != info.channels {
+		return nil, fmt.Errorf("frame has %d channels, stream has %d", channels, info.channels)
+	}
+
+	subframes := make([][]int64, channels)
+	for ch := range subframes {
+		sampleBits := bps
+		// The side channel needs one extra bit
+		if (channelCode == 8 && ch == 1) || (channelCode == 9 && ch == 0) || (channelCode == 10 && ch == 1) {
+			sampleBits++
+		}
+		subframes[ch], err = readFLACSubframe(br, blockSize, sampleBits)
+		if err != nil {
+			return nil, fmt.Errorf("channel %d: %w", ch, err)
+		}
+	}
+
+	br.align()
+	frameCRC := br.crc16()
+	crc, err = br.readBits(16)
+	if err != nil {
+		return nil, err
+	}
+	if uint16(crc) != frameCRC {
+		return nil, fmt.Errorf("frame checksum mismatch")
+	}
+
+	switch channelCode {
+	case 8: // left, side
+		for i := range subframes[1] {
+			subframes[1][i] = subframes[0][i] - subframes[1][i]
+		}
+	case 9: // side, right
+		for i := range subframes[0] {
+			subframes[0][i] += subframes[1][i]
+		}
+	case 10: // mid, side
+		for i := range subframes[0] {
+			mid, side := subframes[0][i]<<1|subframes[1][i]&1, subframes[1][i]
+			subframes[0][i] = (mid + side) >> 1
+			subframes[1][i] = (mid - side) >> 1
+		}
+	}
+
+	out := make([]int16, blockSize*channels)
+	for ch, samples := range subframes {
+		for i, s := range samples {
+			out[i*channels+ch] = scaleTo16(s, bps)
+		}
+	}
+	return out, nil
+}
+
+// skipFLACNumber reads past a frame or sample number in UTF-8-like coding
+func skipFLACNumber(br *bitReader) error {
+	lead, err := br.readBits(8)
+	if err != nil {
+		return err
+	}
+	extra := 0
+	for mask := uint64(0x80); lead&mask != 0 && mask > 1; mask >>= 1 {
+		extra++
+	}
+	if extra == 1 || extra > 7 {
+		return fmt.Errorf("invalid frame number")
+	}
+	if extra > 1 {
+		if _, err := br.readBits(8 * (extra - 1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFLACSubframe(br *bitReader, blockSize, bps int) ([]int64, error) {
+	if pad, _ := br.readBits(1); pad != 0 {
+		return nil, fmt.Errorf("invalid subframe padding")
+	}
+	kind, _ := br.readBits(6)
+	wasted := 0
+	if flag, _ := br.readBits(1); flag == 1 {
+		for {
+			bit, err := br.readBits(1)
+			if err != nil {
+				return nil, err
+			}
+			wasted++
+			if bit == 1 {
+				break
+			}
+		}
+	}
+	bps -= wasted
+
+	samples := make([]int64, blockSize)
+	var err error
+	switch {
+	case kind == 0:
+		v, e := br.readSigned(bps)
+		for i := range samples {
+			samples[i] = v
+		}
+		err = e
+	case kind == 1:
+		for i := range samples {
+			if samples[i], err = br.readSigned(bps); err != nil {
+				break
+			}
+		}
+	case kind >= 8 && kind <= 12:
+		err = readFLACFixed(br, samples, int(kind-8), bps)
+	case kind >= 32:
+		err = readFLACLPC(br, samples, int(kind-31), bps)
+	default:
+		return nil, fmt.Errorf("reserved subframe type %d", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if wasted > 0 {
+		for i := range samples {
+			samples[i] <<= wasted
+		}
+	}
+	return samples, nil
+}
+
+func readFLACFixed(br *bitReader, samples []int64, order, bps int) error {
+	if order > len(samples) {
+		return fmt.Errorf("predictor order %d exceeds block size", order)
+	}
+	for i := 0; i < order; i++ {
+		v, err := br.readSigned(bps)
+		if err != nil {
+			return err
+		}
+		samples[i] = v
+	}
+	if err := readFLACResidual(br, samples, order); err != nil {
+		return err
+	}
+
+	for i := order; i < len(samples); i++ {
+		switch order {
+		case 1:
+			samples[i] += samples[i-1]
+		case 2:
+			samples[i] += 2*samples[i-1] - samples[i-2]
+		case 3:
+			samples[i] += 3*samples[i-1] - 3*samples[i-2] + samples[i-3]
+		case 4:
+			samples[i] += 4*samples[i-1] - 6*samples[i-2] + 4*samples[i-3] - samples[i-4]
+		}
+	}
+	return nil
+}
+
+func readFLACLPC(br *bitReader, samples []int64, order, bps int) error {
+	if order > len(samples) {
+		return fmt.Errorf("predictor order %d exceeds block size", order)
+	}
+	for i := 0; i < order; i++ {
+		v, err := br.readSigned(bps)
+		if err != nil {
+			return err
+		}
+		samples[i] = v
+	}
+
+	precision, _ := br.readBits(4)
+	if precision == 15 {
+		return fmt.Errorf("invalid LPC precision")
+	}
+	shift, err := br.readSigned(5)
+	if err != nil {
+		return err
+	}
+	if shift < 0 {
+		return fmt.Errorf("negative LPC shift")
+	}
+	coeffs := make([]int64, order)
+	for i := range coeffs {
+		if coeffs[i], err = br.readSigned(int(precision) + 1); err != nil {
+			return err
+		}
+	}
+
+	if err := readFLACResidual(br, samples, order); err != nil {
+		return err
+	}
+
+	for i := order; i < len(samples); i++ {
+		var sum int64
+		for j, c := range coeffs {
+			sum += c * samples[i-1-j]
+		}
+		samples[i] += sum >> shift
+	}
+	return nil
+}
+
+// readFLACResidual reads Rice-coded residuals into samples[order:]
+func readFLACResidual(br *bitReader, samples []int64, order int) error {
+	method, _ := br.readBits(2)
+	if method > 1 {
+		return fmt.Errorf("reserved residual coding method")
+	}
+	paramBits, escape := 4, uint64(15)
+	if method == 1 {
+		paramBits, escape = 5, 31
+	}
+
+	partitionOrder, err := br.readBits(4)
+	if err != nil {
+		return err
+	}
+	partitions := 1 << partitionOrder
+	size := len(samples) >> partitionOrder
+	if size<<partitionOrder != len(samples) || size < order {
+		return fmt.Errorf("invalid residual partition order %d", partitionOrder)
+	}
+
+	i := order
+	for p := 0; p < partitions; p++ {
+		end := (p + 1) * size
+		param, err := br.readBits(paramBits)
+		if err != nil {
+			return err
+		}
+
+		if param == escape {
+			n, _ := br.readBits(5)
+			for ; i < end; i++ {
+				if samples[i], err = br.readSigned(int(n)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		for ; i < end; i++ {
+			q, err := br.readUnary()
+			if err != nil {
+				return err
+			}
+			low, err := br.readBits(int(param))
+			if err != nil {
+				return err
+			}
+			u := q<<param | low
+			samples[i] = int64(u>>1) ^ -int64(u&1)
+		}
+	}
+	return nil
+}
+
+// scaleTo16 converts a sample of bps bits to 16 bits
+func scaleTo16(s int64, bps int) int16 {
+	if bps > 16 {
+		return int16(s >> (bps - 16))
+	}
+	return int16(s << (16 - bps))
+}
+
+// bitReader reads values most significant bit first and keeps running
+// checksums of the bytes it consumes
+type bitReader struct {
+	r     *bufio.Reader
+	acc   uint64
+	nbits int
+
+	crcOn bool
+	c8    uint8
+	c16   uint16
+}
+
+func (br *bitReader) readByte() (byte, error) {
+	b, err := br.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if br.crcOn {
+		br.c8 = crc8Update(br.c8, b)
+		br.c16 = crc16Update(br.c16, b)
+	}
+	return b, nil
+}
+
+func (br *bitReader) readBits(n int) (uint64, error) {
+	var v uint64
+	for n > 0 {
+		if br.nbits == 0 {
+			b, err := br.readByte()
+			if err != nil {
+				if err == io.EOF {
+					return 0, io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+			br.acc, br.nbits = uint64(b), 8
+		}
+		take := n
+		if take > br.nbits {
+			take = br.nbits
+		}
+		br.nbits -= take
+		n -= take
+		v = v<<take | (br.acc>>br.nbits)&(1<<take-1)
+	}
+	return v, nil
+}
+
+func (br *bitReader) readSigned(n int) (int64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	v, err := br.readBits(n)
+	if err != nil {
+		return 0, err
+	}
+	return int64(v<<(64-n)) >> (64 - n), nil
+}
+
+// readUnary counts zero bits up to the next one
+func (br *bitReader) readUnary() (uint64, error) {
+	var n uint64
+	for {
+		bit, err := br.readBits(1)
+		if err != nil {
+			return 0, err
+		}
+		if bit == 1 {
+			return n, nil
+		}
+		n++
+	}
+}
+
+// align discards bits up to the next byte boundary
+func (br *bitReader) align() {
+	br.nbits = 0
+}
+
+func (br *bitReader) startCRC() {
+	br.crcOn, br.c8, br.c16 = true, 0, 0
+}
+
+// crc8 returns the checksum of the bytes read since startCRC
+func (br *bitReader) crc8() uint8 { return br.c8 }
+
+// crc16 returns the checksum of the bytes read since startCRC
+func (br *bitReader) crc16() uint16 { return br.c16 }
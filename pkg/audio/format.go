@@ -0,0 +1,92 @@
+package audio
+
+import "fmt"
+
+// Format describes interleaved 16-bit PCM audio
+type Format struct {
+	SampleRate int
+	Channels   int
+}
+
+// TargetFormat is the format handed to transcription: SampleRate Hz, Channels channels
+func TargetFormat() Format {
+	return Format{SampleRate: SampleRate, Channels: Channels}
+}
+
+// Validate reports whether the format can describe real audio
+func (f Format) Validate() error {
+	if f.SampleRate <= 0 {
+		return fmt.Errorf("invalid sample rate %d", f.SampleRate)
+	}
+	if f.Channels <= 0 {
+		return fmt.Errorf("invalid channel count %d", f.Channels)
+	}
+	return nil
+}
+
+// String returns a description such as "44100 Hz, 2 channels"
+func (f Format) String() string {
+	if f.Channels == 1 {
+		return fmt.Sprintf("%d Hz, mono", f.SampleRate)
+	}
+	return fmt.Sprintf("%d Hz, %d channels", f.SampleRate, f.Channels)
+}
+
+// Downmix averages interleaved channels into a single mono channel. A
+// trailing partial frame is dropped. Mono input is returned unchanged.
+func Downmix(samples []int16, channels int) []int16 {
+	if channels <= 1 {
+		return samples
+	}
+
+	out := make([]int16, len(samples)/channels)
+	for i := range out {
+		var sum int
+		for _, s := range samples[i*channels : (i+1)*channels] {
+			sum += int(s)
+		}
+		out[i] = int16(sum / channels)
+	}
+	return out
+}
+
+// Convert downmixes and resamples a whole recording from one format to mono
+// at the target rate
+func Convert(samples []int16, from Format, toRate int) []int16 {
+	c := newConverter(from, toRate)
+	out := c.Process(samples)
+	return append(out, c.Flush()...)
+}
+
+// converter turns audio in the device's format into the target format as it
+// arrives, keeping resampler state between calls
+type converter struct {
+	channels  int
+	resampler *Resampler
+}
+
+func newConverter(from Format, toRate int) *converter {
+	c := &converter{channels: from.Channels}
+	if from.SampleRate != toRate {
+		c.resampler = NewResampler(from.SampleRate, toRate)
+	}
+	return c
+}
+
+// Process converts the next block of interleaved samples. The result may
+// alias in when no conversion is needed.
+func (c *converter) Process(in []int16) []int16 {
+	mono := Downmix(in, c.channels)
+	if c.resampler == nil {
+		return mono
+	}
+	return c.resampler.Process(mono)
+}
+
+// Flush returns the samples still held back by the resampler
+func (c *converter) Flush() []int16 {
+	if c.resampler == nil {
+		return nil
+	}
+	return c.resampler.Flush()
+}
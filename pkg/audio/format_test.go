@@ -0,0 +1,134 @@
+package audio
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDownmix(t *testing.T) {
+	tests := []struct {
+		name     string
+		samples  []int16
+		channels int
+		want     []int16
+	}{
+		{name: "mono unchanged", samples: []int16{1, 2, 3}, channels: 1, want: []int16{1, 2, 3}},
+		{name: "stereo averaged", samples: []int16{100, 200, -50, 50}, channels: 2, want: []int16{150, 0}},
+		{name: "no overflow at full scale", samples: []int16{32767, 32767}, channels: 2, want: []int16{32767}},
+		{name: "partial frame dropped", samples: []int16{10, 20, 30, 40, 50}, channels: 2, want: []int16{15, 35}},
+		{name: "four channels", samples: []int16{4, 8, 12, 16}, channels: 4, want: []int16{10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Downmix(tt.samples, tt.channels); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Downmix() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func sine(freq float64, rate, n int, amplitude float64) []int16 {
+	out := make([]int16, n)
+	for i := range out {
+		out[i] = int16(amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return out
+}
+
+// toneLevel measures the amplitude of freq in samples by correlating with a
+// quadrature pair, ignoring the edges
+func toneLevel(samples []int16, freq float64, rate int) float64 {
+	edge := len(samples) / 10
+	var re, im float64
+	for i := edge; i < len(samples)-edge; i++ {
+		phase := 2 * math.Pi * freq * float64(i) / float64(rate)
+		re += float64(samples[i]) * math.Cos(phase)
+		im += float64(samples[i]) * math.Sin(phase)
+	}
+	n := float64(len(samples) - 2*edge)
+	return 2 * math.Hypot(re, im) / n
+}
+
+func TestResample(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int
+	}{
+		{name: "48k to 16k", from: 48000, to: 16000},
+		{name: "44.1k to 16k", from: 44100, to: 16000},
+		{name: "8k to 16k", from: 8000, to: 16000},
+		{name: "22.05k to 16k", from: 22050, to: 16000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := sine(1000, tt.from, tt.from/2, 10000)
+			out := Resample(in, tt.from, tt.to)
+
+			wantLen := (len(in)*tt.to + tt.from - 1) / tt.from
+			if len(out) != wantLen {
+				t.Errorf("len(Resample()) = %d, want %d", len(out), wantLen)
+			}
+			if level := toneLevel(out, 1000, tt.to); math.Abs(level-10000) > 100 {
+				t.Errorf("1 kHz level after resampling = %.0f, want 10000", level)
+			}
+		})
+	}
+}
+
+func TestResample_RemovesAliases(t *testing.T) {
+	// 12 kHz is above the 8 kHz Nyquist limit of the output and must not fold
+	// back to 4 kHz
+	in := sine(12000, 48000, 24000, 10000)
+	out := Resample(in, 48000, 16000)
+
+	if level := toneLevel(out, 4000, 16000); level > 10 {
+		t.Errorf("alias level at 4 kHz = %.1f, want below 10", level)
+	}
+}
+
+func TestResample_SameRate(t *testing.T) {
+	in := []int16{1, 2, 3}
+	if got := Resample(in, 16000, 16000); !reflect.DeepEqual(got, in) {
+		t.Errorf("Resample() = %v, want %v", got, in)
+	}
+}
+
+func TestResampler_BlocksMatchWhole(t *testing.T) {
+	in := sine(440, 44100, 10000, 8000)
+	want := Resample(in, 44100, 16000)
+
+	r := NewResampler(44100, 16000)
+	var got []int16
+	for start := 0; start < len(in); start += 333 {
+		end := start + 333
+		if end > len(in) {
+			end = len(in)
+		}
+		got = append(got, r.Process(in[start:end])...)
+	}
+	got = append(got, r.Flush()...)
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("block-wise output differs from whole-buffer output (%d vs %d samples)", len(got), len(want))
+	}
+}
+
+func TestConvert(t *testing.T) {
+	// Half a second of 48 kHz stereo with the tone on the left channel only
+	mono := sine(500, 48000, 24000, 8000)
+	stereo := make([]int16, 2*len(mono))
+	for i, s := range mono {
+		stereo[2*i] = s
+	}
+
+	out := Convert(stereo, Format{SampleRate: 48000, Channels: 2}, 16000)
+	if len(out) != 8000 {
+		t.Fatalf("len(Convert()) = %d, want 8000", len(out))
+	}
+	if level := toneLevel(out, 500, 16000); math.Abs(level-4000) > 50 {
+		t.Errorf("500 Hz level = %.0f, want 4000", level)
+	}
+}
@@ -0,0 +1,179 @@
+package audio
+
+import "math"
+
+const (
+	// resamplerTaps is the number of filter taps on each side of an output
+	// sample at full bandwidth; downsampling widens the filter to match
+	resamplerTaps = 32
+	// resamplerRolloff places the passband edge just below the lower of the
+	// two Nyquist frequencies, leaving room for the transition band
+	resamplerRolloff = 0.95
+	// kaiserBeta trades stopband attenuation (about 80 dB) against transition width
+	kaiserBeta = 8.0
+)
+
+// Resampler converts mono audio between sample rates with a Kaiser-windowed
+// sinc filter. It keeps state between calls so a stream can be converted
+// block by block without clicks at the boundaries.
+type Resampler struct {
+	// Output sample n sits at input position n*down/up
+	up, down int
+	cutoff   float64
+	half     int
+	rows     [][]float64
+
+	// hist holds input samples from absolute index base onwards
+	hist []float64
+	base int
+	// pos and frac locate the next output at input position pos + frac/up
+	pos, frac int
+	in, out   int
+}
+
+// NewResampler creates a resampler from one sample rate to another
+func NewResampler(from, to int) *Resampler {
+	g := gcd(from, to)
+	r := &Resampler{
+		up:     to / g,
+		down:   from / g,
+		cutoff: resamplerRolloff * math.Min(1, float64(to)/float64(from)),
+	}
+	r.half = int(math.Ceil(resamplerTaps / r.cutoff))
+	r.rows = make([][]float64, r.up)
+	r.reset()
+	return r
+}
+
+// Resample converts a whole recording from one sample rate to another
+func Resample(samples []int16, from, to int) []int16 {
+	if from == to {
+		return samples
+	}
+	r := NewResampler(from, to)
+	out := r.Process(samples)
+	return append(out, r.Flush()...)
+}
+
+func (r *Resampler) reset() {
+	// Leading zeros let the first outputs see a full window
+	r.hist = make([]float64, r.half)
+	r.base = -r.half
+	r.pos, r.frac = 0, 0
+	r.in, r.out = 0, 0
+}
+
+// Process consumes the next block of input and returns every output sample
+// that can be computed so far
+func (r *Resampler) Process(in []int16) []int16 {
+	for _, s := range in {
+		r.hist = append(r.hist, float64(s))
+	}
+	r.in += len(in)
+	return r.drain(r.in)
+}
+
+// Flush returns the outputs still held back waiting for future input and
+// resets the resampler for a new stream
+func (r *Resampler) Flush() []int16 {
+	r.hist = append(r.hist, make([]float64, r.half)...)
+	out := r.drain(r.in + r.half)
+	r.reset()
+	return out
+}
+
+// drain computes outputs whose filter window ends before available, then
+// discards input no future output will need
+func (r *Resampler) drain(available int) []int16 {
+	// Never produce more than the input length implies, even when flushing
+	limit := (r.in*r.up + r.down - 1) / r.down
+
+	var out []int16
+	for r.pos+r.half < available && r.out < limit {
+		row := r.row(r.frac)
+		start := r.pos - r.half + 1 - r.base
+		var sum float64
+		for j, h := range row {
+			sum += r.hist[start+j] * h
+		}
+		out = append(out, clampInt16(sum))
+
+		r.out++
+		r.frac += r.down
+		r.pos += r.frac / r.up
+		r.frac %= r.up
+	}
+
+	if drop := r.pos - r.half + 1 - r.base; drop > 0 {
+		n := copy(r.hist, r.hist[drop:])
+		r.hist = r.hist[:n]
+		r.base += drop
+	}
+	return out
+}
+
+// row returns the filter taps for outputs at fractional offset phase/up,
+// computing them on first use. Taps are normalised to unity gain at DC.
+func (r *Resampler) row(phase int) []float64 {
+	if r.rows[phase] != nil {
+		return r.rows[phase]
+	}
+
+	offset := float64(phase) / float64(r.up)
+	row := make([]float64, 2*r.half)
+	var sum float64
+	for i := range row {
+		x := offset + float64(r.half-1-i)
+		row[i] = r.cutoff * sinc(r.cutoff*x) * kaiser(x/float64(r.half))
+		sum += row[i]
+	}
+	for i := range row {
+		row[i] /= sum
+	}
+
+	r.rows[phase] = row
+	return row
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiser evaluates the Kaiser window at x in [-1, 1]
+func kaiser(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	return besselI0(kaiserBeta*math.Sqrt(1-x*x)) / besselI0(kaiserBeta)
+}
+
+// besselI0 is the zeroth-order modified Bessel function of the first kind
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > sum*1e-12; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+	}
+	return sum
+}
+
+func clampInt16(v float64) int16 {
+	v = math.Round(v)
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
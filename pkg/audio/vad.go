@@ -0,0 +1,150 @@
+package audio
+
+import "time"
+
+// VADConfig controls the energy and zero-crossing voice activity detector
+type VADConfig struct {
+	// FrameDuration is the analysis window length
+	FrameDuration time.Duration
+	// EnergyThreshold is the RMS amplitude above which a frame is speech
+	EnergyThreshold float64
+	// ZeroCrossingThreshold is the zero-crossing rate (crossings per sample)
+	// above which a quieter frame, at least half EnergyThreshold, still counts
+	// as speech. This keeps unvoiced sounds like "s" and "f" that carry little
+	// energy but cross zero often.
+	ZeroCrossingThreshold float64
+	// Padding is the audio kept around detected speech when trimming
+	Padding time.Duration
+}
+
+// DefaultVADConfig returns detector settings suited to a close-talking microphone
+func DefaultVADConfig() VADConfig {
+	return VADConfig{
+		FrameDuration:         20 * time.Millisecond,
+		EnergyThreshold:       500,
+		ZeroCrossingThreshold: 0.25,
+		Padding:               200 * time.Millisecond,
+	}
+}
+
+// VAD detects which parts of a recording contain speech
+type VAD struct {
+	cfg          VADConfig
+	frameSamples int
+	padSamples   int
+}
+
+// NewVAD creates a detector for audio at the given sample rate
+func NewVAD(cfg VADConfig, sampleRate int) *VAD {
+	frameSamples := durationToSamples(cfg.FrameDuration, sampleRate)
+	if frameSamples < 1 {
+		frameSamples = 1
+	}
+
+	return &VAD{
+		cfg:          cfg,
+		frameSamples: frameSamples,
+		padSamples:   durationToSamples(cfg.Padding, sampleRate),
+	}
+}
+
+// IsSpeech reports whether a single frame contains speech
+func (v *VAD) IsSpeech(frame []int16) bool {
+	energy := rms(frame)
+	if energy >= v.cfg.EnergyThreshold {
+		return true
+	}
+	return v.cfg.ZeroCrossingThreshold > 0 &&
+		energy >= v.cfg.EnergyThreshold/2 &&
+		zeroCrossingRate(frame) >= v.cfg.ZeroCrossingThreshold
+}
+
+// HasSpeech reports whether any analysis window of the recording is speech
+func (v *VAD) HasSpeech(data []int16) bool {
+	first, _ := v.speechBounds(data)
+	return first >= 0
+}
+
+// TrimSilence returns the part of data from the first to the last speech
+// window, widened by the configured padding. It returns nil when the
+// recording holds no speech at all. The result shares data's backing array.
+func (v *VAD) TrimSilence(data []int16) []int16 {
+	first, last := v.speechBounds(data)
+	if first < 0 {
+		return nil
+	}
+
+	start := first - v.padSamples
+	if start < 0 {
+		start = 0
+	}
+	end := last + v.padSamples
+	if end > len(data) {
+		end = len(data)
+	}
+	return data[start:end]
+}
+
+// speechBounds returns the sample offsets of the start of the first speech
+// window and the end of the last one, or -1, -1 if there is no speech
+func (v *VAD) speechBounds(data []int16) (int, int) {
+	first, last := -1, -1
+	for start := 0; start < len(data); start += v.frameSamples {
+		end := start + v.frameSamples
+		if end > len(data) {
+			end = len(data)
+		}
+		if v.IsSpeech(data[start:end]) {
+			if first < 0 {
+				first = start
+			}
+			last = end
+		}
+	}
+	return first, last
+}
+
+// SilenceMonitor watches live frames and reports when speech has been
+// followed by a long enough stretch of silence
+type SilenceMonitor struct {
+	vad            *VAD
+	silenceSamples int
+	heardSpeech    bool
+	silentRun      int
+}
+
+// NewSilenceMonitor creates a monitor that fires after silence of the given
+// length following speech, for audio at the given sample rate
+func NewSilenceMonitor(vad *VAD, silence time.Duration, sampleRate int) *SilenceMonitor {
+	return &SilenceMonitor{
+		vad:            vad,
+		silenceSamples: durationToSamples(silence, sampleRate),
+	}
+}
+
+// Push analyzes a frame and reports whether recording should stop
+func (m *SilenceMonitor) Push(frame []int16) bool {
+	if m.vad.IsSpeech(frame) {
+		m.heardSpeech = true
+		m.silentRun = 0
+		return false
+	}
+
+	m.silentRun += len(frame)
+	return m.heardSpeech && m.silentRun >= m.silenceSamples
+}
+
+// zeroCrossingRate returns the fraction of adjacent sample pairs that change sign
+func zeroCrossingRate(frame []int16) float64 {
+	if len(frame) < 2 {
+		return 0
+	}
+
+	crossings := 0
+	for i := 1; i < len(frame); i++ {
+		if (frame[i-1] >= 0) != (frame[i] >= 0) {
+			crossings++
+		}
+	}
+	return float64(crossings) / float64(len(frame)-1)
+}
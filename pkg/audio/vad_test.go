@@ -0,0 +1,152 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+// alternatingFrame returns a square wave flipping sign every period samples
+func alternatingFrame(n int, amplitude int16, period int) []int16 {
+	frame := make([]int16, n)
+	for i := range frame {
+		if (i/period)%2 == 0 {
+			frame[i] = amplitude
+		} else {
+			frame[i] = -amplitude
+		}
+	}
+	return frame
+}
+
+func testVAD() *VAD {
+	return NewVAD(VADConfig{
+		FrameDuration:         10 * time.Millisecond,
+		EnergyThreshold:       500,
+		ZeroCrossingThreshold: 0.25,
+		Padding:               20 * time.Millisecond,
+	}, 1000)
+}
+
+func TestVAD_IsSpeech(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []int16
+		want  bool
+	}{
+		{name: "silence", frame: constantFrame(10, 0), want: false},
+		{name: "loud voiced", frame: alternatingFrame(10, 2000, 5), want: true},
+		{name: "quiet noise", frame: alternatingFrame(10, 100, 1), want: false},
+		{name: "quiet fricative", frame: alternatingFrame(10, 300, 1), want: true},
+		{name: "quiet low frequency", frame: alternatingFrame(10, 300, 5), want: false},
+	}
+
+	vad := testVAD()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vad.IsSpeech(tt.frame); got != tt.want {
+				t.Errorf("IsSpeech() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVAD_TrimSilence(t *testing.T) {
+	vad := testVAD()
+
+	var data []int16
+	data = append(data, constantFrame(100, 0)...)
+	data = append(data, alternatingFrame(50, 2000, 5)...)
+	data = append(data, constantFrame(100, 0)...)
+
+	trimmed := vad.TrimSilence(data)
+	// 50 samples of speech plus 20 samples of padding on each side
+	if len(trimmed) != 90 {
+		t.Fatalf("TrimSilence() length = %d, want 90", len(trimmed))
+	}
+
+	if trimmed[20] != 2000 {
+		t.Errorf("TrimSilence() speech starts with %d, want 2000", trimmed[20])
+	}
+}
+
+func TestVAD_TrimSilenceAtEdges(t *testing.T) {
+	vad := testVAD()
+
+	data := alternatingFrame(35, 2000, 5)
+	if got := vad.TrimSilence(data); len(got) != len(data) {
+		t.Errorf("TrimSilence() length = %d, want %d", len(got), len(data))
+	}
+}
+
+func TestVAD_NoSpeech(t *testing.T) {
+	vad := testVAD()
+	data := alternatingFrame(300, 50, 1)
+
+	if vad.HasSpeech(data) {
+		t.Error("HasSpeech() = true, want false")
+	}
+
+	if got := vad.TrimSilence(data); got != nil {
+		t.Errorf("TrimSilence() = %d samples, want nil", len(got))
+	}
+
+	if vad.HasSpeech(nil) {
+		t.Error("HasSpeech(nil) = true, want false")
+	}
+}
+
+func TestSilenceMonitor(t *testing.T) {
+	monitor := NewSilenceMonitor(testVAD(), 50*time.Millisecond, 1000)
+	silence := constantFrame(20, 0)
+	speech := alternatingFrame(20, 2000, 5)
+
+	// Silence before any speech never stops the recording
+	for i := 0; i < 10; i++ {
+		if monitor.Push(silence) {
+			t.Fatal("Push() stopped before any speech")
+		}
+	}
+
+	if monitor.Push(speech) {
+		t.Fatal("Push() stopped on speech")
+	}
+
+	if monitor.Push(silence) || monitor.Push(silence) {
+		t.Fatal("Push() stopped before the silence duration")
+	}
+
+	if monitor.Push(speech) {
+		t.Fatal("Push() stopped on speech")
+	}
+
+	for i := 0; i < 2; i++ {
+		if monitor.Push(silence) {
+			t.Fatal("Push() did not reset the silence run after speech")
+		}
+	}
+
+	if !monitor.Push(silence) {
+		t.Error("Push() did not stop after 60ms of silence")
+	}
+}
+
+func TestZeroCrossingRate(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []int16
+		want  float64
+	}{
+		{name: "empty", frame: nil, want: 0},
+		{name: "no crossings", frame: []int16{1, 2, 3}, want: 0},
+		{name: "every sample", frame: []int16{1, -1, 1, -1, 1}, want: 1},
+		{name: "half", frame: []int16{1, -1, -1, 1, 1}, want: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := zeroCrossingRate(tt.frame); got != tt.want {
+				t.Errorf("zeroCrossingRate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
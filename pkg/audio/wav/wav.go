@@ -0,0 +1,538 @@
+// Package wav reads and writes RIFF WAVE files with integer PCM or IEEE
+// float samples, including WAVE_FORMAT_EXTENSIBLE headers and LIST/INFO
+// metadata.
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Encoding is how each sample is stored
+type Encoding int
+
+const (
+	PCM8 Encoding = iota + 1
+	PCM16
+	PCM24
+	PCM32
+	Float32
+	Float64
+)
+
+// BitsPerSample returns the stored size of one sample
+func (e Encoding) BitsPerSample() int {
+	switch e {
+	case PCM8:
+		return 8
+	case PCM16:
+		return 16
+	case PCM24:
+		return 24
+	case PCM32, Float32:
+		return 32
+	case Float64:
+		return 64
+	}
+	return 0
+}
+
+// IsFloat reports whether samples are IEEE floats
+func (e Encoding) IsFloat() bool {
+	return e == Float32 || e == Float64
+}
+
+// String returns a name such as "pcm16" or "float32"
+func (e Encoding) String() string {
+	switch {
+	case e.BitsPerSample() == 0:
+		return fmt.Sprintf("Encoding(%d)", int(e))
+	case e.IsFloat():
+		return fmt.Sprintf("float%d", e.BitsPerSample())
+	}
+	return fmt.Sprintf("pcm%d", e.BitsPerSample())
+}
+
+// Format describes the samples in a file
+type Format struct {
+	SampleRate int
+	Channels   int
+	Encoding   Encoding
+}
+
+// Validate reports whether the format can be written
+func (f Format) Validate() error {
+	if f.SampleRate <= 0 {
+		return fmt.Errorf("invalid sample rate %d", f.SampleRate)
+	}
+	if f.Channels <= 0 || f.Channels > math.MaxUint16 {
+		return fmt.Errorf("invalid channel count %d", f.Channels)
+	}
+	if f.Encoding.BitsPerSample() == 0 {
+		return fmt.Errorf("invalid encoding %v", f.Encoding)
+	}
+	return nil
+}
+
+// frameSize is the number of bytes holding one sample for every channel
+func (f Format) frameSize() int {
+	return f.Channels * f.Encoding.BitsPerSample() / 8
+}
+
+// File is a WAV file held in memory
+type File struct {
+	Format Format
+	// Data holds the samples as stored: interleaved and little-endian
+	Data []byte
+	// Info holds LIST/INFO tags keyed by four-character ID, such as INAM
+	// for the title or ICMT for a comment
+	Info map[string]string
+}
+
+// NewInt16 creates a file from interleaved 16-bit samples, stored with
+// the given encoding
+func NewInt16(samples []int16, sampleRate, channels int, enc Encoding) (*File, error) {
+	f := &File{Format: Format{SampleRate: sampleRate, Channels: channels, Encoding: enc}}
+	if err := f.Format.Validate(); err != nil {
+		return nil, err
+	}
+	if len(samples)%channels != 0 {
+		return nil, fmt.Errorf("%d samples do not fill %d-channel frames", len(samples), channels)
+	}
+
+	width := enc.BitsPerSample() / 8
+	f.Data = make([]byte, len(samples)*width)
+	for i, s := range samples {
+		putSample(f.Data[i*width:], enc, float64(s)/32768)
+	}
+	return f, nil
+}
+
+// NewFloat32 creates a file from interleaved samples in [-1, 1], stored
+// with the given encoding
+func NewFloat32(samples []float32, sampleRate, channels int, enc Encoding) (*File, error) {
+	f := &File{Format: Format{SampleRate: sampleRate, Channels: channels, Encoding: enc}}
+	if err := f.Format.Validate(); err != nil {
+		return nil, err
+	}
+	if len(samples)%channels != 0 {
+		return nil, fmt.Errorf("%d samples do not fill %d-channel frames", len(samples), channels)
+	}
+
+	width := enc.BitsPerSample() / 8
+	f.Data = make([]byte, len(samples)*width)
+	for i, s := range samples {
+		putSample(f.Data[i*width:], enc, float64(s))
+	}
+	return f, nil
+}
+
+// Frames returns the number of sample frames, one sample per channel each
+func (f *File) Frames() int {
+	if size := f.Format.frameSize(); size > 0 {
+		return len(f.Data) / size
+	}
+	return 0
+}
+
+// Int16 returns the interleaved samples scaled to 16 bits. Wider integer
+// samples are truncated and float samples are clipped to [-1, 1).
+func (f *File) Int16() []int16 {
+	width := f.Format.Encoding.BitsPerSample() / 8
+	out := make([]int16, f.Frames()*f.Format.Channels)
+	for i := range out {
+		b := f.Data[i*width:]
+		switch f.Format.Encoding {
+		case PCM8:
+			out[i] = int16(int(b[0])-128) << 8
+		case PCM16:
+			out[i] = int16(binary.LittleEndian.Uint16(b))
+		case PCM24:
+			out[i] = int16(uint16(b[1]) | uint16(b[2])<<8)
+		case PCM32:
+			out[i] = int16(binary.LittleEndian.Uint32(b) >> 16)
+		default:
+			out[i] = floatToInt16(sample(b, f.Format.Encoding))
+		}
+	}
+	return out
+}
+
+// Float32 returns the interleaved samples scaled to [-1, 1)
+func (f *File) Float32() []float32 {
+	width := f.Format.Encoding.BitsPerSample() / 8
+	out := make([]float32, f.Frames()*f.Format.Channels)
+	for i := range out {
+		out[i] = float32(sample(f.Data[i*width:], f.Format.Encoding))
+	}
+	return out
+}
+
+// sample reads one stored sample as a float where full scale is [-1, 1)
+func sample(b []byte, enc Encoding) float64 {
+	switch enc {
+	case PCM8:
+		return float64(int(b[0])-128) / (1 << 7)
+	case PCM16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case PCM24:
+		v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+		return float64(v) / (1 << 23)
+	case PCM32:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	case Float32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case Float64:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
+
+// putSample stores v, where full scale is [-1, 1), clipping integer encodings
+func putSample(b []byte, enc Encoding, v float64) {
+	switch enc {
+	case PCM8:
+		b[0] = byte(clip(v, 7) + 128)
+	case PCM16:
+		binary.LittleEndian.PutUint16(b, uint16(int16(clip(v, 15))))
+	case PCM24:
+		s := uint32(int32(clip(v, 23)))
+		b[0], b[1], b[2] = byte(s), byte(s>>8), byte(s>>16)
+	case PCM32:
+		binary.LittleEndian.PutUint32(b, uint32(int32(clip(v, 31))))
+	case Float32:
+		binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+	case Float64:
+		binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+	}
+}
+
+// clip scales v to a signed integer of bits+1 bits and clips it to range
+func clip(v float64, bits uint) int64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	scaled := math.Round(v * float64(int64(1)<<bits))
+	max := float64(int64(1)<<bits - 1)
+	if scaled > max {
+		return int64(max)
+	}
+	if scaled < -max-1 {
+		return int64(-max - 1)
+	}
+	return int64(scaled)
+}
+
+func floatToInt16(v float64) int16 {
+	return int16(clip(v, 15))
+}
+
+// Format tags used in the fmt chunk
+const (
+	formatPCM        = 1
+	formatFloat      = 3
+	formatExtensible = 0xFFFE
+)
+
+// subformatSuffix completes the sub-format GUID of an extensible header
+// after its leading format tag
+var subformatSuffix = []byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71}
+
+// Decode reads a WAV file. Chunks other than fmt, data and LIST/INFO are
+// skipped. A data chunk whose size is 0 or the maximum, as streaming writers
+// leave it, extends to the end of the input.
+func Decode(r io.Reader) (*File, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("failed to read WAV header: %w", err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV file")
+	}
+
+	f := &File{}
+	var haveFormat, haveData bool
+	for {
+		chunk := make([]byte, 8)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			// Running out of input between chunks ends the file
+			break
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		if id == "data" && (size == 0 || size == math.MaxUint32) {
+			body, err := io.ReadAll(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read WAV data: %w", err)
+			}
+			f.Data, haveData = body, true
+			break
+		}
+
+		switch id {
+		case "fmt ":
+			body, err := readChunk(r, id, size)
+			if err != nil {
+				return nil, err
+			}
+			format, err := parseFormat(body)
+			if err != nil {
+				return nil, err
+			}
+			f.Format, haveFormat = format, true
+		case "LIST":
+			body, err := readChunk(r, id, size)
+			if err != nil {
+				return nil, err
+			}
+			if info := parseInfo(body); info != nil {
+				f.Info = info
+			}
+		case "data":
+			// The buffer grows with the data actually there, whatever the
+			// header claims
+			var body bytes.Buffer
+			n, err := body.ReadFrom(io.LimitReader(r, int64(size)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read WAV data: %w", err)
+			}
+			if n == 0 {
+				return nil, fmt.Errorf("failed to read WAV data: %w", io.EOF)
+			}
+			// A truncated recording keeps what there is
+			f.Data, haveData = body.Bytes(), true
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size)); err != nil {
+				return nil, fmt.Errorf("failed to read WAV %q chunk: %w", id, err)
+			}
+		}
+
+		// Chunks are padded to an even length
+		if size%2 == 1 {
+			if _, err := io.ReadFull(r, make([]byte, 1)); err != nil {
+				break
+			}
+		}
+	}
+
+	if !haveFormat {
+		return nil, fmt.Errorf("WAV file has no format chunk")
+	}
+	if !haveData {
+		return nil, fmt.Errorf("WAV file has no data chunk")
+	}
+
+	// Drop a trailing partial frame
+	size := f.Format.frameSize()
+	f.Data = f.Data[:len(f.Data)/size*size]
+	return f, nil
+}
+
+// maxHeaderChunk bounds the fmt and LIST chunks read into memory; real ones
+// are a few hundred bytes at most
+const maxHeaderChunk = 1 << 20
+
+// readChunk reads a chunk body of a bounded size
+func readChunk(r io.Reader, id string, size uint32) ([]byte, error) {
+	if size > maxHeaderChunk {
+		return nil, fmt.Errorf("WAV %q chunk of %d bytes is too large", id, size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("failed to read WAV %q chunk: %w", id, err)
+	}
+	return body, nil
+}
+
+func parseFormat(body []byte) (Format, error) {
+	if len(body) < 16 {
+		return Format{}, fmt.Errorf("WAV format chunk is too short")
+	}
+
+	tag := binary.LittleEndian.Uint16(body[0:2])
+	format := Format{
+		Channels:   int(binary.LittleEndian.Uint16(body[2:4])),
+		SampleRate: int(binary.LittleEndian.Uint32(body[4:8])),
+	}
+	bits := int(binary.LittleEndian.Uint16(body[14:16]))
+
+	if tag == formatExtensible {
+		// The real format tag opens the sub-format GUID
+		if len(body) < 26 {
+			return Format{}, fmt.Errorf("WAV extensible format chunk is too short")
+		}
+		tag = binary.LittleEndian.Uint16(body[24:26])
+	}
+
+	switch {
+	case tag == formatPCM && bits == 8:
+		format.Encoding = PCM8
+	case tag == formatPCM && bits == 16:
+		format.Encoding = PCM16
+	case tag == formatPCM && bits == 24:
+		format.Encoding = PCM24
+	case tag == formatPCM && bits == 32:
+		format.Encoding = PCM32
+	case tag == formatFloat && bits == 32:
+		format.Encoding = Float32
+	case tag == formatFloat && bits == 64:
+		format.Encoding = Float64
+	default:
+		return Format{}, fmt.Errorf("unsupported WAV encoding: format %d with %d bits per sample", tag, bits)
+	}
+
+	if err := format.Validate(); err != nil {
+		return Format{}, fmt.Errorf("invalid WAV format: %w", err)
+	}
+	return format, nil
+}
+
+// parseInfo returns the tags of a LIST/INFO chunk body, or nil for other
+// kinds of list
+func parseInfo(body []byte) map[string]string {
+	if len(body) < 4 || string(body[0:4]) != "INFO" {
+		return nil
+	}
+
+	info := make(map[string]string)
+	for rest := body[4:]; len(rest) >= 8; {
+		id := string(rest[0:4])
+		size := int(binary.LittleEndian.Uint32(rest[4:8]))
+		rest = rest[8:]
+		if size > len(rest) {
+			break
+		}
+		info[id] = string(bytes.TrimRight(rest[:size], "\x00"))
+		// The last tag may leave out its pad byte
+		rest = rest[min(size+size%2, len(rest)):]
+	}
+	return info
+}
+
+// Encode writes the file. Integer PCM wider than 16 bits or with more than
+// two channels gets a WAVE_FORMAT_EXTENSIBLE header, float samples get the
+// fact chunk the format requires, and Info is written as a LIST/INFO chunk.
+func (f *File) Encode(w io.Writer) error {
+	if err := f.Format.Validate(); err != nil {
+		return err
+	}
+	if len(f.Data)%f.Format.frameSize() != 0 {
+		return fmt.Errorf("%d data bytes do not fill %d-byte frames", len(f.Data), f.Format.frameSize())
+	}
+
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	writeChunk(&body, "fmt ", f.formatChunk())
+	if f.Format.Encoding.IsFloat() {
+		fact := make([]byte, 4)
+		binary.LittleEndian.PutUint32(fact, uint32(f.Frames()))
+		writeChunk(&body, "fact", fact)
+	}
+	if len(f.Info) > 0 {
+		writeChunk(&body, "LIST", infoChunk(f.Info))
+	}
+
+	// The data chunk header is written here and its body streamed after
+	riffSize := body.Len() + 8 + len(f.Data) + len(f.Data)%2
+	header := make([]byte, 8)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(riffSize))
+	dataHeader := make([]byte, 8)
+	copy(dataHeader, "data")
+	binary.LittleEndian.PutUint32(dataHeader[4:], uint32(len(f.Data)))
+
+	for _, b := range [][]byte{header, body.Bytes(), dataHeader, f.Data} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	if len(f.Data)%2 == 1 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *File) formatChunk() []byte {
+	format := f.Format
+	bits := format.Encoding.BitsPerSample()
+	extensible := !format.Encoding.IsFloat() && (bits > 16 || format.Channels > 2)
+
+	tag := uint16(formatPCM)
+	if format.Encoding.IsFloat() {
+		tag = formatFloat
+	}
+
+	size := 16
+	switch {
+	case extensible:
+		size = 40
+	case format.Encoding.IsFloat():
+		size = 18 // with an empty extension
+	}
+
+	b := make([]byte, size)
+	binary.LittleEndian.PutUint16(b[0:], tag)
+	binary.LittleEndian.PutUint16(b[2:], uint16(format.Channels))
+	binary.LittleEndian.PutUint32(b[4:], uint32(format.SampleRate))
+	binary.LittleEndian.PutUint32(b[8:], uint32(format.SampleRate*format.frameSize()))
+	binary.LittleEndian.PutUint16(b[12:], uint16(format.frameSize()))
+	binary.LittleEndian.PutUint16(b[14:], uint16(bits))
+
+	if extensible {
+		binary.LittleEndian.PutUint16(b[0:], formatExtensible)
+		binary.LittleEndian.PutUint16(b[16:], 22) // extension size
+		binary.LittleEndian.PutUint16(b[18:], uint16(bits))
+		binary.LittleEndian.PutUint32(b[20:], channelMask(format.Channels))
+		binary.LittleEndian.PutUint16(b[24:], tag)
+		copy(b[26:], subformatSuffix)
+	}
+	return b
+}
+
+// channelMask returns the speaker layout for common channel counts and
+// leaves the rest unassigned
+func channelMask(channels int) uint32 {
+	switch channels {
+	case 1:
+		return 0x4 // front centre
+	case 2:
+		return 0x3 // front left and right
+	}
+	return 0
+}
+
+// infoChunk encodes tags as a LIST/INFO body in a stable order
+func infoChunk(info map[string]string) []byte {
+	ids := make([]string, 0, len(info))
+	for id := range info {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var b bytes.Buffer
+	b.WriteString("INFO")
+	for _, id := range ids {
+		// Values are NUL-terminated strings
+		writeChunk(&b, (id + "    ")[:4], append([]byte(info[id]), 0))
+	}
+	return b.Bytes()
+}
+
+// writeChunk appends a chunk with its header and padding byte
+func writeChunk(b *bytes.Buffer, id string, body []byte) {
+	header := make([]byte, 8)
+	copy(header, id)
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(body)))
+	b.Write(header)
+	b.Write(body)
+	if len(body)%2 == 1 {
+		b.WriteByte(0)
+	}
+}
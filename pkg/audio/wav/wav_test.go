@@ -0,0 +1,262 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRoundTrip_Int16(t *testing.T) {
+	samples := []int16{0, 1, -1, 12345, -12345, math.MaxInt16, math.MinInt16, 256}
+
+	tests := []struct {
+		name     string
+		channels int
+		encoding Encoding
+	}{
+		{name: "pcm16 mono", channels: 1, encoding: PCM16},
+		{name: "pcm16 stereo", channels: 2, encoding: PCM16},
+		{name: "pcm24 mono", channels: 1, encoding: PCM24},
+		{name: "pcm24 stereo", channels: 2, encoding: PCM24},
+		{name: "pcm32 stereo", channels: 2, encoding: PCM32},
+		{name: "float32 mono", channels: 1, encoding: Float32},
+		{name: "float32 stereo", channels: 2, encoding: Float32},
+		{name: "float64 mono", channels: 1, encoding: Float64},
+		{name: "pcm16 four channels", channels: 4, encoding: PCM16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewInt16(samples, 44100, tt.channels, tt.encoding)
+			if err != nil {
+				t.Fatalf("NewInt16() error = %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := f.Encode(&buf); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			got, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			want := Format{SampleRate: 44100, Channels: tt.channels, Encoding: tt.encoding}
+			if got.Format != want {
+				t.Errorf("Decode() format = %+v, want %+v", got.Format, want)
+			}
+			if got.Frames() != len(samples)/tt.channels {
+				t.Errorf("Frames() = %d, want %d", got.Frames(), len(samples)/tt.channels)
+			}
+			if !reflect.DeepEqual(got.Int16(), samples) {
+				t.Errorf("Int16() = %v, want %v", got.Int16(), samples)
+			}
+		})
+	}
+}
+
+func TestRoundTrip_Float32(t *testing.T) {
+	samples := []float32{0, 0.5, -0.5, 0.25, -1, 0.999}
+
+	tests := []struct {
+		encoding  Encoding
+		tolerance float64
+	}{
+		{encoding: PCM16, tolerance: 1.0 / (1 << 15)},
+		{encoding: PCM24, tolerance: 1.0 / (1 << 23)},
+		{encoding: Float32, tolerance: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding.String(), func(t *testing.T) {
+			f, err := NewFloat32(samples, 48000, 2, tt.encoding)
+			if err != nil {
+				t.Fatalf("NewFloat32() error = %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := f.Encode(&buf); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			got, err := Decode(&buf)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			for i, v := range got.Float32() {
+				if diff := math.Abs(float64(v - samples[i])); diff > tt.tolerance {
+					t.Errorf("Float32()[%d] = %v, want %v", i, v, samples[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRoundTrip_Info(t *testing.T) {
+	f, err := NewInt16([]int16{1, 2, 3}, 16000, 1, PCM16)
+	if err != nil {
+		t.Fatalf("NewInt16() error = %v", err)
+	}
+	f.Info = map[string]string{"INAM": "Dictation", "ICMT": "odd"}
+
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if riff := binary.LittleEndian.Uint32(buf.Bytes()[4:8]); int(riff) != buf.Len()-8 {
+		t.Errorf("RIFF size = %d, want %d", riff, buf.Len()-8)
+	}
+
+	got, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.Info, f.Info) {
+		t.Errorf("Decode() info = %v, want %v", got.Info, f.Info)
+	}
+	if !reflect.DeepEqual(got.Int16(), []int16{1, 2, 3}) {
+		t.Errorf("Int16() = %v, want [1 2 3]", got.Int16())
+	}
+}
+
+func TestEncode_Header(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels int
+		encoding Encoding
+		fmtSize  int
+		tag      uint16
+		hasFact  bool
+	}{
+		{name: "pcm16 mono", channels: 1, encoding: PCM16, fmtSize: 16, tag: formatPCM},
+		{name: "pcm24 mono", channels: 1, encoding: PCM24, fmtSize: 40, tag: formatExtensible},
+		{name: "pcm16 surround", channels: 6, encoding: PCM16, fmtSize: 40, tag: formatExtensible},
+		{name: "float32 stereo", channels: 2, encoding: Float32, fmtSize: 18, tag: formatFloat, hasFact: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewInt16(make([]int16, tt.channels*3), 16000, tt.channels, tt.encoding)
+			if err != nil {
+				t.Fatalf("NewInt16() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := f.Encode(&buf); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			b := buf.Bytes()
+
+			if size := int(binary.LittleEndian.Uint32(b[16:20])); size != tt.fmtSize {
+				t.Errorf("fmt size = %d, want %d", size, tt.fmtSize)
+			}
+			if tag := binary.LittleEndian.Uint16(b[20:22]); tag != tt.tag {
+				t.Errorf("format tag = %#x, want %#x", tag, tt.tag)
+			}
+			next := string(b[20+tt.fmtSize : 24+tt.fmtSize])
+			if hasFact := next == "fact"; hasFact != tt.hasFact {
+				t.Errorf("chunk after fmt = %q, want fact chunk %v", next, tt.hasFact)
+			}
+		})
+	}
+}
+
+func TestDecode_LayoutVariants(t *testing.T) {
+	f, err := NewInt16([]int16{10, -10, 20, -20}, 8000, 2, PCM16)
+	if err != nil {
+		t.Fatalf("NewInt16() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := f.Encode(&buf); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	encoded := buf.Bytes()
+
+	// A streaming writer leaves the data size at zero
+	streamed := append([]byte(nil), encoded...)
+	binary.LittleEndian.PutUint32(streamed[40:44], 0)
+
+	// Metadata may follow the samples
+	trailing := append([]byte(nil), encoded...)
+	trailing = append(trailing, []byte("LIST\x10\x00\x00\x00INFOINAM\x04\x00\x00\x00end\x00")...)
+
+	// The last INFO tag may have an odd size and no pad byte
+	unpadded := append([]byte(nil), encoded...)
+	unpadded = append(unpadded, []byte("LIST\x0d\x00\x00\x00INFOINAM\x01\x00\x00\x00x")...)
+
+	// A recording cut off mid-frame keeps its whole frames
+	truncated := encoded[:len(encoded)-3]
+
+	tests := []struct {
+		name string
+		file []byte
+		want []int16
+		info map[string]string
+	}{
+		{name: "streamed", file: streamed, want: []int16{10, -10, 20, -20}},
+		{name: "trailing info", file: trailing, want: []int16{10, -10, 20, -20}, info: map[string]string{"INAM": "end"}},
+		{name: "truncated", file: truncated, want: []int16{10, -10}},
+		{name: "unpadded info", file: unpadded, want: []int16{10, -10, 20, -20}, info: map[string]string{"INAM": "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(bytes.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Int16(), tt.want) {
+				t.Errorf("Int16() = %v, want %v", got.Int16(), tt.want)
+			}
+			if !reflect.DeepEqual(got.Info, tt.info) {
+				t.Errorf("Info = %v, want %v", got.Info, tt.info)
+			}
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+	}{
+		{name: "empty", file: nil},
+		{name: "not wave", file: []byte("RIFF\x04\x00\x00\x00AVI ")},
+		{name: "no chunks", file: []byte("RIFF\x04\x00\x00\x00WAVE")},
+		{name: "short fmt", file: []byte("RIFF\x10\x00\x00\x00WAVEfmt \x02\x00\x00\x00\x01\x00")},
+		{name: "huge fmt", file: []byte("RIFF\x10\x00\x00\x00WAVEfmt \xff\xff\xff\xff\x01\x00")},
+		{name: "truncated unknown chunk", file: []byte("RIFF\x10\x00\x00\x00WAVEjunk\xfe\xff\xff\xff\x01\x00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(bytes.NewReader(tt.file)); err == nil {
+				t.Error("Decode() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestNewInt16_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		samples  []int16
+		rate     int
+		channels int
+		encoding Encoding
+	}{
+		{name: "partial frame", samples: []int16{1, 2, 3}, rate: 16000, channels: 2, encoding: PCM16},
+		{name: "zero rate", samples: []int16{1}, rate: 0, channels: 1, encoding: PCM16},
+		{name: "zero channels", samples: []int16{1}, rate: 16000, channels: 0, encoding: PCM16},
+		{name: "unknown encoding", samples: []int16{1}, rate: 16000, channels: 1, encoding: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewInt16(tt.samples, tt.rate, tt.channels, tt.encoding); err == nil {
+				t.Error("NewInt16() error = nil, want error")
+			}
+		})
+	}
+}
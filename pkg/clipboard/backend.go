@@ -0,0 +1,242 @@
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Backend names the tool that reaches the clipboard
+type Backend string
+
+const (
+	// BackendAuto picks a backend from the session, see detectBackend
+	BackendAuto Backend = "auto"
+	// BackendWayland uses wl-copy and wl-paste from wl-clipboard
+	BackendWayland Backend = "wl-clipboard"
+	BackendXclip   Backend = "xclip"
+	BackendXsel    Backend = "xsel"
+	// BackendTmux uses the tmux paste buffer
+	BackendTmux Backend = "tmux"
+	// BackendOSC52 asks the terminal to set its clipboard, which reaches the
+	// local machine from an SSH session. It cannot read the clipboard.
+	BackendOSC52 Backend = "osc52"
+	// BackendSystem uses the platform clipboard API on macOS and Windows and
+	// whichever of xclip, xsel or wl-clipboard is found elsewhere
+	BackendSystem Backend = "system"
+)
+
+// ParseBackend validates a backend name
+func ParseBackend(s string) (Backend, error) {
+	switch b := Backend(strings.ToLower(s)); b {
+	case BackendAuto, BackendWayland, BackendXclip, BackendXsel, BackendTmux, BackendOSC52, BackendSystem:
+		return b, nil
+	}
+	return "", fmt.Errorf("unknown clipboard backend %q, want auto, wl-clipboard, xclip, xsel, tmux, osc52 or system", s)
+}
+
+// commandBackend reaches the clipboard through a copy and a paste command
+type commandBackend struct {
+	copyCmd  []string
+	pasteCmd []string
+	// run runs argv with input, if any, on stdin and returns its output
+	run func(input io.Reader, argv []string) ([]byte, error)
+}
+
+// Write passes text to the copy command
+func (c *commandBackend) Write(text string) error {
+	if _, err := c.run(strings.NewReader(text), c.copyCmd); err != nil {
+		return fmt.Errorf("failed to write to clipboard: %w", err)
+	}
+	return nil
+}
+
+// Read returns the output of the paste command
+func (c *commandBackend) Read() (string, error) {
+	out, err := c.run(nil, c.pasteCmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to read from clipboard: %w", err)
+	}
+	return string(out), nil
+}
+
+// watchingBackend is a commandBackend whose tool can serve a single paste
+// and exit, which tells that the content was pasted
+type watchingBackend struct {
+	*commandBackend
+	watchCmd []string
+	// start runs argv in the background with input on stdin and returns a
+	// channel closed when it exits
+	start func(input io.Reader, argv []string) (<-chan struct{}, error)
+}
+
+// WriteWatched copies text with the paste-once command
+func (w *watchingBackend) WriteWatched(text string) (<-chan struct{}, error) {
+	done, err := w.start(strings.NewReader(text), w.watchCmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write to clipboard: %w", err)
+	}
+	return done, nil
+}
+
+// commands are the copy and paste command lines of each command backend.
+// watch, where the tool has one, copies and exits after the first paste;
+// it stays in the foreground so its exit can be seen.
+var commands = map[Backend]struct{ copy, paste, watch []string }{
+	BackendWayland: {
+		copy:  []string{"wl-copy"},
+		paste: []string{"wl-paste", "--no-newline"},
+		watch: []string{"wl-copy", "--foreground", "--paste-once"},
+	},
+	BackendXclip: {
+		copy:  []string{"xclip", "-in", "-selection", "clipboard"},
+		paste: []string{"xclip", "-out", "-selection", "clipboard"},
+		watch: []string{"xclip", "-in", "-selection", "clipboard", "-quiet", "-loops", "1"},
+	},
+	BackendXsel: {copy: []string{"xsel", "--input", "--clipboard"}, paste: []string{"xsel", "--output", "--clipboard"}},
+	BackendTmux: {copy: []string{"tmux", "load-buffer", "-"}, paste: []string{"tmux", "save-buffer", "-"}},
+}
+
+// runCommand runs a clipboard tool. Copy tools such as xclip fork a child
+// that keeps serving the selection with the tool's stdout still open, so
+// output is only collected from paste commands.
+func runCommand(input io.Reader, argv []string) ([]byte, error) {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	if input != nil {
+		cmd.Stdin = input
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("%s: %w", argv[0], err)
+		}
+		return nil, nil
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(strings.TrimSpace(string(exitErr.Stderr))) > 0 {
+			return nil, fmt.Errorf("%s: %w: %s", argv[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("%s: %w", argv[0], err)
+	}
+	return out, nil
+}
+
+// startCommand starts a clipboard tool that keeps running until its content
+// is pasted or replaced
+func startCommand(input io.Reader, argv []string) (<-chan struct{}, error) {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = input
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%s: %w", argv[0], err)
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+	return done, nil
+}
+
+// environment is what backend selection looks at, replaced in tests
+type environment struct {
+	goos     string
+	getenv   func(string) string
+	lookPath func(string) (string, error)
+}
+
+var systemEnvironment = environment{goos: runtime.GOOS, getenv: os.Getenv, lookPath: exec.LookPath}
+
+// newBackend returns opts.Backend, detecting one for BackendAuto
+func newBackend(opts Options, env environment) (clipboardBackend, error) {
+	name := opts.Backend
+	if name == "" || name == BackendAuto {
+		var err error
+		if name, err = detectBackend(env); err != nil {
+			return nil, err
+		}
+	}
+
+	switch name {
+	case BackendSystem:
+		return systemClipboard{}, nil
+	case BackendOSC52:
+		return newOSC52(opts, env), nil
+	}
+	cmds, ok := commands[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown clipboard backend %q", name)
+	}
+	for _, tool := range toolsOf(name) {
+		if _, err := env.lookPath(tool); err != nil {
+			return nil, fmt.Errorf("clipboard backend %s is not installed: %w", name, err)
+		}
+	}
+	backend := &commandBackend{copyCmd: cmds.copy, pasteCmd: cmds.paste, run: runCommand}
+	if cmds.watch != nil {
+		return &watchingBackend{commandBackend: backend, watchCmd: cmds.watch, start: startCommand}, nil
+	}
+	return backend, nil
+}
+
+// toolsOf returns the programs a command backend runs
+func toolsOf(name Backend) []string {
+	cmds := commands[name]
+	if cmds.copy[0] == cmds.paste[0] {
+		return []string{cmds.copy[0]}
+	}
+	return []string{cmds.copy[0], cmds.paste[0]}
+}
+
+// detectBackend picks the backend for the session: the platform API on
+// macOS and Windows; elsewhere wl-clipboard under Wayland, xclip or xsel
+// under X11, OSC 52 over SSH and the tmux buffer inside tmux. The error
+// lists every backend that was considered and why it was passed over.
+func detectBackend(env environment) (Backend, error) {
+	if env.goos == "darwin" || env.goos == "windows" {
+		return BackendSystem, nil
+	}
+
+	var tried []string
+	installed := func(name Backend) bool {
+		for _, tool := range toolsOf(name) {
+			if _, err := env.lookPath(tool); err != nil {
+				tried = append(tried, fmt.Sprintf("%s (%s not installed)", name, tool))
+				return false
+			}
+		}
+		return true
+	}
+	requires := func(variable string, names ...Backend) bool {
+		if env.getenv(variable) != "" {
+			return true
+		}
+		for _, name := range names {
+			tried = append(tried, fmt.Sprintf("%s (%s not set)", name, variable))
+		}
+		return false
+	}
+
+	if requires("WAYLAND_DISPLAY", BackendWayland) && installed(BackendWayland) {
+		return BackendWayland, nil
+	}
+	if requires("DISPLAY", BackendXclip, BackendXsel) {
+		if installed(BackendXclip) {
+			return BackendXclip, nil
+		}
+		if installed(BackendXsel) {
+			return BackendXsel, nil
+		}
+	}
+	if env.getenv("SSH_TTY") != "" || env.getenv("SSH_CONNECTION") != "" {
+		return BackendOSC52, nil
+	}
+	tried = append(tried, fmt.Sprintf("%s (not an SSH session)", BackendOSC52))
+	if requires("TMUX", BackendTmux) && installed(BackendTmux) {
+		return BackendTmux, nil
+	}
+	return "", fmt.Errorf("no clipboard backend found; tried %s", strings.Join(tried, ", "))
+}
@@ -0,0 +1,188 @@
+package clipboard
+
+import (
+	"io"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeEnv is a Linux session with the given variables and tools
+func fakeEnv(vars map[string]string, tools ...string) environment {
+	return environment{
+		goos:   "linux",
+		getenv: func(name string) string { return vars[name] },
+		lookPath: func(name string) (string, error) {
+			for _, t := range tools {
+				if t == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", exec.ErrNotFound
+		},
+	}
+}
+
+func TestParseBackend(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Backend
+		wantErr bool
+	}{
+		{in: "auto", want: BackendAuto},
+		{in: "wl-clipboard", want: BackendWayland},
+		{in: "XCLIP", want: BackendXclip},
+		{in: "osc52", want: BackendOSC52},
+		{in: "pbcopy", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBackend(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseBackend(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestDetectBackend(t *testing.T) {
+	all := []string{"wl-copy", "wl-paste", "xclip", "xsel", "tmux"}
+	tests := []struct {
+		name  string
+		goos  string
+		vars  map[string]string
+		tools []string
+		want  Backend
+	}{
+		{name: "wayland", vars: map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, tools: all, want: BackendWayland},
+		{name: "xwayland without wl-clipboard", vars: map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, tools: []string{"xclip"}, want: BackendXclip},
+		{name: "x11", vars: map[string]string{"DISPLAY": ":0"}, tools: all, want: BackendXclip},
+		{name: "x11 with xsel only", vars: map[string]string{"DISPLAY": ":0"}, tools: []string{"xsel"}, want: BackendXsel},
+		{name: "ssh", vars: map[string]string{"SSH_TTY": "/dev/pts/3", "TMUX": "/tmp/tmux-1000/default"}, tools: all, want: BackendOSC52},
+		{name: "local tmux", vars: map[string]string{"TMUX": "/tmp/tmux-1000/default"}, tools: all, want: BackendTmux},
+		{name: "macos", goos: "darwin", want: BackendSystem},
+		{name: "windows", goos: "windows", want: BackendSystem},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := fakeEnv(tt.vars, tt.tools...)
+			if tt.goos != "" {
+				env.goos = tt.goos
+			}
+			got, err := detectBackend(env)
+			if err != nil || got != tt.want {
+				t.Errorf("detectBackend() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectBackend_NothingFound(t *testing.T) {
+	_, err := detectBackend(fakeEnv(map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, "wl-copy"))
+	if err == nil {
+		t.Fatal("detectBackend() error = nil, want the backends tried")
+	}
+	for _, want := range []string{
+		"wl-clipboard (wl-paste not installed)",
+		"xclip (DISPLAY not set)",
+		"xsel (DISPLAY not set)",
+		"osc52 (not an SSH session)",
+		"tmux (TMUX not set)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("detectBackend() error = %v, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	if _, err := newBackend(Options{Backend: BackendXsel}, fakeEnv(nil, "xclip")); err == nil || !strings.Contains(err.Error(), "xsel is not installed") {
+		t.Errorf("newBackend(xsel) error = %v, want not installed", err)
+	}
+	if _, err := newBackend(Options{Backend: BackendAuto}, fakeEnv(nil)); err == nil {
+		t.Error("newBackend(auto) in an empty session succeeded")
+	}
+
+	b, err := newBackend(Options{}, fakeEnv(map[string]string{"DISPLAY": ":0"}, "xsel"))
+	if err != nil {
+		t.Fatalf("newBackend() error = %v", err)
+	}
+	if cmd, ok := b.(*commandBackend); !ok || cmd.copyCmd[0] != "xsel" {
+		t.Errorf("newBackend() = %#v, want xsel", b)
+	}
+	if b, err := newBackend(Options{Backend: BackendOSC52}, fakeEnv(nil)); err != nil || b == nil {
+		t.Errorf("newBackend(osc52) = %v, %v", b, err)
+	}
+}
+
+func TestCommandBackend(t *testing.T) {
+	var calls [][]string
+	var input string
+	b := &commandBackend{
+		copyCmd:  commands[BackendWayland].copy,
+		pasteCmd: commands[BackendWayland].paste,
+		run: func(in io.Reader, argv []string) ([]byte, error) {
+			calls = append(calls, argv)
+			if in == nil {
+				return []byte("pasted"), nil
+			}
+			data, err := io.ReadAll(in)
+			input = string(data)
+			return nil, err
+		},
+	}
+
+	if err := b.Write("hello"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err := b.Read()
+	if err != nil || got != "pasted" {
+		t.Errorf("Read() = %q, %v, want pasted", got, err)
+	}
+	if input != "hello" {
+		t.Errorf("copy command got %q on stdin, want hello", input)
+	}
+	want := [][]string{{"wl-copy"}, {"wl-paste", "--no-newline"}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("ran %v, want %v", calls, want)
+	}
+}
+
+func TestWatchingBackend(t *testing.T) {
+	b, err := newBackend(Options{Backend: BackendWayland}, fakeEnv(nil, "wl-copy", "wl-paste"))
+	if err != nil {
+		t.Fatalf("newBackend() error = %v", err)
+	}
+	w, ok := b.(*watchingBackend)
+	if !ok {
+		t.Fatalf("newBackend(wl-clipboard) = %#v, want a paste watcher", b)
+	}
+
+	var argv []string
+	var input string
+	exited := make(chan struct{})
+	w.start = func(in io.Reader, args []string) (<-chan struct{}, error) {
+		data, err := io.ReadAll(in)
+		argv, input = args, string(data)
+		return exited, err
+	}
+
+	done, err := w.WriteWatched("hello")
+	if err != nil {
+		t.Fatalf("WriteWatched() error = %v", err)
+	}
+	if done != exited {
+		t.Error("WriteWatched() did not return the exit of the copy command")
+	}
+	if want := []string{"wl-copy", "--foreground", "--paste-once"}; !reflect.DeepEqual(argv, want) || input != "hello" {
+		t.Errorf("ran %v with %q, want %v with hello", argv, input, want)
+	}
+
+	if b, _ := newBackend(Options{Backend: BackendTmux}, fakeEnv(nil, "tmux")); b == nil {
+		t.Fatal("newBackend(tmux) failed")
+	} else if _, ok := b.(pasteWatcher); ok {
+		t.Error("newBackend(tmux) claims to watch pastes")
+	}
+}
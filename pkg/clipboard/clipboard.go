@@ -2,25 +2,113 @@ package clipboard
 
 import (
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/atotto/clipboard"
 )
 
 // Manager handles clipboard operations
 type Manager interface {
+	// Write puts a new transcript on the clipboard, combined with the current
+	// content as the manager's mode says
 	Write(text string) error
 	Read() (string, error)
+	// Update replaces the transcript from the last Write, such as a streaming
+	// transcript that grew, without combining it with itself
+	Update(text string) error
+	// History returns the most recent transcripts, newest first
+	History() []string
+	// Recall puts History()[i] back on the clipboard in place of its content
+	Recall(i int) error
+	// Restore puts back the content the last transcript replaced when
+	// RestoreAfter or RestoreOnPaste is set, unless something else was
+	// copied since. It runs by itself after the delay or the first paste;
+	// calling it earlier restores at once.
+	Restore() error
+	// Restored returns a channel closed once no restore is pending, so a
+	// program can wait for it before exiting
+	Restored() <-chan struct{}
 }
 
-type clipboardManager struct{}
+// Mode decides how a new transcript combines with the clipboard content
+type Mode string
 
-// NewManager creates a new clipboard manager
-func NewManager() Manager {
-	return &clipboardManager{}
+const (
+	Replace Mode = "replace"
+	Append  Mode = "append"
+	Prepend Mode = "prepend"
+)
+
+// ParseMode validates a mode name
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(s)); m {
+	case Replace, Append, Prepend:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown clipboard mode %q, want replace, append or prepend", s)
+}
+
+// DefaultHistorySize is the number of transcripts kept for recall
+const DefaultHistorySize = 10
+
+// Options configure a Manager
+type Options struct {
+	// Backend is the tool that reaches the clipboard; empty means BackendAuto
+	Backend Backend
+	Mode    Mode
+	// Separator goes between the existing content and an appended or
+	// prepended transcript
+	Separator string
+	// HistorySize is the number of transcripts kept for recall; 0 keeps none
+	HistorySize int
+	// RestoreAfter, when positive, is how long a transcript stays on the
+	// clipboard before the previous content is put back
+	RestoreAfter time.Duration
+	// RestoreOnPaste puts the previous content back once the transcript was
+	// pasted. Only wl-clipboard and xclip can tell; other backends rely on
+	// RestoreAfter.
+	RestoreOnPaste bool
+
+	// OSC52Passthrough and OSC52MaxBytes configure BackendOSC52; a
+	// transcript over OSC52MaxBytes fails to copy, and 0 sends any length
+	OSC52Passthrough Passthrough
+	OSC52MaxBytes    int
+}
+
+// DefaultOptions detect the backend, replace the clipboard content and keep
+// the last DefaultHistorySize transcripts
+func DefaultOptions() Options {
+	return Options{
+		Backend:          BackendAuto,
+		Mode:             Replace,
+		Separator:        "\n",
+		HistorySize:      DefaultHistorySize,
+		OSC52Passthrough: PassthroughAuto,
+		OSC52MaxBytes:    DefaultOSC52MaxBytes,
+	}
+}
+
+// clipboardBackend reads and writes the raw clipboard content
+type clipboardBackend interface {
+	Write(text string) error
+	Read() (string, error)
+}
+
+// pasteWatcher is a backend that can tell when its content was pasted
+type pasteWatcher interface {
+	// WriteWatched writes text and returns a channel closed once it was
+	// pasted or something else took its place. The clipboard is empty after
+	// a paste.
+	WriteWatched(text string) (<-chan struct{}, error)
 }
 
+// systemClipboard is the clipboard as github.com/atotto/clipboard reaches it
+type systemClipboard struct{}
+
 // Write writes text to the system clipboard
-func (c *clipboardManager) Write(text string) error {
+func (systemClipboard) Write(text string) error {
 	if err := clipboard.WriteAll(text); err != nil {
 		return fmt.Errorf("failed to write to clipboard: %w", err)
 	}
@@ -28,7 +116,7 @@ func (c *clipboardManager) Write(text string) error {
 }
 
 // Read reads text from the system clipboard
-func (c *clipboardManager) Read() (string, error) {
+func (systemClipboard) Read() (string, error) {
 	text, err := clipboard.ReadAll()
 	if err != nil {
 		return "", fmt.Errorf("failed to read from clipboard: %w", err)
@@ -36,10 +124,330 @@ func (c *clipboardManager) Read() (string, error) {
 	return text, nil
 }
 
+type clipboardManager struct {
+	backend clipboardBackend
+	opts    Options
+
+	mu sync.Mutex
+	// base is the content the last transcript was combined with
+	base    string
+	written bool
+	history *ring
+
+	// restore holds what to put back once RestoreAfter has passed
+	restore   *pendingRestore
+	afterFunc func(d time.Duration, f func()) stopper
+}
+
+// pendingRestore is the clipboard content to bring back and the content
+// that must still be there for it to happen
+type pendingRestore struct {
+	previous string
+	ours     string
+	timer    stopper
+	// done is closed once the restore ran or was given up
+	done chan struct{}
+	// scheduled counts the reschedules, so a timer stopped too late is ignored
+	scheduled int
+}
+
+// stopper cancels a scheduled call, as *time.Timer does
+type stopper interface {
+	Stop() bool
+}
+
+// NewManager creates a clipboard manager with the default options. When no
+// backend can be detected it uses BackendSystem; NewManagerWithOptions
+// reports that instead.
+func NewManager() Manager {
+	mgr, err := NewManagerWithOptions(DefaultOptions())
+	if err != nil {
+		return newManager(systemClipboard{}, DefaultOptions())
+	}
+	return mgr
+}
+
+// NewManagerWithOptions creates a clipboard manager using opts.Backend. It
+// fails when the backend is not installed or none can be detected.
+func NewManagerWithOptions(opts Options) (Manager, error) {
+	backend, err := newBackend(opts, systemEnvironment)
+	if err != nil {
+		return nil, err
+	}
+	return newManager(backend, opts), nil
+}
+
+func newManager(backend clipboardBackend, opts Options) *clipboardManager {
+	if opts.Mode == "" {
+		opts.Mode = Replace
+	}
+	return &clipboardManager{
+		backend: backend,
+		opts:    opts,
+		history: newRing(opts.HistorySize),
+		afterFunc: func(d time.Duration, f func()) stopper {
+			return time.AfterFunc(d, f)
+		},
+	}
+}
+
+// Write puts a transcript on the clipboard. In append and prepend mode an
+// empty or unreadable clipboard is simply replaced.
+func (c *clipboardManager) Write(text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.write(text)
+}
+
+func (c *clipboardManager) write(text string) error {
+	base := ""
+	if c.opts.Mode != Replace {
+		base, _ = c.backend.Read()
+	}
+	if err := c.overwrite(c.combine(base, text)); err != nil {
+		return err
+	}
+	c.base, c.written = base, true
+	c.history.push(text)
+	return nil
+}
+
+// overwrite replaces the clipboard content. With RestoreAfter or
+// RestoreOnPaste set, the content from before the first of a run of
+// overwrites is kept and the restore is scheduled again.
+func (c *clipboardManager) overwrite(content string) error {
+	watcher, watch := c.backend.(pasteWatcher)
+	watch = watch && c.opts.RestoreOnPaste
+	if c.opts.RestoreAfter <= 0 && !watch {
+		return c.backend.Write(content)
+	}
+
+	pending := c.restore
+	if pending == nil {
+		previous, err := c.backend.Read()
+		if err != nil {
+			// Content that cannot be read cannot be brought back
+			return c.backend.Write(content)
+		}
+		pending = &pendingRestore{previous: previous, done: make(chan struct{})}
+	}
+	var pasted <-chan struct{}
+	if watch {
+		var err error
+		if pasted, err = watcher.WriteWatched(content); err != nil {
+			return err
+		}
+	} else if err := c.backend.Write(content); err != nil {
+		return err
+	}
+
+	if pending.timer != nil {
+		pending.timer.Stop()
+		pending.timer = nil
+	}
+	pending.ours = content
+	pending.scheduled++
+	scheduled := pending.scheduled
+	// latest reports whether this overwrite is still the one to undo
+	latest := func() bool { return c.restore == pending && pending.scheduled == scheduled }
+	if c.opts.RestoreAfter > 0 {
+		pending.timer = c.afterFunc(c.opts.RestoreAfter, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if latest() {
+				c.restorePrevious()
+			}
+		})
+	}
+	if pasted != nil {
+		go func() {
+			<-pasted
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if latest() {
+				c.restoreAfterPaste()
+			}
+		}()
+	}
+	c.restore = pending
+	return nil
+}
+
+// Restore puts the previous content back now
+func (c *clipboardManager) Restore() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.restorePrevious()
+}
+
+func (c *clipboardManager) restorePrevious() error {
+	pending := c.restore
+	if pending == nil {
+		return nil
+	}
+	c.restore = nil
+	close(pending.done)
+	if pending.timer != nil {
+		pending.timer.Stop()
+	}
+
+	// Leave anything the user copied after the transcript alone
+	current, err := c.backend.Read()
+	if err != nil || current != pending.ours {
+		return nil
+	}
+	return c.putBack(pending)
+}
+
+// restoreAfterPaste puts the previous content back once the watcher saw the
+// transcript go. The paste tools give up the clipboard after one paste, so
+// an empty or unreadable clipboard means it was pasted; other content was
+// copied by the user and stays.
+func (c *clipboardManager) restoreAfterPaste() error {
+	pending := c.restore
+	c.restore = nil
+	close(pending.done)
+	if pending.timer != nil {
+		pending.timer.Stop()
+	}
+
+	current, err := c.backend.Read()
+	if err == nil && current != "" && current != pending.ours {
+		return nil
+	}
+	return c.putBack(pending)
+}
+
+// Restored returns the channel of the pending restore, or a closed one
+func (c *clipboardManager) Restored() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.restore != nil {
+		return c.restore.done
+	}
+	return closed
+}
+
+// closed is returned by Restored when nothing is pending
+var closed = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// putBack writes the content from before the transcript
+func (c *clipboardManager) putBack(pending *pendingRestore) error {
+	if err := c.backend.Write(pending.previous); err != nil {
+		return err
+	}
+	// The transcript is gone, so an Update must start over
+	c.written = false
+	return nil
+}
+
+// Update rewrites the last transcript, or writes a new one if there is none
+func (c *clipboardManager) Update(text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.written {
+		return c.write(text)
+	}
+	if err := c.overwrite(c.combine(c.base, text)); err != nil {
+		return err
+	}
+	c.history.replaceNewest(text)
+	return nil
+}
+
+// Read reads text from the clipboard
+func (c *clipboardManager) Read() (string, error) {
+	return c.backend.Read()
+}
+
+// History returns the most recent transcripts, newest first
+func (c *clipboardManager) History() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.history.list()
+}
+
+// Recall replaces the clipboard content with an earlier transcript
+func (c *clipboardManager) Recall(i int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := c.history.list()
+	if i < 0 || i >= len(entries) {
+		return fmt.Errorf("no transcript %d in clipboard history of %d", i+1, len(entries))
+	}
+	if err := c.overwrite(entries[i]); err != nil {
+		return err
+	}
+	// A following Update must not bring back what was recalled over
+	c.written = false
+	return nil
+}
+
+// combine joins a transcript with the content it is added to
+func (c *clipboardManager) combine(base, text string) string {
+	switch {
+	case base == "":
+		return text
+	case c.opts.Mode == Append:
+		return base + c.opts.Separator + text
+	case c.opts.Mode == Prepend:
+		return text + c.opts.Separator + base
+	}
+	return text
+}
+
+// ring keeps the last few strings pushed to it
+type ring struct {
+	items []string
+	next  int
+	full  bool
+}
+
+func newRing(size int) *ring {
+	return &ring{items: make([]string, max(size, 0))}
+}
+
+func (r *ring) push(s string) {
+	if len(r.items) == 0 {
+		return
+	}
+	r.items[r.next] = s
+	r.next = (r.next + 1) % len(r.items)
+	r.full = r.full || r.next == 0
+}
+
+func (r *ring) replaceNewest(s string) {
+	if len(r.items) == 0 || (!r.full && r.next == 0) {
+		r.push(s)
+		return
+	}
+	r.items[(r.next-1+len(r.items))%len(r.items)] = s
+}
+
+// list returns the strings newest first
+func (r *ring) list() []string {
+	n := r.next
+	if r.full {
+		n = len(r.items)
+	}
+	out := make([]string, n)
+	for i := range out {
+		out[i] = r.items[(r.next-1-i+2*len(r.items))%len(r.items)]
+	}
+	return out
+}
+
 // MockManager is a mock implementation for testing
 type MockManager struct {
 	content string
 	err     error
+	history []string
 }
 
 // NewMockManager creates a mock clipboard manager
@@ -56,6 +464,7 @@ func (m *MockManager) Write(text string) error {
 		return m.err
 	}
 	m.content = text
+	m.history = append([]string{text}, m.history...)
 	return nil
 }
 
@@ -67,6 +476,46 @@ func (m *MockManager) Read() (string, error) {
 	return m.content, nil
 }
 
+// Update replaces the last text written to the mock clipboard
+func (m *MockManager) Update(text string) error {
+	if m.err != nil {
+		return m.err
+	}
+	if len(m.history) == 0 {
+		return m.Write(text)
+	}
+	m.content = text
+	m.history[0] = text
+	return nil
+}
+
+// History returns every text written, newest first
+func (m *MockManager) History() []string {
+	return m.history
+}
+
+// Recall puts an earlier text back in the mock clipboard
+func (m *MockManager) Recall(i int) error {
+	if m.err != nil {
+		return m.err
+	}
+	if i < 0 || i >= len(m.history) {
+		return fmt.Errorf("no transcript %d in clipboard history of %d", i+1, len(m.history))
+	}
+	m.content = m.history[i]
+	return nil
+}
+
+// Restore does nothing; the mock keeps no earlier content
+func (m *MockManager) Restore() error {
+	return m.err
+}
+
+// Restored returns a closed channel; the mock never restores later
+func (m *MockManager) Restored() <-chan struct{} {
+	return closed
+}
+
 // SetError sets an error to be returned by operations
 func (m *MockManager) SetError(err error) {
 	m.err = err
@@ -2,7 +2,10 @@ package clipboard
 
 import (
 	"fmt"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestMockManager_Write(t *testing.T) {
@@ -94,3 +97,463 @@ func TestMockManager_Error(t *testing.T) {
 		t.Error("Read() expected error, got nil")
 	}
 }
+
+func TestManager_Modes(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		existing string
+		want     string
+	}{
+		{name: "replace", opts: Options{Mode: Replace}, existing: "old", want: "new"},
+		{name: "append", opts: Options{Mode: Append, Separator: "\n"}, existing: "old", want: "old\nnew"},
+		{name: "prepend", opts: Options{Mode: Prepend, Separator: " | "}, existing: "old", want: "new | old"},
+		{name: "append to empty clipboard", opts: Options{Mode: Append, Separator: "\n"}, existing: "", want: "new"},
+		{name: "zero mode replaces", opts: Options{}, existing: "old", want: "new"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := NewMockManager()
+			_ = backend.Write(tt.existing)
+
+			mgr := newManager(backend, tt.opts)
+			if err := mgr.Write("new"); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got := backend.GetContent(); got != tt.want {
+				t.Errorf("clipboard = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_Update(t *testing.T) {
+	backend := NewMockManager()
+	_ = backend.Write("notes")
+	mgr := newManager(backend, Options{Mode: Append, Separator: "\n", HistorySize: 5})
+
+	// A streaming transcript grows segment by segment
+	for _, text := range []string{"one", "one two", "one two three"} {
+		if err := mgr.Update(text); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	if got, want := backend.GetContent(), "notes\none two three"; got != want {
+		t.Errorf("clipboard = %q, want %q", got, want)
+	}
+
+	if err := mgr.Write("four"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got, want := backend.GetContent(), "notes\none two three\nfour"; got != want {
+		t.Errorf("clipboard = %q, want %q", got, want)
+	}
+	if got, want := mgr.History(), []string{"four", "one two three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("History() = %q, want %q", got, want)
+	}
+}
+
+func TestManager_UnreadableClipboard(t *testing.T) {
+	backend := &unreadable{MockManager: NewMockManager()}
+	mgr := newManager(backend, Options{Mode: Append, Separator: "\n"})
+
+	if err := mgr.Write("new"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got := backend.GetContent(); got != "new" {
+		t.Errorf("clipboard = %q, want %q", got, "new")
+	}
+}
+
+// unreadable is a clipboard whose content cannot be read, as with xclip
+// when nothing has been copied yet
+type unreadable struct {
+	*MockManager
+}
+
+func (u *unreadable) Read() (string, error) {
+	return "", fmt.Errorf("target STRING not available")
+}
+
+func TestManager_HistoryRing(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		writes []string
+		want   []string
+	}{
+		{name: "empty", size: 3, want: []string{}},
+		{name: "partly filled", size: 3, writes: []string{"a", "b"}, want: []string{"b", "a"}},
+		{name: "full", size: 3, writes: []string{"a", "b", "c"}, want: []string{"c", "b", "a"}},
+		{name: "wrapped", size: 3, writes: []string{"a", "b", "c", "d", "e"}, want: []string{"e", "d", "c"}},
+		{name: "disabled", size: 0, writes: []string{"a"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := newManager(NewMockManager(), Options{HistorySize: tt.size})
+			for _, w := range tt.writes {
+				if err := mgr.Write(w); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if got := mgr.History(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("History() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManager_Recall(t *testing.T) {
+	backend := NewMockManager()
+	mgr := newManager(backend, Options{Mode: Append, Separator: " ", HistorySize: 3})
+	for _, w := range []string{"first", "second"} {
+		if err := mgr.Write(w); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	if err := mgr.Recall(1); err != nil {
+		t.Fatalf("Recall() error = %v", err)
+	}
+	if got := backend.GetContent(); got != "first" {
+		t.Errorf("clipboard = %q, want %q", got, "first")
+	}
+	if err := mgr.Recall(2); err == nil {
+		t.Error("Recall() beyond the history error = nil, want error")
+	}
+
+	// The next transcript is added to the recalled one
+	if err := mgr.Update("third"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := backend.GetContent(); got != "first third" {
+		t.Errorf("clipboard = %q, want %q", got, "first third")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Mode
+		wantErr bool
+	}{
+		{in: "replace", want: Replace},
+		{in: "Append", want: Append},
+		{in: "prepend", want: Prepend},
+		{in: "insert", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMode(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMode(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMode(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// fakeTimer records a scheduled call so tests can run it when they choose
+type fakeTimer struct {
+	f       func()
+	d       time.Duration
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.stopped = true
+	return true
+}
+
+// withFakeTimers makes mgr schedule restores on timers the test fires
+func withFakeTimers(mgr *clipboardManager) *[]*fakeTimer {
+	var timers []*fakeTimer
+	mgr.afterFunc = func(d time.Duration, f func()) stopper {
+		t := &fakeTimer{f: f, d: d}
+		timers = append(timers, t)
+		return t
+	}
+	return &timers
+}
+
+func TestManager_RestoreAfterDelay(t *testing.T) {
+	backend := NewMockManager()
+	_ = backend.Write("copied earlier")
+	mgr := newManager(backend, Options{RestoreAfter: 5 * time.Second})
+	timers := withFakeTimers(mgr)
+
+	if err := mgr.Write("transcript"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if got := backend.GetContent(); got != "transcript" {
+		t.Errorf("clipboard = %q, want %q", got, "transcript")
+	}
+	if len(*timers) != 1 || (*timers)[0].d != 5*time.Second {
+		t.Fatalf("scheduled %d restores, want one after 5s", len(*timers))
+	}
+
+	(*timers)[0].f()
+	if got := backend.GetContent(); got != "copied earlier" {
+		t.Errorf("clipboard after delay = %q, want %q", got, "copied earlier")
+	}
+
+	// Firing again, or restoring by hand, changes nothing more
+	_ = backend.Write("later copy")
+	(*timers)[0].f()
+	if err := mgr.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := backend.GetContent(); got != "later copy" {
+		t.Errorf("clipboard = %q, want %q", got, "later copy")
+	}
+}
+
+func TestManager_RestoreKeepsNewerCopies(t *testing.T) {
+	backend := NewMockManager()
+	_ = backend.Write("copied earlier")
+	mgr := newManager(backend, Options{RestoreAfter: time.Second})
+	timers := withFakeTimers(mgr)
+
+	if err := mgr.Write("transcript"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	_ = backend.Write("copied after the transcript")
+
+	(*timers)[0].f()
+	if got := backend.GetContent(); got != "copied after the transcript" {
+		t.Errorf("clipboard = %q, want the newer copy kept", got)
+	}
+}
+
+func TestManager_RestoreBeforeDelay(t *testing.T) {
+	backend := NewMockManager()
+	_ = backend.Write("copied earlier")
+	mgr := newManager(backend, Options{RestoreAfter: time.Minute})
+	timers := withFakeTimers(mgr)
+
+	if err := mgr.Write("transcript"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := mgr.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := backend.GetContent(); got != "copied earlier" {
+		t.Errorf("clipboard = %q, want %q", got, "copied earlier")
+	}
+	if !(*timers)[0].stopped {
+		t.Error("Restore() left the timer running")
+	}
+}
+
+func TestManager_RestoreStreaming(t *testing.T) {
+	backend := NewMockManager()
+	_ = backend.Write("copied earlier")
+	mgr := newManager(backend, Options{RestoreAfter: time.Second})
+	timers := withFakeTimers(mgr)
+
+	// Every segment starts the delay again, but the snapshot is the
+	// content from before the first one
+	for _, text := range []string{"one", "one two"} {
+		if err := mgr.Update(text); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	if len(*timers) != 2 || !(*timers)[0].stopped || (*timers)[1].stopped {
+		t.Fatalf("timers = %+v, want the first stopped and the second running", *timers)
+	}
+
+	(*timers)[0].f()
+	if got := backend.GetContent(); got != "one two" {
+		t.Errorf("clipboard after a stale timer = %q, want %q", got, "one two")
+	}
+	(*timers)[1].f()
+	if got := backend.GetContent(); got != "copied earlier" {
+		t.Errorf("clipboard = %q, want %q", got, "copied earlier")
+	}
+}
+
+func TestManager_Restored(t *testing.T) {
+	backend := NewMockManager()
+	_ = backend.Write("copied earlier")
+	mgr := newManager(backend, Options{RestoreAfter: time.Second})
+	timers := withFakeTimers(mgr)
+
+	isClosed := func(ch <-chan struct{}) bool {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	}
+	if !isClosed(mgr.Restored()) {
+		t.Error("Restored() is open with nothing to restore")
+	}
+	if err := mgr.Write("transcript"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	restored := mgr.Restored()
+	if isClosed(restored) {
+		t.Error("Restored() is closed while a restore is pending")
+	}
+	(*timers)[0].f()
+	if !isClosed(restored) {
+		t.Error("Restored() is still open after the restore")
+	}
+}
+
+func TestManager_NoRestoreByDefault(t *testing.T) {
+	backend := NewMockManager()
+	_ = backend.Write("copied earlier")
+	mgr := newManager(backend, DefaultOptions())
+	timers := withFakeTimers(mgr)
+
+	if err := mgr.Write("transcript"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := mgr.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if got := backend.GetContent(); got != "transcript" || len(*timers) != 0 {
+		t.Errorf("clipboard = %q with %d timers, want the transcript kept", got, len(*timers))
+	}
+}
+
+// pasteOnceBackend serves its content until the test pastes it, the way
+// wl-copy --paste-once does
+type pasteOnceBackend struct {
+	mu      sync.Mutex
+	content string
+	pasted  chan struct{}
+}
+
+// Write replaces the content, which ends a watched write
+func (b *pasteOnceBackend) Write(text string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.release()
+	b.content = text
+	return nil
+}
+
+func (b *pasteOnceBackend) Read() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.content, nil
+}
+
+func (b *pasteOnceBackend) WriteWatched(text string) (<-chan struct{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.release()
+	b.content = text
+	b.pasted = make(chan struct{})
+	return b.pasted, nil
+}
+
+// paste hands out the content and empties the clipboard
+func (b *pasteOnceBackend) paste() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	text := b.content
+	b.content = ""
+	b.release()
+	return text
+}
+
+func (b *pasteOnceBackend) release() {
+	if b.pasted != nil {
+		close(b.pasted)
+		b.pasted = nil
+	}
+}
+
+// waitFor fails the test unless the content becomes want within a second
+func (b *pasteOnceBackend) waitFor(t *testing.T, want string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		got, _ := b.Read()
+		if got == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("clipboard = %q, want %q", got, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManager_RestoreOnPaste(t *testing.T) {
+	backend := &pasteOnceBackend{content: "copied earlier"}
+	mgr := newManager(backend, Options{RestoreOnPaste: true})
+
+	// Every segment of a streaming transcript replaces the one before
+	for _, text := range []string{"one", "one two"} {
+		if err := mgr.Update(text); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	if got := backend.paste(); got != "one two" {
+		t.Errorf("pasted %q, want %q", got, "one two")
+	}
+	backend.waitFor(t, "copied earlier")
+}
+
+func TestManager_RestoreOnPasteKeepsNewerCopies(t *testing.T) {
+	backend := &pasteOnceBackend{content: "copied earlier"}
+	mgr := newManager(backend, Options{RestoreOnPaste: true})
+
+	if err := mgr.Write("transcript"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	// Copying something else ends the watch as well, but is not a paste
+	_ = backend.Write("copied later")
+	deadline := time.Now().Add(time.Second)
+	for {
+		mgr.mu.Lock()
+		pending := mgr.restore != nil
+		mgr.mu.Unlock()
+		if !pending {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the end of the watch was not noticed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got, _ := backend.Read(); got != "copied later" {
+		t.Errorf("clipboard = %q, want %q", got, "copied later")
+	}
+}
+
+func TestManager_RestoreOnPasteWithDelay(t *testing.T) {
+	backend := &pasteOnceBackend{content: "copied earlier"}
+	mgr := newManager(backend, Options{RestoreOnPaste: true, RestoreAfter: time.Minute})
+	timers := withFakeTimers(mgr)
+
+	if err := mgr.Write("transcript"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	backend.paste()
+	backend.waitFor(t, "copied earlier")
+
+	mgr.mu.Lock()
+	stopped := (*timers)[0].stopped
+	mgr.mu.Unlock()
+	if !stopped {
+		t.Error("a paste left the restore timer running")
+	}
+}
+
+func TestNewManager(t *testing.T) {
+	// Whatever the session offers, NewManager returns a usable manager
+	if mgr := NewManager(); mgr == nil {
+		t.Fatal("NewManager() = nil")
+	}
+}
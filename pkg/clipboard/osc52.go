@@ -0,0 +1,130 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultOSC52MaxBytes is the largest transcript sent with OSC 52 by default.
+// It encodes to 100000 bytes of base64, the most that hterm and several other
+// terminals accept; longer sequences are dropped without a word.
+const DefaultOSC52MaxBytes = 74994
+
+// Passthrough is how OSC 52 sequences get through a terminal multiplexer to
+// the terminal outside it
+type Passthrough string
+
+const (
+	// PassthroughAuto wraps for tmux or screen when running inside one
+	PassthroughAuto Passthrough = "auto"
+	// PassthroughNone sends sequences as they are, for terminals and
+	// multiplexers that handle OSC 52 themselves
+	PassthroughNone Passthrough = "none"
+	// PassthroughTmux wraps sequences in a tmux DCS passthrough; tmux 3.3
+	// and later also need "set -g allow-passthrough on"
+	PassthroughTmux Passthrough = "tmux"
+	// PassthroughScreen wraps sequences in screen DCS strings
+	PassthroughScreen Passthrough = "screen"
+)
+
+// ParsePassthrough validates a passthrough name
+func ParsePassthrough(s string) (Passthrough, error) {
+	switch p := Passthrough(strings.ToLower(s)); p {
+	case PassthroughAuto, PassthroughNone, PassthroughTmux, PassthroughScreen:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown OSC 52 passthrough %q, want auto, none, tmux or screen", s)
+}
+
+// screenChunk is the longest piece of a sequence screen passes in one DCS
+// string; screen drops longer strings
+const screenChunk = 76
+
+// osc52Clipboard sets the clipboard of the terminal the program runs in with
+// an OSC 52 escape sequence. Over SSH that is the clipboard of the local
+// machine, where the terminal runs.
+type osc52Clipboard struct {
+	passthrough Passthrough
+	// maxBytes limits the text sent; 0 sends any length
+	maxBytes int
+	// openTerminal opens the controlling terminal for writing
+	openTerminal func() (io.WriteCloser, error)
+}
+
+// newOSC52 returns an OSC 52 backend, resolving PassthroughAuto from the
+// multiplexer the session runs in
+func newOSC52(opts Options, env environment) *osc52Clipboard {
+	passthrough := opts.OSC52Passthrough
+	if passthrough == "" || passthrough == PassthroughAuto {
+		passthrough = detectPassthrough(env)
+	}
+	return &osc52Clipboard{
+		passthrough: passthrough,
+		maxBytes:    opts.OSC52MaxBytes,
+		openTerminal: func() (io.WriteCloser, error) {
+			return os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+		},
+	}
+}
+
+// detectPassthrough recognizes tmux by $TMUX and screen by $STY, which
+// both set for the programs inside them
+func detectPassthrough(env environment) Passthrough {
+	switch {
+	case env.getenv("TMUX") != "":
+		return PassthroughTmux
+	case env.getenv("STY") != "":
+		return PassthroughScreen
+	}
+	return PassthroughNone
+}
+
+// Write sends text to the terminal's clipboard. Text over the size limit is
+// refused rather than cut, since a terminal may silently drop it.
+func (o *osc52Clipboard) Write(text string) error {
+	if o.maxBytes > 0 && len(text) > o.maxBytes {
+		return fmt.Errorf("failed to write to clipboard: %d bytes is more than the OSC 52 limit of %d", len(text), o.maxBytes)
+	}
+
+	tty, err := o.openTerminal()
+	if err != nil {
+		return fmt.Errorf("failed to open terminal for OSC 52: %w", err)
+	}
+	defer tty.Close()
+
+	if _, err := io.WriteString(tty, o.sequence(text)); err != nil {
+		return fmt.Errorf("failed to write to clipboard: %w", err)
+	}
+	return nil
+}
+
+// sequence builds the escape sequence setting the clipboard to text, wrapped
+// for the multiplexer in between
+func (o *osc52Clipboard) sequence(text string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+
+	switch o.passthrough {
+	case PassthroughTmux:
+		// tmux wants every ESC inside the passthrough doubled
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	case PassthroughScreen:
+		var b strings.Builder
+		for len(seq) > 0 {
+			n := min(screenChunk, len(seq))
+			b.WriteString("\x1bP" + seq[:n] + "\x1b\\")
+			seq = seq[n:]
+		}
+		return b.String()
+	}
+	return seq
+}
+
+// Read fails: few terminals answer clipboard queries, and those that do ask
+// the user first
+func (o *osc52Clipboard) Read() (string, error) {
+	return "", errors.New("the osc52 clipboard backend cannot read the clipboard")
+}
@@ -0,0 +1,112 @@
+package clipboard
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// nopCloser is a terminal that records what is written to it
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+// newTestOSC52 returns an OSC 52 backend writing to tty
+func newTestOSC52(tty *bytes.Buffer, passthrough Passthrough, maxBytes int) *osc52Clipboard {
+	o := newOSC52(Options{OSC52Passthrough: passthrough, OSC52MaxBytes: maxBytes}, fakeEnv(nil))
+	o.openTerminal = func() (io.WriteCloser, error) { return nopCloser{tty}, nil }
+	return o
+}
+
+func TestOSC52(t *testing.T) {
+	tests := []struct {
+		passthrough Passthrough
+		want        string
+	}{
+		{passthrough: PassthroughNone, want: "\x1b]52;c;aGVsbG8=\a"},
+		{passthrough: PassthroughTmux, want: "\x1bPtmux;\x1b\x1b]52;c;aGVsbG8=\a\x1b\\"},
+		{passthrough: PassthroughScreen, want: "\x1bP\x1b]52;c;aGVsbG8=\a\x1b\\"},
+	}
+
+	for _, tt := range tests {
+		var tty bytes.Buffer
+		if err := newTestOSC52(&tty, tt.passthrough, 0).Write("hello"); err != nil {
+			t.Fatalf("%s: Write() error = %v", tt.passthrough, err)
+		}
+		if tty.String() != tt.want {
+			t.Errorf("%s: wrote %q, want %q", tt.passthrough, tty.String(), tt.want)
+		}
+	}
+
+	if _, err := newTestOSC52(&bytes.Buffer{}, PassthroughNone, 0).Read(); err == nil {
+		t.Error("Read() error = nil, want unsupported")
+	}
+}
+
+func TestOSC52_ScreenChunks(t *testing.T) {
+	var tty bytes.Buffer
+	text := strings.Repeat("dictation ", 30)
+	if err := newTestOSC52(&tty, PassthroughScreen, 0).Write(text); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	chunks := strings.Split(strings.TrimSuffix(tty.String(), "\x1b\\"), "\x1b\\")
+	var seq strings.Builder
+	for _, c := range chunks {
+		if !strings.HasPrefix(c, "\x1bP") || len(c) > len("\x1bP")+screenChunk {
+			t.Fatalf("chunk %q is not a DCS string of at most %d bytes", c, screenChunk)
+		}
+		seq.WriteString(strings.TrimPrefix(c, "\x1bP"))
+	}
+	if want := newTestOSC52(&bytes.Buffer{}, PassthroughNone, 0).sequence(text); seq.String() != want {
+		t.Errorf("chunks join to %q, want %q", seq.String(), want)
+	}
+}
+
+func TestOSC52_MaxBytes(t *testing.T) {
+	var tty bytes.Buffer
+	o := newTestOSC52(&tty, PassthroughNone, 8)
+
+	if err := o.Write("12345678"); err != nil {
+		t.Errorf("Write() at the limit error = %v", err)
+	}
+	tty.Reset()
+	if err := o.Write("123456789"); err == nil || !strings.Contains(err.Error(), "limit of 8") {
+		t.Errorf("Write() over the limit error = %v, want the limit", err)
+	}
+	if tty.Len() != 0 {
+		t.Errorf("Write() over the limit wrote %q", tty.String())
+	}
+}
+
+func TestDetectPassthrough(t *testing.T) {
+	tests := []struct {
+		vars map[string]string
+		want Passthrough
+	}{
+		{vars: map[string]string{"TMUX": "/tmp/tmux-1000/default,123,0"}, want: PassthroughTmux},
+		{vars: map[string]string{"STY": "4242.pts-0.devbox"}, want: PassthroughScreen},
+		{vars: nil, want: PassthroughNone},
+	}
+
+	for _, tt := range tests {
+		if got := newOSC52(Options{}, fakeEnv(tt.vars)).passthrough; got != tt.want {
+			t.Errorf("passthrough with %v = %v, want %v", tt.vars, got, tt.want)
+		}
+	}
+	if got := newOSC52(Options{OSC52Passthrough: PassthroughNone}, fakeEnv(map[string]string{"TMUX": "x"})).passthrough; got != PassthroughNone {
+		t.Errorf("explicit none passthrough = %v, want none", got)
+	}
+}
+
+func TestParsePassthrough(t *testing.T) {
+	for _, in := range []string{"auto", "none", "TMUX", "screen"} {
+		if _, err := ParsePassthrough(in); err != nil {
+			t.Errorf("ParsePassthrough(%q) error = %v", in, err)
+		}
+	}
+	if _, err := ParsePassthrough("zellij"); err == nil {
+		t.Error("ParsePassthrough(zellij) error = nil")
+	}
+}
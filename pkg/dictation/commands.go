@@ -0,0 +1,197 @@
+package dictation
+
+import (
+	"fmt"
+	"strings"
+
+	"speech-to-clipboard/pkg/postprocess"
+)
+
+// punct is a punctuation mark that ends a word
+func punct(phrase, text string) Command {
+	return Command{Phrase: phrase, Text: text, Attach: AttachLeft}
+}
+
+// stop is a punctuation mark that ends a sentence
+func stop(phrase, text string) Command {
+	return Command{Phrase: phrase, Text: text, Attach: AttachLeft, Capitalize: true}
+}
+
+// open is an opening quote or bracket
+func open(phrase, text string) Command {
+	return Command{Phrase: phrase, Text: text, Attach: AttachRight}
+}
+
+// lineBreak starts a new line or paragraph with a capital letter
+func lineBreak(phrase, text string) Command {
+	return Command{Phrase: phrase, Text: text, Attach: AttachBoth, Capitalize: true}
+}
+
+var english = Table{
+	stop("period", "."),
+	stop("full stop", "."),
+	stop("question mark", "?"),
+	stop("exclamation mark", "!"),
+	stop("exclamation point", "!"),
+	punct("comma", ","),
+	punct("colon", ":"),
+	punct("semicolon", ";"),
+	punct("semi colon", ";"),
+	punct("ellipsis", "..."),
+	lineBreak("new line", "\n"),
+	lineBreak("new paragraph", "\n\n"),
+	open("open quote", `"`),
+	punct("close quote", `"`),
+	punct("end quote", `"`),
+	open("open paren", "("),
+	open("open parenthesis", "("),
+	open("open bracket", "("),
+	punct("close paren", ")"),
+	punct("close parenthesis", ")"),
+	punct("close bracket", ")"),
+	{Phrase: "hyphen", Text: "-", Attach: AttachBoth},
+	{Phrase: "dash", Text: "-"},
+	{Phrase: "all caps", Action: CapsOn},
+	{Phrase: "end caps", Action: CapsOff},
+	{Phrase: "literal", Action: Literal},
+}
+
+var german = Table{
+	stop("punkt", "."),
+	stop("fragezeichen", "?"),
+	stop("ausrufezeichen", "!"),
+	punct("komma", ","),
+	punct("doppelpunkt", ":"),
+	punct("semikolon", ";"),
+	punct("auslassungspunkte", "..."),
+	lineBreak("neue zeile", "\n"),
+	lineBreak("neuer absatz", "\n\n"),
+	open("anführungszeichen auf", "„"),
+	punct("anführungszeichen zu", "“"),
+	open("klammer auf", "("),
+	punct("klammer zu", ")"),
+	{Phrase: "bindestrich", Text: "-", Attach: AttachBoth},
+	{Phrase: "gedankenstrich", Text: "–"},
+	{Phrase: "großbuchstaben an", Action: CapsOn},
+	{Phrase: "großbuchstaben aus", Action: CapsOff},
+	{Phrase: "wörtlich", Action: Literal},
+}
+
+var french = Table{
+	stop("point", "."),
+	stop("point d'interrogation", "?"),
+	stop("point d'exclamation", "!"),
+	punct("virgule", ","),
+	punct("deux points", ":"),
+	punct("point-virgule", ";"),
+	punct("point virgule", ";"),
+	punct("points de suspension", "..."),
+	lineBreak("à la ligne", "\n"),
+	lineBreak("nouvelle ligne", "\n"),
+	lineBreak("nouveau paragraphe", "\n\n"),
+	open("ouvrez les guillemets", "« "),
+	{Phrase: "fermez les guillemets", Text: " »", Attach: AttachLeft},
+	open("ouvrez la parenthèse", "("),
+	punct("fermez la parenthèse", ")"),
+	{Phrase: "trait d'union", Text: "-", Attach: AttachBoth},
+	{Phrase: "tiret", Text: "–"},
+	{Phrase: "tout en majuscules", Action: CapsOn},
+	{Phrase: "fin des majuscules", Action: CapsOff},
+	{Phrase: "littéralement", Action: Literal},
+}
+
+var spanish = Table{
+	stop("punto", "."),
+	stop("punto y seguido", "."),
+	lineBreak("punto y aparte", ".\n\n"),
+	stop("signo de interrogación", "?"),
+	stop("signo de exclamación", "!"),
+	punct("coma", ","),
+	punct("dos puntos", ":"),
+	punct("punto y coma", ";"),
+	punct("puntos suspensivos", "..."),
+	lineBreak("nueva línea", "\n"),
+	lineBreak("nuevo párrafo", "\n\n"),
+	open("abrir comillas", `"`),
+	punct("cerrar comillas", `"`),
+	open("abrir paréntesis", "("),
+	punct("cerrar paréntesis", ")"),
+	{Phrase: "guion", Text: "-", Attach: AttachBoth},
+	{Phrase: "raya", Text: "–"},
+	{Phrase: "todo mayúsculas", Action: CapsOn},
+	{Phrase: "fin mayúsculas", Action: CapsOff},
+	{Phrase: "literal", Action: Literal},
+}
+
+var tables = map[string]Table{
+	"en": english,
+	"de": german,
+	"fr": french,
+	"es": spanish,
+}
+
+// ForLanguage returns the default commands for a language code such as "en"
+// or "de-AT". Languages without a table, and auto-detection, get English.
+func ForLanguage(lang string) Table {
+	base, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	base, _, _ = strings.Cut(base, "_")
+	if t, ok := tables[base]; ok {
+		return t
+	}
+	return english
+}
+
+// actions are the special values a command file may map a phrase to
+var actions = map[string]Action{
+	"<caps-on>":  CapsOn,
+	"<caps-off>": CapsOff,
+	"<literal>":  Literal,
+	"<none>":     Remove,
+}
+
+// attachments are the options naming which side inserted text joins
+var attachments = map[string]Attach{
+	"left":  AttachLeft,
+	"right": AttachRight,
+	"both":  AttachBoth,
+}
+
+// ParseCommands reads a command file with one "phrase = value [options]"
+// entry per line. The value is the text to insert, where \n, \t and \s stand
+// for a line break, a tab and a space, or one of <caps-on>, <caps-off>,
+// <literal> and <none>, which disables a default command. The options left,
+// right and both join the text to the word before, after or on both sides;
+// cap capitalizes the next word. Lines starting with # are comments.
+func ParseCommands(spec string) ([]Command, error) {
+	var cmds []Command
+	for n, line := range strings.Split(spec, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		phrase, rest, ok := strings.Cut(line, "=")
+		phrase = strings.TrimSpace(phrase)
+		fields := strings.Fields(rest)
+		if !ok || phrase == "" || len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: expected phrase = value", n+1)
+		}
+
+		cmd := Command{Phrase: phrase, Text: postprocess.Unescape(fields[0])}
+		if action, ok := actions[fields[0]]; ok {
+			cmd.Action, cmd.Text = action, ""
+		}
+		for _, opt := range fields[1:] {
+			if attach, ok := attachments[opt]; ok {
+				cmd.Attach = attach
+				continue
+			}
+			if opt != "cap" {
+				return nil, fmt.Errorf("line %d: unknown option %q, want left, right, both or cap", n+1, opt)
+			}
+			cmd.Capitalize = true
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
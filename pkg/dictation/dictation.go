@@ -0,0 +1,256 @@
+// Package dictation interprets spoken commands such as "period", "new
+// paragraph", "open quote" and "all caps ... end caps" in a transcript.
+package dictation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"speech-to-clipboard/pkg/postprocess"
+)
+
+// Action is what a spoken command does
+type Action int
+
+const (
+	// Insert writes the command's text
+	Insert Action = iota
+	// CapsOn upper-cases the following words until CapsOff
+	CapsOn
+	CapsOff
+	// Literal writes the next word as spoken, so "literal period" gives "period"
+	Literal
+	// Remove drops a phrase from a table; used to disable a default command
+	Remove
+)
+
+// Attach says which neighbours inserted text joins without a space
+type Attach int
+
+const (
+	AttachNone  Attach = iota
+	AttachLeft         // joins the word before, like "." or ")"
+	AttachRight        // joins the word after, like "("
+	AttachBoth         // joins both, like a line break
+)
+
+// Command is a spoken phrase and what it does
+type Command struct {
+	Phrase string
+	Action Action
+	Text   string
+	Attach Attach
+	// Capitalize raises the first letter of the next word, as after a full stop
+	Capitalize bool
+}
+
+// Table is a set of commands. Later entries for a phrase replace earlier ones.
+type Table []Command
+
+// With returns the table extended by cmds, which replace or, with the Remove
+// action, disable commands for the same phrase
+func (t Table) With(cmds ...Command) Table {
+	out := make(Table, 0, len(t)+len(cmds))
+	for _, c := range t {
+		if !replaced(c.Phrase, cmds) {
+			out = append(out, c)
+		}
+	}
+	for _, c := range cmds {
+		if c.Action != Remove {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func replaced(phrase string, cmds []Command) bool {
+	key := normalize(strings.Fields(phrase))
+	for _, c := range cmds {
+		if normalize(strings.Fields(c.Phrase)) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Grammar applies a command table to transcripts
+type Grammar struct {
+	commands map[string]Command
+	maxWords int
+}
+
+var _ postprocess.Processor = (*Grammar)(nil)
+
+// New returns a grammar for the commands in t
+func New(t Table) *Grammar {
+	g := &Grammar{commands: make(map[string]Command, len(t))}
+	for _, c := range t {
+		words := strings.Fields(c.Phrase)
+		if len(words) == 0 || c.Action == Remove {
+			continue
+		}
+		g.commands[normalize(words)] = c
+		g.maxWords = max(g.maxWords, len(words))
+	}
+	return g
+}
+
+// piece is a word or inserted text in the output
+type piece struct {
+	text       string
+	glueBefore bool
+	glueAfter  bool
+	inserted   bool
+}
+
+// Process replaces the spoken commands in text. Matching ignores case and
+// the punctuation the model adds around words, so "Period." is a command too.
+// Line breaks already in the text are kept.
+func (g *Grammar) Process(text string) string {
+	words := split(text)
+	out := make([]piece, 0, len(words))
+	caps, capNext, literal := false, false, false
+
+	for i := 0; i < len(words); {
+		if isBreak(words[i]) {
+			out = append(out, piece{text: words[i], glueBefore: true, glueAfter: true, inserted: true})
+			i++
+			continue
+		}
+		if !literal {
+			if cmd, n, ok := g.match(words[i:]); ok {
+				i += n
+				switch cmd.Action {
+				case Insert:
+					out = insert(out, cmd)
+				case CapsOn:
+					caps = true
+				case CapsOff:
+					caps = false
+				case Literal:
+					literal = true
+				}
+				capNext = capNext || cmd.Capitalize
+				continue
+			}
+		}
+
+		word := words[i]
+		switch {
+		case caps:
+			word = strings.ToUpper(word)
+		case capNext:
+			word = upperFirst(word)
+		}
+		out = append(out, piece{text: word})
+		capNext, literal = false, false
+		i++
+	}
+
+	var b strings.Builder
+	for i, p := range out {
+		if i > 0 && !out[i-1].glueAfter && !p.glueBefore {
+			b.WriteByte(' ')
+		}
+		b.WriteString(p.text)
+	}
+	return b.String()
+}
+
+// split breaks text into words and runs of line breaks, dropping the other
+// whitespace
+func split(text string) []string {
+	var tokens []string
+	fields := strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == '\t' || r == '\r' })
+	for _, field := range fields {
+		for field != "" {
+			i := strings.IndexByte(field, '\n')
+			if i < 0 {
+				tokens = append(tokens, field)
+				break
+			}
+			if i > 0 {
+				tokens = append(tokens, field[:i])
+			}
+			j := i
+			for j < len(field) && field[j] == '\n' {
+				j++
+			}
+			tokens = append(tokens, field[i:j])
+			field = field[j:]
+		}
+	}
+	return tokens
+}
+
+// isBreak reports whether a token from split is a run of line breaks
+func isBreak(token string) bool {
+	return token[0] == '\n'
+}
+
+// match finds the longest command at the start of words. Commands do not
+// span a line break.
+func (g *Grammar) match(words []string) (Command, int, bool) {
+	limit := min(g.maxWords, len(words))
+	for j := range limit {
+		if isBreak(words[j]) {
+			limit = j
+			break
+		}
+	}
+	for n := limit; n > 0; n-- {
+		if cmd, ok := g.commands[normalize(words[:n])]; ok {
+			return cmd, n, true
+		}
+	}
+	return Command{}, 0, false
+}
+
+// insert appends a command's text. Spoken punctuation replaces whatever
+// punctuation the model already put after the previous word.
+func insert(out []piece, cmd Command) []piece {
+	p := piece{
+		text:       cmd.Text,
+		glueBefore: cmd.Attach == AttachLeft || cmd.Attach == AttachBoth,
+		glueAfter:  cmd.Attach == AttachRight || cmd.Attach == AttachBoth,
+		inserted:   true,
+	}
+	// A paragraph break such as ".\n\n" replaces punctuation like a stop
+	if p.glueBefore && isPunctuation(strings.Trim(cmd.Text, "\n")) && len(out) > 0 && !out[len(out)-1].inserted {
+		last := &out[len(out)-1]
+		last.text = strings.TrimRight(last.text, ",.;:!?")
+	}
+	return append(out, p)
+}
+
+// normalize joins words in lower case without surrounding punctuation and
+// with typographic apostrophes made plain, as in "d’interrogation"
+func normalize(words []string) string {
+	parts := make([]string, len(words))
+	for i, w := range words {
+		w = strings.ReplaceAll(strings.TrimFunc(w, unicode.IsPunct), "’", "'")
+		parts[i] = strings.ToLower(w)
+	}
+	return strings.Join(parts, " ")
+}
+
+func isPunctuation(s string) bool {
+	for _, r := range s {
+		if !unicode.IsPunct(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// upperFirst upper-cases the first letter, skipping an opening quote or bracket
+func upperFirst(word string) string {
+	for i, r := range word {
+		if unicode.IsLetter(r) {
+			return word[:i] + string(unicode.ToUpper(r)) + word[i+utf8.RuneLen(r):]
+		}
+	}
+	return word
+}
@@ -0,0 +1,127 @@
+package dictation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGrammar_English(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "punctuation", in: "hello comma world period", want: "hello, world."},
+		{name: "capitalizes after a full stop", in: "done period next one", want: "done. Next one"},
+		{name: "model punctuation is replaced", in: "Hello. Period. How are you? Question mark.", want: "Hello. How are you?"},
+		{name: "new paragraph", in: "Dear Sam comma new paragraph thanks for the notes", want: "Dear Sam,\n\nThanks for the notes"},
+		{name: "quotes", in: "He said, open quote, hi, close quote.", want: `He said, "hi"`},
+		{name: "parentheses", in: "see open paren below close paren", want: "see (below)"},
+		{name: "all caps", in: "this is all caps very important end caps okay", want: "this is VERY IMPORTANT okay"},
+		{name: "literal", in: "the trial literal period ended", want: "the trial period ended"},
+		{name: "longest phrase wins", in: "one exclamation point two", want: "one! Two"},
+		{name: "case insensitive", in: "Yes Comma", want: "Yes,"},
+		{name: "hyphen", in: "well hyphen known", want: "well-known"},
+		{name: "no commands", in: "nothing to do here.", want: "nothing to do here."},
+		{name: "keeps line breaks", in: "Line one.\nLine two\n\n  Line three", want: "Line one.\nLine two\n\nLine three"},
+		{name: "command before a line break", in: "first period\nsecond", want: "first.\nSecond"},
+		{name: "no command across a line break", in: "new\nline", want: "new\nline"},
+		{name: "empty", in: "", want: ""},
+	}
+
+	g := New(ForLanguage("en"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.Process(tt.in); got != tt.want {
+				t.Errorf("Process(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrammar_Locales(t *testing.T) {
+	tests := []struct {
+		lang string
+		in   string
+		want string
+	}{
+		{lang: "de", in: "Hallo Komma wie geht's Fragezeichen gut", want: "Hallo, wie geht's? Gut"},
+		{lang: "de-AT", in: "er sagte Anführungszeichen auf ja Anführungszeichen zu", want: "er sagte „ja“"},
+		{lang: "fr", in: "Bonjour virgule ça va point d’interrogation oui", want: "Bonjour, ça va? Oui"},
+		{lang: "fr", in: "il dit ouvrez les guillemets oui fermez les guillemets", want: "il dit « oui »"},
+		{lang: "es", in: "hola coma qué tal punto y coma bien punto", want: "hola, qué tal; bien."},
+		{lang: "es", in: "hola punto y seguido qué tal punto y aparte adiós", want: "hola. Qué tal.\n\nAdiós"},
+		{lang: "es", in: "hola, punto y aparte adiós", want: "hola.\n\nAdiós"},
+		{lang: "ja", in: "hello period", want: "hello."},
+		{lang: "", in: "hello period", want: "hello."},
+	}
+
+	for _, tt := range tests {
+		g := New(ForLanguage(tt.lang))
+		if got := g.Process(tt.in); got != tt.want {
+			t.Errorf("%s: Process(%q) = %q, want %q", tt.lang, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTable_With(t *testing.T) {
+	table := Table{
+		{Phrase: "period", Text: ".", Attach: AttachLeft},
+		{Phrase: "comma", Text: ",", Attach: AttachLeft},
+	}.With(
+		Command{Phrase: "Period", Action: Remove},
+		Command{Phrase: "comma", Text: ";", Attach: AttachLeft},
+		Command{Phrase: "smiley", Text: ":-)"},
+	)
+
+	want := Table{
+		{Phrase: "comma", Text: ";", Attach: AttachLeft},
+		{Phrase: "smiley", Text: ":-)"},
+	}
+	if !reflect.DeepEqual(table, want) {
+		t.Errorf("With() = %+v, want %+v", table, want)
+	}
+
+	if got := New(table).Process("a period b comma smiley"); got != "a period b; :-)" {
+		t.Errorf("Process() = %q, want %q", got, "a period b; :-)")
+	}
+}
+
+func TestParseCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []Command
+		wantErr bool
+	}{
+		{
+			name: "entries",
+			spec: "# code comments\nfull stop = . left cap\nnew line = \\n both cap\nopen brace = { right\nshout = <caps-on>\nquiet = <caps-off>\nperiod = <none>\narrow = \\s->\\s\n",
+			want: []Command{
+				{Phrase: "full stop", Text: ".", Attach: AttachLeft, Capitalize: true},
+				{Phrase: "new line", Text: "\n", Attach: AttachBoth, Capitalize: true},
+				{Phrase: "open brace", Text: "{", Attach: AttachRight},
+				{Phrase: "shout", Action: CapsOn},
+				{Phrase: "quiet", Action: CapsOff},
+				{Phrase: "period", Action: Remove},
+				{Phrase: "arrow", Text: " -> "},
+			},
+		},
+		{name: "empty", spec: "\n# nothing\n", want: nil},
+		{name: "missing value", spec: "comma =", wantErr: true},
+		{name: "missing separator", spec: "comma", wantErr: true},
+		{name: "unknown option", spec: "comma = , sticky", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCommands(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCommands() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCommands() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
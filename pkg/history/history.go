@@ -0,0 +1,285 @@
+// Package history keeps recordings and their transcripts on disk so they can
+// be copied again or re-transcribed later.
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"speech-to-clipboard/pkg/audio/wav"
+)
+
+// ErrNotFound is returned when no entry matches an ID
+var ErrNotFound = errors.New("history entry not found")
+
+// idLayout names entries after their recording time so they sort by age
+const idLayout = "20060102-150405"
+
+// Entry describes one recording session
+type Entry struct {
+	ID       string        `json:"id"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Model    string        `json:"model,omitempty"`
+	Language string        `json:"language,omitempty"`
+	Text     string        `json:"text"`
+	// Error records why transcription failed; the audio is kept so the
+	// recording can be retried
+	Error string `json:"error,omitempty"`
+	// Retranscribed is set when Text came from a later transcription
+	Retranscribed time.Time `json:"retranscribed,omitzero"`
+}
+
+// Store keeps each entry as a JSON file next to a WAV file of its audio
+type Store struct {
+	dir string
+}
+
+// Open returns the store in dir, creating the directory if needed
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Dir returns the directory holding the store
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// AudioPath returns the WAV file holding an entry's audio
+func (s *Store) AudioPath(id string) string {
+	return filepath.Join(s.dir, id+".wav")
+}
+
+func (s *Store) entryPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Save stores a new entry with its mono audio. The entry gets an ID derived
+// from its time, which defaults to now.
+func (s *Store) Save(e *Entry, samples []int16, sampleRate int) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Duration == 0 && sampleRate > 0 {
+		e.Duration = time.Duration(len(samples)) * time.Second / time.Duration(sampleRate)
+	}
+
+	f, err := wav.NewInt16(samples, sampleRate, 1, wav.PCM16)
+	if err != nil {
+		return fmt.Errorf("failed to encode history audio: %w", err)
+	}
+
+	id := s.newID(e.Time)
+	e.ID = id
+
+	if err := writeFileAtomic(s.AudioPath(id), f.Encode); err != nil {
+		return fmt.Errorf("failed to save history audio: %w", err)
+	}
+	if err := s.Update(e); err != nil {
+		os.Remove(s.AudioPath(id))
+		return err
+	}
+	return nil
+}
+
+// newID returns an unused ID for an entry recorded at t
+func (s *Store) newID(t time.Time) string {
+	base := t.Format(idLayout)
+	id := base
+	for n := 2; ; n++ {
+		if _, err := os.Stat(s.entryPath(id)); errors.Is(err, os.ErrNotExist) {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// Update rewrites an existing entry's metadata
+func (s *Store) Update(e *Entry) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	write := func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}
+	if err := writeFileAtomic(s.entryPath(e.ID), write); err != nil {
+		return fmt.Errorf("failed to save history entry: %w", err)
+	}
+	return nil
+}
+
+// List returns every entry, newest first
+func (s *Store) List() ([]*Entry, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0, len(ids))
+	for _, id := range ids {
+		e, err := s.load(id)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// Get returns the entry with the given ID or unique ID prefix
+func (s *Store) Get(ref string) (*Entry, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, id := range ids {
+		if id == ref {
+			return s.load(id)
+		}
+		if strings.HasPrefix(id, ref) {
+			matches = append(matches, id)
+		}
+	}
+
+	switch {
+	case ref == "" || len(matches) == 0:
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, ref)
+	case len(matches) > 1:
+		return nil, fmt.Errorf("%q matches %d history entries", ref, len(matches))
+	}
+	return s.load(matches[0])
+}
+
+// Audio returns an entry's recorded samples and their sample rate
+func (s *Store) Audio(id string) ([]int16, int, error) {
+	f, err := os.Open(s.AudioPath(id))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open history audio: %w", err)
+	}
+	defer f.Close()
+
+	w, err := wav.Decode(f)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read history audio: %w", err)
+	}
+	if w.Format.Channels != 1 {
+		return nil, 0, fmt.Errorf("history audio has %d channels, want 1", w.Format.Channels)
+	}
+	return w.Int16(), w.Format.SampleRate, nil
+}
+
+// Delete removes an entry and its audio
+func (s *Store) Delete(id string) error {
+	if err := os.Remove(s.entryPath(id)); err != nil {
+		return fmt.Errorf("failed to delete history entry: %w", err)
+	}
+	if err := os.Remove(s.AudioPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete history audio: %w", err)
+	}
+	return nil
+}
+
+// Prune deletes all but the newest keep entries
+func (s *Store) Prune(keep int) error {
+	ids, err := s.ids()
+	if err != nil {
+		return err
+	}
+	if keep < 0 || len(ids) <= keep {
+		return nil
+	}
+
+	for _, id := range ids[keep:] {
+		if err := s.Delete(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ids returns the IDs of stored entries, newest first
+func (s *Store) ids() ([]string, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+
+	var ids []string
+	for _, f := range files {
+		if id, ok := strings.CutSuffix(f.Name(), ".json"); ok && !f.IsDir() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return idLess(ids[j], ids[i]) })
+	return ids, nil
+}
+
+// idLess orders IDs by time, then by the counter added to IDs that share a
+// second
+func idLess(a, b string) bool {
+	baseA, nA := splitID(a)
+	baseB, nB := splitID(b)
+	if baseA != baseB {
+		return baseA < baseB
+	}
+	return nA < nB
+}
+
+func splitID(id string) (string, int) {
+	if len(id) > len(idLayout) && id[len(idLayout)] == '-' {
+		var n int
+		if _, err := fmt.Sscanf(id[len(idLayout)+1:], "%d", &n); err == nil {
+			return id[:len(idLayout)], n
+		}
+	}
+	return id, 1
+}
+
+func (s *Store) load(id string) (*Entry, error) {
+	data, err := os.ReadFile(s.entryPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history entry: %w", err)
+	}
+
+	e := &Entry{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, fmt.Errorf("failed to parse history entry %s: %w", id, err)
+	}
+	e.ID = id
+	return e, nil
+}
+
+// writeFileAtomic writes a file through a temporary file in the same
+// directory, so readers never see it half written
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
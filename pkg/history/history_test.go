@@ -0,0 +1,164 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStore_SaveAndGet(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	recorded := time.Date(2024, 5, 1, 9, 30, 0, 0, time.Local)
+	samples := []int16{1, -2, 3, -4, 5, -6, 7, -8}
+	e := &Entry{Time: recorded, Model: "whisper-1", Language: "en", Text: "hello world"}
+	if err := store.Save(e, samples, 8); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	if e.ID != "20240501-093000" {
+		t.Errorf("Save() ID = %q, want %q", e.ID, "20240501-093000")
+	}
+	if e.Duration != time.Second {
+		t.Errorf("Save() duration = %v, want %v", e.Duration, time.Second)
+	}
+
+	got, err := store.Get(e.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Text != e.Text || got.Model != e.Model || got.Language != e.Language || !got.Time.Equal(e.Time) {
+		t.Errorf("Get() = %+v, want %+v", got, e)
+	}
+
+	audio, rate, err := store.Audio(e.ID)
+	if err != nil {
+		t.Fatalf("Audio() error = %v", err)
+	}
+	if rate != 8 || !reflect.DeepEqual(audio, samples) {
+		t.Errorf("Audio() = %v at %d Hz, want %v at 8 Hz", audio, rate, samples)
+	}
+}
+
+func TestStore_ListOrderAndIDs(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	first := time.Date(2024, 5, 1, 9, 30, 0, 0, time.Local)
+	times := []time.Time{first, first, first.Add(time.Hour), first}
+	for i, tm := range times {
+		if err := store.Save(&Entry{Time: tm, Text: string(rune('a' + i))}, []int16{0}, 16000); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	want := []string{"20240501-103000", "20240501-093000-3", "20240501-093000-2", "20240501-093000"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("List() IDs = %v, want %v", ids, want)
+	}
+}
+
+func TestStore_GetByPrefix(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	for _, tm := range []time.Time{
+		time.Date(2024, 5, 1, 9, 30, 0, 0, time.Local),
+		time.Date(2024, 5, 1, 9, 45, 0, 0, time.Local),
+		time.Date(2024, 6, 2, 8, 0, 0, 0, time.Local),
+	} {
+		if err := store.Save(&Entry{Time: tm}, []int16{0}, 16000); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{ref: "20240501-093000", want: "20240501-093000"},
+		{ref: "202406", want: "20240602-080000"},
+		{ref: "20240501", wantErr: true},
+		{ref: "2023", wantErr: true},
+		{ref: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := store.Get(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.ID != tt.want {
+				t.Errorf("Get() ID = %q, want %q", got.ID, tt.want)
+			}
+		})
+	}
+
+	if _, err := store.Get("2023"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStore_UpdateAndPrune(t *testing.T) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	first := time.Date(2024, 5, 1, 9, 30, 0, 0, time.Local)
+	var entries []*Entry
+	for i := 0; i < 3; i++ {
+		e := &Entry{Time: first.Add(time.Duration(i) * time.Minute), Error: "timeout"}
+		if err := store.Save(e, []int16{0}, 16000); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		entries = append(entries, e)
+	}
+
+	e := entries[2]
+	e.Text, e.Error = "fixed", ""
+	if err := store.Update(e); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, err := store.Get(e.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Text != "fixed" || got.Error != "" {
+		t.Errorf("Get() after Update() = %+v", got)
+	}
+
+	if err := store.Prune(1); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	remaining, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].ID != e.ID {
+		t.Errorf("List() after Prune(1) = %v, want only %s", remaining, e.ID)
+	}
+	if _, err := os.Stat(store.AudioPath(entries[0].ID)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Prune() left audio for %s", entries[0].ID)
+	}
+}
@@ -0,0 +1,35 @@
+package hotkey
+
+import "sync"
+
+// FakeSource is a Source driven by test code
+type FakeSource struct {
+	events    chan Event
+	closeOnce sync.Once
+}
+
+// NewFakeSource creates a fake source with room for a few queued events
+func NewFakeSource() *FakeSource {
+	return &FakeSource{events: make(chan Event, 16)}
+}
+
+// Events returns the channel of injected events
+func (f *FakeSource) Events() <-chan Event {
+	return f.events
+}
+
+// Press injects a key press
+func (f *FakeSource) Press() {
+	f.events <- Event{Type: Pressed}
+}
+
+// Release injects a key release
+func (f *FakeSource) Release() {
+	f.events <- Event{Type: Released}
+}
+
+// Close closes the event channel
+func (f *FakeSource) Close() error {
+	f.closeOnce.Do(func() { close(f.events) })
+	return nil
+}
@@ -0,0 +1,230 @@
+// Package hotkey delivers global keyboard shortcut presses so recording can
+// be controlled without the terminal having focus.
+package hotkey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned by NewSource on platforms without a backend
+var ErrUnsupported = errors.New("global hotkeys are not supported on this platform")
+
+// EventType distinguishes key presses from releases
+type EventType int
+
+const (
+	// Pressed is sent when the hotkey goes down
+	Pressed EventType = iota
+	// Released is sent when the hotkey comes back up
+	Released
+)
+
+func (t EventType) String() string {
+	if t == Pressed {
+		return "pressed"
+	}
+	return "released"
+}
+
+// Event is a single hotkey transition
+type Event struct {
+	Type EventType
+}
+
+// Source delivers events for one registered hotkey
+type Source interface {
+	// Events returns the channel of hotkey transitions; it is closed by Close
+	Events() <-chan Event
+	// Close releases the hotkey
+	Close() error
+}
+
+// Modifier is a bit set of modifier keys
+type Modifier uint8
+
+const (
+	ModShift Modifier = 1 << iota
+	ModCtrl
+	ModAlt
+	ModSuper
+)
+
+var modifierNames = map[string]Modifier{
+	"shift":   ModShift,
+	"ctrl":    ModCtrl,
+	"control": ModCtrl,
+	"alt":     ModAlt,
+	"super":   ModSuper,
+	"win":     ModSuper,
+	"meta":    ModSuper,
+}
+
+// Hotkey is a key together with the modifiers that must be held with it
+type Hotkey struct {
+	Modifiers Modifier
+	// Key is the lower-case key name, e.g. "space", "f9" or "r"
+	Key string
+}
+
+func (h Hotkey) String() string {
+	var parts []string
+	for _, m := range []struct {
+		mod  Modifier
+		name string
+	}{{ModCtrl, "ctrl"}, {ModAlt, "alt"}, {ModShift, "shift"}, {ModSuper, "super"}} {
+		if h.Modifiers&m.mod != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	return strings.Join(append(parts, h.Key), "+")
+}
+
+// Parse reads a hotkey written as modifiers and a key joined by "+", such as
+// "ctrl+alt+space" or "super+F9"
+func Parse(spec string) (Hotkey, error) {
+	var hk Hotkey
+
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(spec)), "+")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return Hotkey{}, fmt.Errorf("invalid hotkey %q: empty key name", spec)
+		}
+
+		if i < len(parts)-1 {
+			mod, ok := modifierNames[part]
+			if !ok {
+				return Hotkey{}, fmt.Errorf("invalid hotkey %q: unknown modifier %q", spec, part)
+			}
+			hk.Modifiers |= mod
+			continue
+		}
+
+		if _, ok := modifierNames[part]; ok {
+			return Hotkey{}, fmt.Errorf("invalid hotkey %q: missing key after modifiers", spec)
+		}
+		if _, ok := keysyms[part]; !ok {
+			return Hotkey{}, fmt.Errorf("invalid hotkey %q: unknown key %q", spec, part)
+		}
+		hk.Key = part
+	}
+
+	return hk, nil
+}
+
+// Mode decides how hotkey events control recording
+type Mode string
+
+const (
+	// PushToTalk records while the hotkey is held down
+	PushToTalk Mode = "push"
+	// Toggle starts recording on one press and stops it on the next
+	Toggle Mode = "toggle"
+)
+
+// ParseMode validates a mode name
+func ParseMode(name string) (Mode, error) {
+	switch Mode(name) {
+	case PushToTalk, Toggle:
+		return Mode(name), nil
+	}
+	return "", fmt.Errorf("invalid hotkey mode %q, want push or toggle", name)
+}
+
+// Action is what a hotkey event asks the recorder to do
+type Action int
+
+const (
+	// ActionNone means the event should be ignored
+	ActionNone Action = iota
+	// ActionStart starts a recording
+	ActionStart
+	// ActionStop stops the current recording
+	ActionStop
+	// ActionToggle starts a recording if idle and stops it otherwise
+	ActionToggle
+)
+
+// Action maps an event to what the recorder should do in this mode
+func (m Mode) Action(e Event) Action {
+	switch {
+	case m == Toggle && e.Type == Pressed:
+		return ActionToggle
+	case m == PushToTalk && e.Type == Pressed:
+		return ActionStart
+	case m == PushToTalk && e.Type == Released:
+		return ActionStop
+	}
+	return ActionNone
+}
+
+// rawKey is a key transition as reported by a backend, with the server
+// timestamp used to recognise auto-repeat
+type rawKey struct {
+	press bool
+	time  uint32
+}
+
+// filterAutoRepeat turns raw key transitions into clean press/release pairs.
+// Holding a key makes X11 send release+press pairs with equal timestamps;
+// a release is therefore held back for window and dropped together with a
+// press that matches it. Repeated presses without a release are collapsed.
+func filterAutoRepeat(raw <-chan rawKey, out chan<- Event, window time.Duration) {
+	defer close(out)
+
+	down := false
+	emit := func(pressed bool) {
+		if pressed == down {
+			return
+		}
+		down = pressed
+		if pressed {
+			out <- Event{Type: Pressed}
+		} else {
+			out <- Event{Type: Released}
+		}
+	}
+
+	var pending *rawKey
+	for {
+		if pending == nil {
+			key, ok := <-raw
+			if !ok {
+				return
+			}
+			if key.press {
+				emit(true)
+			} else {
+				pending = &key
+			}
+			continue
+		}
+
+		timer := time.NewTimer(window)
+		select {
+		case key, ok := <-raw:
+			timer.Stop()
+			if !ok {
+				emit(false)
+				return
+			}
+			if key.press && key.time == pending.time {
+				pending = nil
+				continue
+			}
+			emit(false)
+			pending = nil
+			if key.press {
+				emit(true)
+			} else {
+				pending = &key
+			}
+		case <-timer.C:
+			emit(false)
+			pending = nil
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package hotkey
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    Hotkey
+		wantErr bool
+	}{
+		{name: "single key", spec: "F9", want: Hotkey{Key: "f9"}},
+		{name: "modifiers", spec: "ctrl+alt+space", want: Hotkey{Modifiers: ModCtrl | ModAlt, Key: "space"}},
+		{name: "aliases and spaces", spec: " Control + Win + r ", want: Hotkey{Modifiers: ModCtrl | ModSuper, Key: "r"}},
+		{name: "empty", spec: "", wantErr: true},
+		{name: "unknown modifier", spec: "hyper+a", wantErr: true},
+		{name: "unknown key", spec: "ctrl+banana", wantErr: true},
+		{name: "only modifiers", spec: "ctrl+shift", wantErr: true},
+		{name: "trailing plus", spec: "ctrl+", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHotkey_String(t *testing.T) {
+	hk := Hotkey{Modifiers: ModSuper | ModShift | ModCtrl, Key: "f12"}
+	if got := hk.String(); got != "ctrl+shift+super+f12" {
+		t.Errorf("String() = %v, want %v", got, "ctrl+shift+super+f12")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, name := range []string{"push", "toggle"} {
+		if _, err := ParseMode(name); err != nil {
+			t.Errorf("ParseMode(%q) unexpected error = %v", name, err)
+		}
+	}
+
+	if _, err := ParseMode("hold"); err == nil {
+		t.Error("ParseMode(\"hold\") expected error, got nil")
+	}
+}
+
+func TestMode_Action(t *testing.T) {
+	tests := []struct {
+		mode  Mode
+		event EventType
+		want  Action
+	}{
+		{PushToTalk, Pressed, ActionStart},
+		{PushToTalk, Released, ActionStop},
+		{Toggle, Pressed, ActionToggle},
+		{Toggle, Released, ActionNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode)+" "+tt.event.String(), func(t *testing.T) {
+			if got := tt.mode.Action(Event{Type: tt.event}); got != tt.want {
+				t.Errorf("Action() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeSource(t *testing.T) {
+	src := NewFakeSource()
+	src.Press()
+	src.Release()
+	if err := src.Close(); err != nil {
+		t.Fatalf("Close() unexpected error = %v", err)
+	}
+	if err := src.Close(); err != nil {
+		t.Fatalf("second Close() unexpected error = %v", err)
+	}
+
+	var got []EventType
+	for e := range src.Events() {
+		got = append(got, e.Type)
+	}
+
+	if len(got) != 2 || got[0] != Pressed || got[1] != Released {
+		t.Errorf("Events() = %v, want [pressed released]", got)
+	}
+}
+
+func collect(raw []rawKey, gap time.Duration) []EventType {
+	in := make(chan rawKey)
+	out := make(chan Event)
+	go filterAutoRepeat(in, out, 20*time.Millisecond)
+
+	go func() {
+		defer close(in)
+		for _, key := range raw {
+			in <- key
+			time.Sleep(gap)
+		}
+	}()
+
+	var got []EventType
+	for e := range out {
+		got = append(got, e.Type)
+	}
+	return got
+}
+
+func TestFilterAutoRepeat(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  []rawKey
+		gap  time.Duration
+		want []EventType
+	}{
+		{
+			name: "single tap",
+			raw:  []rawKey{{press: true, time: 1}, {press: false, time: 2}},
+			want: []EventType{Pressed, Released},
+		},
+		{
+			name: "held key with auto-repeat",
+			raw: []rawKey{
+				{press: true, time: 1},
+				{press: false, time: 5}, {press: true, time: 5},
+				{press: false, time: 9}, {press: true, time: 9},
+				{press: false, time: 12},
+			},
+			want: []EventType{Pressed, Released},
+		},
+		{
+			name: "repeated presses without release",
+			raw:  []rawKey{{press: true, time: 1}, {press: true, time: 2}, {press: false, time: 3}},
+			want: []EventType{Pressed, Released},
+		},
+		{
+			name: "two separate taps",
+			raw: []rawKey{
+				{press: true, time: 1}, {press: false, time: 2},
+				{press: true, time: 3}, {press: false, time: 4},
+			},
+			gap:  30 * time.Millisecond,
+			want: []EventType{Pressed, Released, Pressed, Released},
+		},
+		{
+			name: "quick taps with different timestamps",
+			raw: []rawKey{
+				{press: true, time: 1}, {press: false, time: 2},
+				{press: true, time: 3}, {press: false, time: 4},
+			},
+			want: []EventType{Pressed, Released, Pressed, Released},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collect(tt.raw, tt.gap)
+			if len(got) != len(tt.want) {
+				t.Fatalf("events = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("events = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package hotkey
+
+import "strconv"
+
+// keysyms maps key names accepted by Parse to X11 keysym values. Letters and
+// digits are added in init.
+var keysyms = map[string]uint32{
+	"space":       0x0020,
+	"grave":       0x0060,
+	"minus":       0x002d,
+	"equal":       0x003d,
+	"comma":       0x002c,
+	"period":      0x002e,
+	"slash":       0x002f,
+	"semicolon":   0x003b,
+	"backslash":   0x005c,
+	"return":      0xff0d,
+	"enter":       0xff0d,
+	"tab":         0xff09,
+	"escape":      0xff1b,
+	"pause":       0xff13,
+	"scroll_lock": 0xff14,
+	"print":       0xff61,
+	"insert":      0xff63,
+	"delete":      0xffff,
+	"home":        0xff50,
+	"end":         0xff57,
+	"page_up":     0xff55,
+	"page_down":   0xff56,
+	"left":        0xff51,
+	"up":          0xff52,
+	"right":       0xff53,
+	"down":        0xff54,
+	"menu":        0xff67,
+}
+
+func init() {
+	for c := 'a'; c <= 'z'; c++ {
+		keysyms[string(c)] = uint32(c)
+	}
+	for c := '0'; c <= '9'; c++ {
+		keysyms[string(c)] = uint32(c)
+	}
+	for i := 1; i <= 24; i++ {
+		keysyms["f"+strconv.Itoa(i)] = 0xffbe + uint32(i-1)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package hotkey
+
+// NewSource registers a global hotkey with the platform's window system
+func NewSource(hk Hotkey) (Source, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,444 @@
+//go:build linux
+
+package hotkey
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// X11 protocol constants used by the hotkey grabber
+const (
+	x11OpGrabKey            = 33
+	x11OpGetInputFocus      = 43
+	x11OpGetKeyboardMapping = 101
+
+	x11Error      = 0
+	x11Reply      = 1
+	x11KeyPress   = 2
+	x11KeyRelease = 3
+
+	x11BadAccess = 10
+
+	x11GrabModeAsync = 1
+
+	x11MaskShift = 0x01
+	x11MaskLock  = 0x02
+	x11MaskCtrl  = 0x04
+	x11MaskMod1  = 0x08
+	x11MaskMod2  = 0x10
+	x11MaskMod4  = 0x40
+
+	// autoRepeatWindow is how long a release waits for a matching repeat press
+	autoRepeatWindow = 30 * time.Millisecond
+)
+
+// NewSource registers a global hotkey with the X server named by $DISPLAY.
+// Wayland sessions work through XWayland only while an X11 window has focus.
+func NewSource(hk Hotkey) (Source, error) {
+	return NewX11Source(os.Getenv("DISPLAY"), hk)
+}
+
+// x11Source grabs one key combination on the root window of an X display
+type x11Source struct {
+	conn      net.Conn
+	events    chan Event
+	closeOnce sync.Once
+}
+
+// NewX11Source connects to an X display and grabs the hotkey on its root window
+func NewX11Source(display string, hk Hotkey) (Source, error) {
+	network, address, number, err := parseDisplay(display)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout(network, address, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X display %q: %w", display, err)
+	}
+
+	authName, authData := findXauthCookie(xauthorityPath(), number)
+	src, err := newX11Source(conn, hk, authName, authData)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return src, nil
+}
+
+func newX11Source(conn net.Conn, hk Hotkey, authName string, authData []byte) (*x11Source, error) {
+	keysym, ok := keysyms[hk.Key]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", hk.Key)
+	}
+
+	setup, err := x11Handshake(conn, authName, authData)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &x11Client{rw: conn}
+	keycode, err := c.findKeycode(setup, keysym)
+	if err != nil {
+		return nil, err
+	}
+
+	// Grab every combination of the lock modifiers so the hotkey still
+	// fires with Caps Lock or Num Lock on
+	mods := x11Modifiers(hk.Modifiers)
+	for _, lock := range []uint16{0, x11MaskLock, x11MaskMod2, x11MaskLock | x11MaskMod2} {
+		if err := c.grabKey(setup.root, mods|lock, keycode); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.sync(); err != nil {
+		if errors.Is(err, errX11Access) {
+			return nil, fmt.Errorf("hotkey %s is already grabbed by another application", hk)
+		}
+		return nil, err
+	}
+
+	raw := make(chan rawKey)
+	src := &x11Source{conn: conn, events: make(chan Event)}
+	go filterAutoRepeat(raw, src.events, autoRepeatWindow)
+	go c.readKeys(keycode, raw)
+	return src, nil
+}
+
+// Events returns the hotkey transitions
+func (s *x11Source) Events() <-chan Event {
+	return s.events
+}
+
+// Close disconnects from the X server, which releases the grab
+func (s *x11Source) Close() error {
+	var err error
+	s.closeOnce.Do(func() { err = s.conn.Close() })
+	return err
+}
+
+func x11Modifiers(m Modifier) uint16 {
+	var mask uint16
+	if m&ModShift != 0 {
+		mask |= x11MaskShift
+	}
+	if m&ModCtrl != 0 {
+		mask |= x11MaskCtrl
+	}
+	if m&ModAlt != 0 {
+		mask |= x11MaskMod1
+	}
+	if m&ModSuper != 0 {
+		mask |= x11MaskMod4
+	}
+	return mask
+}
+
+// parseDisplay turns a DISPLAY value such as ":0", ":1.0", "unix:0" or
+// "host:0" into a dial address and the display number
+func parseDisplay(display string) (network, address, number string, err error) {
+	if display == "" {
+		return "", "", "", errors.New("DISPLAY is not set; global hotkeys need an X11 session")
+	}
+
+	// Some systems export a socket path, e.g. /private/tmp/com.apple.launchd.x/org.xquartz:0
+	if strings.HasPrefix(display, "/") {
+		socket := display
+		if i := strings.LastIndex(display, ":"); i >= 0 {
+			socket = display[:i]
+			number = display[i+1:]
+		}
+		if i := strings.Index(number, "."); i >= 0 {
+			number = number[:i]
+		}
+		return "unix", socket, number, nil
+	}
+
+	colon := strings.LastIndex(display, ":")
+	if colon < 0 {
+		return "", "", "", fmt.Errorf("invalid DISPLAY %q", display)
+	}
+
+	host := display[:colon]
+	number = display[colon+1:]
+	if i := strings.Index(number, "."); i >= 0 {
+		number = number[:i]
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid DISPLAY %q", display)
+	}
+
+	if host == "" || host == "unix" {
+		return "unix", "/tmp/.X11-unix/X" + number, number, nil
+	}
+	return "tcp", net.JoinHostPort(host, strconv.Itoa(6000+n)), number, nil
+}
+
+func xauthorityPath() string {
+	if path := os.Getenv("XAUTHORITY"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".Xauthority")
+}
+
+// findXauthCookie returns the MIT-MAGIC-COOKIE-1 for a local display from an
+// Xauthority file, or empty values if there is none
+func findXauthCookie(path, number string) (string, []byte) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil
+	}
+
+	hostname, _ := os.Hostname()
+	return parseXauthority(bytes.NewReader(data), hostname, number)
+}
+
+// parseXauthority scans Xauthority entries for a cookie usable on display
+// number of this host
+func parseXauthority(r io.Reader, hostname, number string) (string, []byte) {
+	const (
+		familyLocal = 256
+		familyWild  = 65535
+	)
+
+	readField := func() ([]byte, error) {
+		var n uint16
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		field := make([]byte, n)
+		_, err := io.ReadFull(r, field)
+		return field, err
+	}
+
+	for {
+		var family uint16
+		if err := binary.Read(r, binary.BigEndian, &family); err != nil {
+			return "", nil
+		}
+		address, err1 := readField()
+		num, err2 := readField()
+		name, err3 := readField()
+		data, err4 := readField()
+		if err := errors.Join(err1, err2, err3, err4); err != nil {
+			return "", nil
+		}
+
+		if string(name) != "MIT-MAGIC-COOKIE-1" {
+			continue
+		}
+		if len(num) > 0 && string(num) != number {
+			continue
+		}
+		if family == familyWild || (family == familyLocal && string(address) == hostname) {
+			return string(name), data
+		}
+	}
+}
+
+// x11Setup holds the parts of the connection setup reply the grabber needs
+type x11Setup struct {
+	root       uint32
+	minKeycode byte
+	maxKeycode byte
+}
+
+// x11Handshake sends the little-endian connection request and parses the reply
+func x11Handshake(rw io.ReadWriter, authName string, authData []byte) (*x11Setup, error) {
+	req := make([]byte, 12)
+	req[0] = 'l'
+	binary.LittleEndian.PutUint16(req[2:], 11)
+	binary.LittleEndian.PutUint16(req[6:], uint16(len(authName)))
+	binary.LittleEndian.PutUint16(req[8:], uint16(len(authData)))
+	req = append(req, pad4([]byte(authName))...)
+	req = append(req, pad4(authData)...)
+	if _, err := rw.Write(req); err != nil {
+		return nil, fmt.Errorf("failed to send X11 setup: %w", err)
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(rw, header); err != nil {
+		return nil, fmt.Errorf("failed to read X11 setup: %w", err)
+	}
+	body := make([]byte, int(binary.LittleEndian.Uint16(header[6:]))*4)
+	if _, err := io.ReadFull(rw, body); err != nil {
+		return nil, fmt.Errorf("failed to read X11 setup: %w", err)
+	}
+
+	if header[0] != 1 {
+		reason := body
+		if header[0] == 0 && int(header[1]) <= len(body) {
+			reason = body[:header[1]]
+		}
+		return nil, fmt.Errorf("X server refused connection: %s", strings.TrimSpace(string(reason)))
+	}
+
+	if len(body) < 32 {
+		return nil, errors.New("X11 setup reply too short")
+	}
+	vendorLen := int(binary.LittleEndian.Uint16(body[16:]))
+	formats := int(body[21])
+	screen := 32 + (vendorLen+3)/4*4 + formats*8
+	if len(body) < screen+4 {
+		return nil, errors.New("X11 setup reply has no screens")
+	}
+
+	return &x11Setup{
+		root:       binary.LittleEndian.Uint32(body[screen:]),
+		minKeycode: body[26],
+		maxKeycode: body[27],
+	}, nil
+}
+
+var errX11Access = errors.New("X11 access denied")
+
+// x11Client issues requests on an established connection. It is only used
+// from one goroutine at a time: setup, then the event reader.
+type x11Client struct {
+	rw  io.ReadWriter
+	seq uint16
+}
+
+func (c *x11Client) send(req []byte) error {
+	c.seq++
+	_, err := c.rw.Write(req)
+	return err
+}
+
+// readMessage reads one server message: a 32 byte event, error or reply
+// header plus the reply's extra data
+func (c *x11Client) readMessage() ([]byte, error) {
+	msg := make([]byte, 32)
+	if _, err := io.ReadFull(c.rw, msg); err != nil {
+		return nil, err
+	}
+	if msg[0] == x11Reply {
+		extra := make([]byte, int(binary.LittleEndian.Uint32(msg[4:]))*4)
+		if _, err := io.ReadFull(c.rw, extra); err != nil {
+			return nil, err
+		}
+		msg = append(msg, extra...)
+	}
+	return msg, nil
+}
+
+// roundTrip waits for the reply to the last request, failing on any error
+// reported for the requests sent before it
+func (c *x11Client) roundTrip() ([]byte, error) {
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read X11 reply: %w", err)
+		}
+		switch msg[0] {
+		case x11Error:
+			if msg[1] == x11BadAccess {
+				return nil, errX11Access
+			}
+			return nil, fmt.Errorf("X11 request failed with error code %d", msg[1])
+		case x11Reply:
+			if binary.LittleEndian.Uint16(msg[2:]) == c.seq {
+				return msg, nil
+			}
+		}
+	}
+}
+
+// findKeycode looks up the keycode that produces keysym
+func (c *x11Client) findKeycode(setup *x11Setup, keysym uint32) (byte, error) {
+	count := setup.maxKeycode - setup.minKeycode + 1
+	req := []byte{x11OpGetKeyboardMapping, 0, 2, 0, setup.minKeycode, count, 0, 0}
+	if err := c.send(req); err != nil {
+		return 0, fmt.Errorf("failed to query keyboard mapping: %w", err)
+	}
+
+	reply, err := c.roundTrip()
+	if err != nil {
+		return 0, err
+	}
+
+	perKeycode := int(reply[1])
+	syms := reply[32:]
+	for i := 0; i < int(count); i++ {
+		for j := 0; j < perKeycode; j++ {
+			off := (i*perKeycode + j) * 4
+			if off+4 > len(syms) {
+				break
+			}
+			if binary.LittleEndian.Uint32(syms[off:]) == keysym {
+				return setup.minKeycode + byte(i), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no key on this keyboard produces keysym 0x%x", keysym)
+}
+
+func (c *x11Client) grabKey(window uint32, modifiers uint16, keycode byte) error {
+	req := make([]byte, 16)
+	req[0] = x11OpGrabKey
+	req[1] = 1 // owner-events
+	binary.LittleEndian.PutUint16(req[2:], 4)
+	binary.LittleEndian.PutUint32(req[4:], window)
+	binary.LittleEndian.PutUint16(req[8:], modifiers)
+	req[10] = keycode
+	req[11] = x11GrabModeAsync
+	req[12] = x11GrabModeAsync
+	if err := c.send(req); err != nil {
+		return fmt.Errorf("failed to grab hotkey: %w", err)
+	}
+	return nil
+}
+
+// sync makes a round trip so errors from earlier requests are reported
+func (c *x11Client) sync() error {
+	if err := c.send([]byte{x11OpGetInputFocus, 0, 1, 0}); err != nil {
+		return fmt.Errorf("failed to sync with X server: %w", err)
+	}
+	_, err := c.roundTrip()
+	return err
+}
+
+// readKeys forwards presses and releases of keycode until the connection closes
+func (c *x11Client) readKeys(keycode byte, raw chan<- rawKey) {
+	defer close(raw)
+
+	for {
+		msg, err := c.readMessage()
+		if err != nil {
+			return
+		}
+
+		// The high bit marks events sent by other clients
+		kind := msg[0] & 0x7f
+		if (kind != x11KeyPress && kind != x11KeyRelease) || msg[1] != keycode {
+			continue
+		}
+		raw <- rawKey{
+			press: kind == x11KeyPress,
+			time:  binary.LittleEndian.Uint32(msg[4:]),
+		}
+	}
+}
+
+// pad4 returns b padded with zeros to a multiple of four bytes
+func pad4(b []byte) []byte {
+	padded := make([]byte, (len(b)+3)/4*4)
+	copy(padded, b)
+	return padded
+}
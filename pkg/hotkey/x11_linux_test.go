@@ -0,0 +1,264 @@
+//go:build linux
+
+package hotkey
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeXServer plays the server side of the protocol exchange performed by
+// newX11Source, then sends the given key events
+type fakeXServer struct {
+	conn      net.Conn
+	denyGrab  bool
+	grabMasks []uint16
+	grabKeys  []byte
+	events    [][]byte
+	err       chan error
+}
+
+func (s *fakeXServer) run() {
+	s.err <- s.serve()
+}
+
+func (s *fakeXServer) serve() error {
+	le := binary.LittleEndian
+
+	// Connection setup request with empty authorization
+	req := make([]byte, 12)
+	if _, err := io.ReadFull(s.conn, req); err != nil {
+		return err
+	}
+
+	vendor := []byte("Fake")
+	body := make([]byte, 32)
+	le.PutUint16(body[16:], uint16(len(vendor)))
+	body[20] = 1 // screens
+	body[21] = 0 // formats
+	body[26] = 8 // min keycode
+	body[27] = 10
+	body = append(body, vendor...)
+	screen := make([]byte, 4)
+	le.PutUint32(screen, 0x1234)
+	body = append(body, screen...)
+
+	header := []byte{1, 0, 11, 0, 0, 0, 0, 0}
+	le.PutUint16(header[6:], uint16(len(body)/4))
+	if _, err := s.conn.Write(append(header, body...)); err != nil {
+		return err
+	}
+
+	// GetKeyboardMapping for keycodes 8..10, two keysyms each
+	req = make([]byte, 8)
+	if _, err := io.ReadFull(s.conn, req); err != nil {
+		return err
+	}
+	reply := make([]byte, 32)
+	reply[0] = 1
+	reply[1] = 2
+	le.PutUint16(reply[2:], 1)
+	le.PutUint32(reply[4:], 6)
+	for _, sym := range []uint32{0x61, 0x41, 0x20, 0, 0xffc6, 0} {
+		b := make([]byte, 4)
+		le.PutUint32(b, sym)
+		reply = append(reply, b...)
+	}
+	if _, err := s.conn.Write(reply); err != nil {
+		return err
+	}
+
+	// Four GrabKey requests
+	for i := 0; i < 4; i++ {
+		req = make([]byte, 16)
+		if _, err := io.ReadFull(s.conn, req); err != nil {
+			return err
+		}
+		if le.Uint32(req[4:]) != 0x1234 {
+			return io.ErrUnexpectedEOF
+		}
+		s.grabMasks = append(s.grabMasks, le.Uint16(req[8:]))
+		s.grabKeys = append(s.grabKeys, req[10])
+	}
+
+	// GetInputFocus sync
+	req = make([]byte, 4)
+	if _, err := io.ReadFull(s.conn, req); err != nil {
+		return err
+	}
+	if s.denyGrab {
+		msg := make([]byte, 32)
+		msg[1] = x11BadAccess
+		le.PutUint16(msg[2:], 2)
+		if _, err := s.conn.Write(msg); err != nil {
+			return err
+		}
+	}
+	reply = make([]byte, 32)
+	reply[0] = 1
+	le.PutUint16(reply[2:], 6)
+	if _, err := s.conn.Write(reply); err != nil {
+		return err
+	}
+
+	for _, event := range s.events {
+		if _, err := s.conn.Write(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func keyEvent(kind byte, keycode byte, time uint32) []byte {
+	msg := make([]byte, 32)
+	msg[0] = kind
+	msg[1] = keycode
+	binary.LittleEndian.PutUint32(msg[4:], time)
+	return msg
+}
+
+func TestX11Source_GrabAndEvents(t *testing.T) {
+	client, server := net.Pipe()
+	fake := &fakeXServer{
+		conn: server,
+		events: [][]byte{
+			keyEvent(x11KeyPress, 9, 100),
+			keyEvent(x11KeyPress, 8, 110), // another key, ignored
+			keyEvent(x11KeyRelease, 9, 200),
+			keyEvent(x11KeyPress, 9, 200), // auto-repeat
+			keyEvent(x11KeyRelease, 9, 300),
+		},
+		err: make(chan error, 1),
+	}
+	go fake.run()
+
+	src, err := newX11Source(client, Hotkey{Modifiers: ModCtrl | ModAlt, Key: "space"}, "", nil)
+	if err != nil {
+		t.Fatalf("newX11Source() unexpected error = %v", err)
+	}
+	defer src.Close()
+
+	var got []EventType
+	for len(got) < 2 {
+		select {
+		case e := <-src.Events():
+			got = append(got, e.Type)
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for events, got %v", got)
+		}
+	}
+
+	if got[0] != Pressed || got[1] != Released {
+		t.Errorf("events = %v, want [pressed released]", got)
+	}
+
+	if err := <-fake.err; err != nil {
+		t.Fatalf("fake server error = %v", err)
+	}
+
+	wantMasks := []uint16{0x0c, 0x0e, 0x1c, 0x1e}
+	for i, mask := range fake.grabMasks {
+		if mask != wantMasks[i] {
+			t.Errorf("grab %d modifiers = 0x%x, want 0x%x", i, mask, wantMasks[i])
+		}
+		if fake.grabKeys[i] != 9 {
+			t.Errorf("grab %d keycode = %d, want 9", i, fake.grabKeys[i])
+		}
+	}
+}
+
+func TestX11Source_AlreadyGrabbed(t *testing.T) {
+	client, server := net.Pipe()
+	fake := &fakeXServer{conn: server, denyGrab: true, err: make(chan error, 1)}
+	go fake.run()
+	defer client.Close()
+
+	_, err := newX11Source(client, Hotkey{Key: "f9"}, "", nil)
+	if err == nil || !strings.Contains(err.Error(), "already grabbed") {
+		t.Errorf("newX11Source() error = %v, want already grabbed", err)
+	}
+}
+
+func TestX11Source_UnmappedKey(t *testing.T) {
+	client, server := net.Pipe()
+	fake := &fakeXServer{conn: server, err: make(chan error, 1)}
+	go fake.run()
+	defer client.Close()
+	defer server.Close()
+
+	if _, err := newX11Source(client, Hotkey{Key: "f1"}, "", nil); err == nil {
+		t.Error("newX11Source() expected error for key missing from the keymap, got nil")
+	}
+}
+
+func TestParseDisplay(t *testing.T) {
+	tests := []struct {
+		display     string
+		wantNetwork string
+		wantAddress string
+		wantNumber  string
+		wantErr     bool
+	}{
+		{display: ":0", wantNetwork: "unix", wantAddress: "/tmp/.X11-unix/X0", wantNumber: "0"},
+		{display: ":1.0", wantNetwork: "unix", wantAddress: "/tmp/.X11-unix/X1", wantNumber: "1"},
+		{display: "unix:2", wantNetwork: "unix", wantAddress: "/tmp/.X11-unix/X2", wantNumber: "2"},
+		{display: "localhost:10.0", wantNetwork: "tcp", wantAddress: "localhost:6010", wantNumber: "10"},
+		{display: "/tmp/launchd/org.xquartz:0", wantNetwork: "unix", wantAddress: "/tmp/launchd/org.xquartz", wantNumber: "0"},
+		{display: "", wantErr: true},
+		{display: "nocolon", wantErr: true},
+		{display: ":x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.display, func(t *testing.T) {
+			network, address, number, err := parseDisplay(tt.display)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDisplay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if network != tt.wantNetwork || address != tt.wantAddress || number != tt.wantNumber {
+				t.Errorf("parseDisplay() = %q %q %q, want %q %q %q",
+					network, address, number, tt.wantNetwork, tt.wantAddress, tt.wantNumber)
+			}
+		})
+	}
+}
+
+func xauthEntry(family uint16, address, number, name string, data []byte) []byte {
+	buf := new(bytes.Buffer)
+	_ = binary.Write(buf, binary.BigEndian, family)
+	for _, field := range [][]byte{[]byte(address), []byte(number), []byte(name), data} {
+		_ = binary.Write(buf, binary.BigEndian, uint16(len(field)))
+		buf.Write(field)
+	}
+	return buf.Bytes()
+}
+
+func TestParseXauthority(t *testing.T) {
+	var file []byte
+	file = append(file, xauthEntry(256, "otherhost", "0", "MIT-MAGIC-COOKIE-1", []byte{1})...)
+	file = append(file, xauthEntry(256, "myhost", "1", "MIT-MAGIC-COOKIE-1", []byte{2})...)
+	file = append(file, xauthEntry(256, "myhost", "0", "XDM-AUTHORIZATION-1", []byte{3})...)
+	file = append(file, xauthEntry(256, "myhost", "0", "MIT-MAGIC-COOKIE-1", []byte{4, 5})...)
+
+	name, data := parseXauthority(bytes.NewReader(file), "myhost", "0")
+	if name != "MIT-MAGIC-COOKIE-1" || !bytes.Equal(data, []byte{4, 5}) {
+		t.Errorf("parseXauthority() = %q %v, want MIT-MAGIC-COOKIE-1 [4 5]", name, data)
+	}
+
+	name, _ = parseXauthority(bytes.NewReader(file), "myhost", "7")
+	if name != "" {
+		t.Errorf("parseXauthority() for unknown display = %q, want empty", name)
+	}
+
+	wild := xauthEntry(65535, "", "", "MIT-MAGIC-COOKIE-1", []byte{9})
+	name, data = parseXauthority(bytes.NewReader(wild), "anyhost", "3")
+	if name != "MIT-MAGIC-COOKIE-1" || !bytes.Equal(data, []byte{9}) {
+		t.Errorf("parseXauthority() wildcard = %q %v, want MIT-MAGIC-COOKIE-1 [9]", name, data)
+	}
+}
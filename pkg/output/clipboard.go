@@ -0,0 +1,52 @@
+package output
+
+import (
+	"sync"
+
+	"speech-to-clipboard/pkg/clipboard"
+)
+
+// ClipboardSink copies each transcript to the clipboard. A streaming
+// transcript is written once by Update and then grows in place.
+type ClipboardSink struct {
+	mgr clipboard.Manager
+
+	mu sync.Mutex
+	// growing is set once Update wrote a transcript that Send has not
+	// finished yet
+	growing bool
+}
+
+// NewClipboardSink returns a sink copying transcripts with mgr
+func NewClipboardSink(mgr clipboard.Manager) *ClipboardSink {
+	return &ClipboardSink{mgr: mgr}
+}
+
+// Send copies text as a new transcript, or as the final form of the one
+// Update has been growing
+func (s *ClipboardSink) Send(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.growing {
+		s.growing = false
+		return s.mgr.Update(text)
+	}
+	return s.mgr.Write(text)
+}
+
+// Update copies the transcript in progress. The first call adds it to the
+// clipboard; later ones replace it until Send finishes it.
+func (s *ClipboardSink) Update(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.growing {
+		return s.mgr.Update(text)
+	}
+	if err := s.mgr.Write(text); err != nil {
+		return err
+	}
+	s.growing = true
+	return nil
+}
@@ -0,0 +1,79 @@
+package output
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"speech-to-clipboard/pkg/clipboard"
+)
+
+func TestClipboardSink(t *testing.T) {
+	mgr := clipboard.NewMockManager()
+	sink := NewClipboardSink(mgr)
+
+	if err := sink.Send("first"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	// A streaming transcript is added once and then grows in place
+	for _, text := range []string{"second", "second and more"} {
+		if err := sink.Update(text); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+	if err := sink.Send("Second and more."); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := sink.Send("third"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	want := []string{"third", "Second and more.", "first"}
+	if got := mgr.History(); !reflect.DeepEqual(got, want) {
+		t.Errorf("History() = %q, want %q", got, want)
+	}
+	if got := mgr.GetContent(); got != "third" {
+		t.Errorf("clipboard = %q, want %q", got, "third")
+	}
+}
+
+func TestClipboardSink_UpdateFails(t *testing.T) {
+	mgr := clipboard.NewMockManager()
+	_ = mgr.Write("earlier")
+	sink := NewClipboardSink(mgr)
+
+	// A failed first Update leaves nothing to grow, so the next one adds
+	// the transcript instead of replacing the earlier content
+	mgr.SetError(errors.New("no display"))
+	if err := sink.Update("one"); err == nil {
+		t.Fatal("Update() error = nil, want an error")
+	}
+	mgr.SetError(nil)
+	if err := sink.Update("one two"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	want := []string{"one two", "earlier"}
+	if got := mgr.History(); !reflect.DeepEqual(got, want) {
+		t.Errorf("History() = %q, want %q", got, want)
+	}
+}
+
+func TestMulti_Update(t *testing.T) {
+	mgr := clipboard.NewMockManager()
+	var sent []string
+	sinks := Multi{NewClipboardSink(mgr), sinkFunc(func(text string) error {
+		sent = append(sent, text)
+		return nil
+	})}
+
+	if err := sinks.Update("partial"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := mgr.GetContent(); got != "partial" {
+		t.Errorf("clipboard = %q, want %q", got, "partial")
+	}
+	if len(sent) != 0 {
+		t.Errorf("Update() reached a plain sink: %q", sent)
+	}
+}
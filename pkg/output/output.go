@@ -0,0 +1,103 @@
+// Package output delivers finished transcripts to their destinations, such
+// as the clipboard, the focused window, a file or a webhook.
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink receives each finished transcript
+type Sink interface {
+	Send(text string) error
+}
+
+// Updater is a Sink that can also show a transcript while it is still
+// being transcribed
+type Updater interface {
+	Sink
+	// Update passes the transcript so far; Send follows with the final text
+	Update(text string) error
+}
+
+// Multi sends each transcript to several sinks at once
+type Multi []Sink
+
+// Send delivers text to every sink concurrently, so a slow webhook does not
+// hold up the others, and returns their errors joined
+func (m Multi) Send(text string) error {
+	errs := make([]error, len(m))
+	var wg sync.WaitGroup
+	for i, sink := range m {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = sink.Send(text)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Update passes a transcript in progress to the sinks that are Updaters,
+// concurrently like Send
+func (m Multi) Update(text string) error {
+	var updaters Multi
+	for _, sink := range m {
+		if u, ok := sink.(Updater); ok {
+			updaters = append(updaters, sinkFunc(u.Update))
+		}
+	}
+	return updaters.Send(text)
+}
+
+// sinkFunc adapts a function to the Sink interface
+type sinkFunc func(text string) error
+
+// Send calls f
+func (f sinkFunc) Send(text string) error { return f(text) }
+
+// Format is how transcripts are written to streams and files
+type Format string
+
+const (
+	// FormatPlain writes the text followed by a line break
+	FormatPlain Format = "plain"
+	// FormatJSON writes one Record per line
+	FormatJSON Format = "json"
+)
+
+// ParseFormat validates a format name
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatPlain, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown output format %q, want plain or json", s)
+}
+
+// Record is a transcript as written in JSON lines and webhook requests
+type Record struct {
+	Time time.Time `json:"time"`
+	Text string    `json:"text"`
+}
+
+// line renders a transcript as one entry. Plain entries get the time in
+// front when stamped is set; JSON entries always carry it.
+func (f Format) line(text string, at time.Time, stamped bool) ([]byte, error) {
+	if f == FormatJSON {
+		data, err := json.Marshal(Record{Time: at, Text: text})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode transcript: %w", err)
+		}
+		return append(data, '\n'), nil
+	}
+	if stamped {
+		text = at.Format(time.RFC3339) + " " + text
+	}
+	return []byte(text + "\n"), nil
+}
@@ -0,0 +1,77 @@
+package output
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixedTime is the clock of the sinks under test
+var fixedTime = time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)
+
+func fixedNow() time.Time { return fixedTime }
+
+func TestMulti(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		got  []string
+		both = make(chan struct{})
+	)
+	record := func(name string) Sink {
+		return sinkFunc(func(text string) error {
+			mu.Lock()
+			got = append(got, name+":"+text)
+			mu.Unlock()
+			return nil
+		})
+	}
+	// blocking only returns once every sink has started, which fails the
+	// test by timing out unless the sinks run concurrently
+	var started sync.WaitGroup
+	started.Add(2)
+	blocking := func(name string) Sink {
+		return sinkFunc(func(text string) error {
+			started.Done()
+			<-both
+			return errors.New(name + " failed")
+		})
+	}
+	go func() {
+		started.Wait()
+		close(both)
+	}()
+
+	err := Multi{blocking("first"), record("log"), blocking("second")}.Send("hello")
+	if err == nil || !strings.Contains(err.Error(), "first failed") || !strings.Contains(err.Error(), "second failed") {
+		t.Errorf("Send() error = %v, want both failures", err)
+	}
+	if len(got) != 1 || got[0] != "log:hello" {
+		t.Errorf("Send() delivered %v, want [log:hello]", got)
+	}
+
+	if err := (Multi{}).Send("hello"); err != nil {
+		t.Errorf("Send() with no sinks error = %v", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{in: "plain", want: FormatPlain},
+		{in: "JSON", want: FormatJSON},
+		{in: "csv", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Typer sends keystrokes to the focused window
+type Typer interface {
+	// Type types text as if entered on the keyboard
+	Type(text string) error
+	// Key presses a single key by its X keysym name, such as Return or Tab,
+	// optionally after shift, ctrl or alt modifiers, as in shift+Return
+	Key(name string) error
+}
+
+// TypeSink types each transcript into the focused window, optionally
+// followed by a key such as Return to send a chat message
+type TypeSink struct {
+	typer Typer
+	key   string
+	// newline is pressed for each line break; empty folds line breaks into
+	// spaces
+	newline string
+}
+
+// NewTypeSink returns a sink typing with t and pressing key after each
+// transcript; an empty key presses nothing. Typed as they are, line breaks
+// would press Return and could send or run a partial transcript, so each
+// one presses newline instead, such as shift+Return, or with an empty
+// newline becomes a space.
+func NewTypeSink(t Typer, key, newline string) *TypeSink {
+	return &TypeSink{typer: t, key: key, newline: newline}
+}
+
+// Send types text line by line and presses the configured key
+func (s *TypeSink) Send(text string) error {
+	if err := s.typeLines(text); err != nil {
+		return err
+	}
+	if s.key == "" {
+		return nil
+	}
+	if err := s.typer.Key(s.key); err != nil {
+		return fmt.Errorf("failed to press %s: %w", s.key, err)
+	}
+	return nil
+}
+
+// typeLines types text without its line breaks, pressing the newline key
+// or typing a space in their place
+func (s *TypeSink) typeLines(text string) error {
+	lines := strings.Split(text, "\n")
+	if s.newline == "" {
+		var words []string
+		for _, line := range lines {
+			if line = strings.TrimSpace(line); line != "" {
+				words = append(words, line)
+			}
+		}
+		lines = []string{strings.Join(words, " ")}
+	}
+
+	for i, line := range lines {
+		if i > 0 {
+			if err := s.typer.Key(s.newline); err != nil {
+				return fmt.Errorf("failed to press %s: %w", s.newline, err)
+			}
+		}
+		if line == "" {
+			continue
+		}
+		if err := s.typer.Type(line); err != nil {
+			return fmt.Errorf("failed to type transcript: %w", err)
+		}
+	}
+	return nil
+}
+
+// Typing backends selectable with NewTyper
+const (
+	TyperAuto    = "auto"
+	TyperXdotool = "xdotool"
+	TyperYdotool = "ydotool"
+	TyperWtype   = "wtype"
+)
+
+// commandTyper types by running a command line tool
+type commandTyper struct {
+	tool     string
+	typeArgs func(text string) []string
+	// keyArgs presses key while holding mods
+	keyArgs func(mods []string, key string) ([]string, error)
+	run     func(name string, args ...string) error
+}
+
+// Type runs the tool's type command
+func (c *commandTyper) Type(text string) error {
+	return c.run(c.tool, c.typeArgs(text)...)
+}
+
+// Key runs the tool's key command
+func (c *commandTyper) Key(name string) error {
+	parts := strings.Split(name, "+")
+	mods := make([]string, 0, len(parts)-1)
+	for _, m := range parts[:len(parts)-1] {
+		m = strings.ToLower(m)
+		if _, ok := modifierKeycodes[m]; !ok {
+			return fmt.Errorf("unknown modifier %q in %q; use shift, ctrl or alt", m, name)
+		}
+		mods = append(mods, m)
+	}
+	args, err := c.keyArgs(mods, canonicalKey(parts[len(parts)-1]))
+	if err != nil {
+		return err
+	}
+	return c.run(c.tool, args...)
+}
+
+// canonicalKey accepts "enter" and any capitalization of common key names
+func canonicalKey(name string) string {
+	switch strings.ToLower(name) {
+	case "enter", "return":
+		return "Return"
+	case "tab":
+		return "Tab"
+	case "escape", "esc":
+		return "Escape"
+	case "space":
+		return "space"
+	case "backspace":
+		return "BackSpace"
+	}
+	return name
+}
+
+// ydotoolKeycodes are the Linux input keycodes of the keys ydotool can press
+// by name; ydotool itself only understands keycodes
+var ydotoolKeycodes = map[string]int{
+	"Return":    28,
+	"Tab":       15,
+	"Escape":    1,
+	"space":     57,
+	"BackSpace": 14,
+}
+
+// modifierKeycodes are the modifiers a key name may start with, as in
+// shift+Return, with their Linux input keycodes
+var modifierKeycodes = map[string]int{
+	"shift": 42,
+	"ctrl":  29,
+	"alt":   56,
+}
+
+func newXdotool(run func(string, ...string) error) Typer {
+	return &commandTyper{
+		tool: TyperXdotool,
+		// --clearmodifiers releases keys still held from a hotkey
+		typeArgs: func(text string) []string { return []string{"type", "--clearmodifiers", "--", text} },
+		keyArgs: func(mods []string, key string) ([]string, error) {
+			return []string{"key", "--clearmodifiers", strings.Join(append(mods, key), "+")}, nil
+		},
+		run: run,
+	}
+}
+
+func newYdotool(run func(string, ...string) error) Typer {
+	return &commandTyper{
+		tool:     TyperYdotool,
+		typeArgs: func(text string) []string { return []string{"type", "--", text} },
+		keyArgs: func(mods []string, key string) ([]string, error) {
+			code, ok := ydotoolKeycodes[key]
+			if !ok {
+				return nil, fmt.Errorf("ydotool cannot press %q; use Return, Tab, Escape, space or BackSpace", key)
+			}
+			// Modifiers go down first and come up last
+			args := []string{"key"}
+			for _, m := range mods {
+				args = append(args, fmt.Sprintf("%d:1", modifierKeycodes[m]))
+			}
+			args = append(args, fmt.Sprintf("%d:1", code), fmt.Sprintf("%d:0", code))
+			for i := len(mods) - 1; i >= 0; i-- {
+				args = append(args, fmt.Sprintf("%d:0", modifierKeycodes[mods[i]]))
+			}
+			return args, nil
+		},
+		run: run,
+	}
+}
+
+func newWtype(run func(string, ...string) error) Typer {
+	return &commandTyper{
+		tool:     TyperWtype,
+		typeArgs: func(text string) []string { return []string{"--", text} },
+		keyArgs: func(mods []string, key string) ([]string, error) {
+			var args []string
+			for _, m := range mods {
+				args = append(args, "-M", m)
+			}
+			args = append(args, "-k", key)
+			for i := len(mods) - 1; i >= 0; i-- {
+				args = append(args, "-m", mods[i])
+			}
+			return args, nil
+		},
+		run: run,
+	}
+}
+
+var typers = map[string]func(run func(string, ...string) error) Typer{
+	TyperXdotool: newXdotool,
+	TyperYdotool: newYdotool,
+	TyperWtype:   newWtype,
+}
+
+// NewTyper returns the named typing backend, or with TyperAuto the one that
+// suits the session
+func NewTyper(backend string) (Typer, error) {
+	return newTyper(backend, os.Getenv, exec.LookPath, runTool)
+}
+
+func newTyper(backend string, getenv func(string) string, lookPath func(string) (string, error), run func(string, ...string) error) (Typer, error) {
+	if backend == "" || backend == TyperAuto {
+		var err error
+		if backend, err = detectTyper(getenv, lookPath); err != nil {
+			return nil, err
+		}
+	}
+
+	build, ok := typers[backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown typing backend %q, want auto, xdotool, ydotool or wtype", backend)
+	}
+	if _, err := lookPath(backend); err != nil {
+		return nil, fmt.Errorf("typing backend %s is not installed: %w", backend, err)
+	}
+	return build(run), nil
+}
+
+// detectTyper picks the first installed tool for the session: wtype, then
+// ydotool under Wayland; xdotool under X11; ydotool, which types through
+// the kernel, anywhere else
+func detectTyper(getenv func(string) string, lookPath func(string) (string, error)) (string, error) {
+	var candidates []string
+	switch {
+	case getenv("WAYLAND_DISPLAY") != "":
+		candidates = []string{TyperWtype, TyperYdotool}
+	case getenv("DISPLAY") != "":
+		candidates = []string{TyperXdotool}
+	default:
+		candidates = []string{TyperYdotool}
+	}
+
+	for _, tool := range candidates {
+		if _, err := lookPath(tool); err == nil {
+			return tool, nil
+		}
+	}
+	return "", fmt.Errorf("no typing tool found; install %s", strings.Join(candidates, " or "))
+}
+
+// runTool runs a typing tool and includes its output in errors
+func runTool(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// RecordingTyper records what would have been typed, for testing
+type RecordingTyper struct {
+	// Events holds typed text and, prefixed with "key:", pressed keys
+	Events []string
+	err    error
+}
+
+// Type records text
+func (r *RecordingTyper) Type(text string) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.Events = append(r.Events, text)
+	return nil
+}
+
+// Key records a key press
+func (r *RecordingTyper) Key(name string) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.Events = append(r.Events, "key:"+name)
+	return nil
+}
+
+// SetError sets an error to be returned by Type and Key
+func (r *RecordingTyper) SetError(err error) {
+	r.err = err
+}
@@ -0,0 +1,207 @@
+package output
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordRun records the command lines a typer would run
+func recordRun(calls *[][]string) func(string, ...string) error {
+	return func(name string, args ...string) error {
+		*calls = append(*calls, append([]string{name}, args...))
+		return nil
+	}
+}
+
+// installed reports the listed tools as found on PATH
+func installed(tools ...string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		for _, t := range tools {
+			if t == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", exec.ErrNotFound
+	}
+}
+
+func TestTyperCommands(t *testing.T) {
+	tests := []struct {
+		backend string
+		key     string
+		want    [][]string
+	}{
+		{
+			backend: TyperXdotool,
+			key:     "enter",
+			want: [][]string{
+				{"xdotool", "type", "--clearmodifiers", "--", "-n hello"},
+				{"xdotool", "key", "--clearmodifiers", "Return"},
+			},
+		},
+		{
+			backend: TyperYdotool,
+			key:     "Tab",
+			want: [][]string{
+				{"ydotool", "type", "--", "-n hello"},
+				{"ydotool", "key", "15:1", "15:0"},
+			},
+		},
+		{
+			backend: TyperWtype,
+			key:     "Return",
+			want: [][]string{
+				{"wtype", "--", "-n hello"},
+				{"wtype", "-k", "Return"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			var calls [][]string
+			typer, err := newTyper(tt.backend, func(string) string { return "" }, installed(tt.backend), recordRun(&calls))
+			if err != nil {
+				t.Fatalf("newTyper() error = %v", err)
+			}
+			if err := NewTypeSink(typer, tt.key, "").Send("-n hello"); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("commands = %q, want %q", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestTyperModifiers(t *testing.T) {
+	tests := []struct {
+		backend string
+		want    []string
+	}{
+		{backend: TyperXdotool, want: []string{"xdotool", "key", "--clearmodifiers", "shift+Return"}},
+		{backend: TyperYdotool, want: []string{"ydotool", "key", "42:1", "28:1", "28:0", "42:0"}},
+		{backend: TyperWtype, want: []string{"wtype", "-M", "shift", "-k", "Return", "-m", "shift"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			var calls [][]string
+			typer := typers[tt.backend](recordRun(&calls))
+			if err := typer.Key("Shift+enter"); err != nil {
+				t.Fatalf("Key() error = %v", err)
+			}
+			if len(calls) != 1 || !reflect.DeepEqual(calls[0], tt.want) {
+				t.Errorf("commands = %q, want %q", calls, tt.want)
+			}
+			if err := typer.Key("hyper+Return"); err == nil || !strings.Contains(err.Error(), "unknown modifier") {
+				t.Errorf("Key(hyper+Return) error = %v, want unknown modifier", err)
+			}
+		})
+	}
+}
+
+func TestYdotoolUnknownKey(t *testing.T) {
+	var calls [][]string
+	typer := newYdotool(recordRun(&calls))
+	if err := typer.Key("F13"); err == nil {
+		t.Error("Key(F13) error = nil, want error")
+	}
+	if len(calls) != 0 {
+		t.Errorf("ran %q, want nothing", calls)
+	}
+}
+
+func TestNewTyper_Detection(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		tools   []string
+		want    string
+		wantErr string
+	}{
+		{name: "wayland prefers wtype", env: map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"}, tools: []string{"wtype", "ydotool", "xdotool"}, want: "wtype"},
+		{name: "wayland falls back to ydotool", env: map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, tools: []string{"ydotool"}, want: "ydotool"},
+		{name: "x11", env: map[string]string{"DISPLAY": ":0"}, tools: []string{"xdotool", "ydotool"}, want: "xdotool"},
+		{name: "console", tools: []string{"ydotool", "xdotool"}, want: "ydotool"},
+		{name: "nothing installed", env: map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, wantErr: "install wtype or ydotool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls [][]string
+			getenv := func(k string) string { return tt.env[k] }
+			typer, err := newTyper(TyperAuto, getenv, installed(tt.tools...), recordRun(&calls))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("newTyper() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newTyper() error = %v", err)
+			}
+
+			if err := typer.Type("x"); err != nil {
+				t.Fatal(err)
+			}
+			if calls[0][0] != tt.want {
+				t.Errorf("newTyper() chose %s, want %s", calls[0][0], tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTyper_Errors(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	run := func(string, ...string) error { return nil }
+
+	if _, err := newTyper("xdotool", noEnv, installed(), run); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("newTyper(xdotool) error = %v, want not installed", err)
+	}
+	if _, err := newTyper("keyboard", noEnv, installed("keyboard"), run); err == nil || !strings.Contains(err.Error(), "unknown typing backend") {
+		t.Errorf("newTyper(keyboard) error = %v, want unknown backend", err)
+	}
+}
+
+func TestTypeSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		key     string
+		newline string
+		typeErr error
+		want    []string
+		wantErr bool
+	}{
+		{name: "text only", text: "hello world", want: []string{"hello world"}},
+		{name: "text and key", text: "hello world", key: "Return", want: []string{"hello world", "key:Return"}},
+		{name: "typing fails", text: "hello world", key: "Return", typeErr: errors.New("no display"), wantErr: true},
+		{name: "line breaks become spaces", text: "Dear team,\n\nhello ", key: "Return", want: []string{"Dear team, hello", "key:Return"}},
+		{
+			name:    "line breaks press the newline key",
+			text:    "Dear team,\n\nhello",
+			key:     "Return",
+			newline: "shift+Return",
+			want:    []string{"Dear team,", "key:shift+Return", "key:shift+Return", "hello", "key:Return"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typer := &RecordingTyper{}
+			typer.SetError(tt.typeErr)
+
+			err := NewTypeSink(typer, tt.key, tt.newline).Send(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(typer.Events, tt.want) {
+				t.Errorf("Events = %q, want %q", typer.Events, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultWebhookTimeout bounds a webhook request, so an unreachable server
+// does not pile up requests
+const DefaultWebhookTimeout = 10 * time.Second
+
+// WebhookSink posts each transcript as a JSON Record to a URL
+type WebhookSink struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhookSink returns a sink posting to url
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: DefaultWebhookTimeout},
+		now:    time.Now,
+	}
+}
+
+// Send posts text and fails unless the server answers with a 2xx status
+func (s *WebhookSink) Send(text string) error {
+	body, err := json.Marshal(Record{Time: s.now(), Text: text})
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post transcript: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package output
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var got Record
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL)
+	sink.now = fixedNow
+	if err := sink.Send("ship it"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got.Text != "ship it" || !got.Time.Equal(fixedTime) {
+		t.Errorf("posted %+v, want ship it at %v", got, fixedTime)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+}
+
+func TestWebhookSink_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid token", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewWebhookSink(server.URL).Send("hello")
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "invalid token") {
+		t.Errorf("Send() error = %v, want the status and message", err)
+	}
+
+	server.Close()
+	if err := NewWebhookSink(server.URL).Send("hello"); err == nil || !strings.Contains(err.Error(), "failed to post transcript") {
+		t.Errorf("Send() to a closed server error = %v, want a post error", err)
+	}
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// WriterSink writes each transcript as a line to a stream such as stdout
+type WriterSink struct {
+	w      io.Writer
+	format Format
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewWriterSink returns a sink writing plain lines or JSON lines to w
+func NewWriterSink(w io.Writer, format Format) *WriterSink {
+	return &WriterSink{w: w, format: format, now: time.Now}
+}
+
+// Send writes text as one line
+func (s *WriterSink) Send(text string) error {
+	line, err := s.format.line(text, s.now(), false)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(line); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
+
+// FileSink appends each transcript with its time to a file
+type FileSink struct {
+	path   string
+	format Format
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewFileSink returns a sink appending to path, which is created if needed
+func NewFileSink(path string, format Format) *FileSink {
+	return &FileSink{path: path, format: format, now: time.Now}
+}
+
+// Send appends text to the file. The file is opened for every transcript so
+// it can be moved or rotated while the program runs.
+func (s *FileSink) Send(text string) error {
+	line, err := s.format.line(text, s.now(), true)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write to %s: %w", s.path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write to %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// FIFOSink writes each transcript to a named pipe for another program to
+// read
+type FIFOSink struct {
+	path   string
+	format Format
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewFIFOSink returns a sink writing to the named pipe at path, as made by
+// mkfifo
+func NewFIFOSink(path string, format Format) *FIFOSink {
+	return &FIFOSink{path: path, format: format, now: time.Now}
+}
+
+// Send writes text to the pipe. It fails instead of waiting when no program
+// has the pipe open for reading.
+func (s *FIFOSink) Send(text string) error {
+	line, err := s.format.line(text, s.now(), false)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to open named pipe: %w", err)
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		return fmt.Errorf("%s is not a named pipe; create it with mkfifo", s.path)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, syscall.ENXIO) {
+		return fmt.Errorf("no program is reading from %s", s.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open named pipe: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("failed to write to %s: %w", s.path, err)
+	}
+	return nil
+}
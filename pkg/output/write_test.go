@@ -0,0 +1,77 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriterSink(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{format: FormatPlain, want: "first\nsecond line\n"},
+		{format: FormatJSON, want: `{"time":"2026-03-14T09:26:53Z","text":"first"}` + "\n" + `{"time":"2026-03-14T09:26:53Z","text":"second line"}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		sink := NewWriterSink(&buf, tt.format)
+		sink.now = fixedNow
+		for _, text := range []string{"first", "second line"} {
+			if err := sink.Send(text); err != nil {
+				t.Fatalf("%s: Send() error = %v", tt.format, err)
+			}
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s: wrote %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("earlier notes\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := NewFileSink(path, FormatPlain)
+	sink.now = fixedNow
+	for _, text := range []string{"buy milk", "call Sam"} {
+		if err := sink.Send(text); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "earlier notes\n2026-03-14T09:26:53Z buy milk\n2026-03-14T09:26:53Z call Sam\n"
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+
+	missing := NewFileSink(filepath.Join(t.TempDir(), "no", "such", "dir", "notes.txt"), FormatPlain)
+	if err := missing.Send("lost"); err == nil || !strings.Contains(err.Error(), "failed to open output file") {
+		t.Errorf("Send() error = %v, want an open error", err)
+	}
+}
+
+func TestFIFOSink_NotAPipe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "regular")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := NewFIFOSink(path, FormatPlain).Send("hello")
+	if err == nil || !strings.Contains(err.Error(), "not a named pipe") {
+		t.Errorf("Send() error = %v, want not a named pipe", err)
+	}
+	if err := NewFIFOSink(path+".missing", FormatPlain).Send("hello"); err == nil {
+		t.Error("Send() to a missing pipe succeeded")
+	}
+}
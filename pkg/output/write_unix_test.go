@@ -0,0 +1,41 @@
+//go:build unix
+
+package output
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestFIFOSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcripts")
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sink := NewFIFOSink(path, FormatPlain)
+
+	if err := sink.Send("nobody listens"); err == nil || !strings.Contains(err.Error(), "no program is reading") {
+		t.Errorf("Send() without a reader error = %v, want no program is reading", err)
+	}
+
+	reader, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	if err := sink.Send("hello pipe"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	line, err := bufio.NewReader(reader).ReadString('\n')
+	if err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+	if line != "hello pipe\n" {
+		t.Errorf("read %q, want %q", line, "hello pipe\n")
+	}
+}
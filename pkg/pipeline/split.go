@@ -0,0 +1,136 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"speech-to-clipboard/pkg/audio"
+	"speech-to-clipboard/pkg/stt"
+)
+
+// DefaultMaxUploadBytes keeps uploads under the OpenAI API's 25 MB file
+// limit with room for the multipart envelope
+const DefaultMaxUploadBytes = 24 << 20
+
+// promptTailChars is how much of the previous segment's text is passed on
+// as the prompt for the next one
+const promptTailChars = 200
+
+// SplitConfig controls how a finished recording is transcribed
+type SplitConfig struct {
+	// MaxBytes is the largest upload allowed; longer recordings are split.
+	// Zero disables splitting.
+	MaxBytes int
+	// Concurrency above one transcribes segments in parallel, at the cost of
+	// prompting each with the previous segment's text
+	Concurrency int
+	// Timeout bounds each segment's transcription; zero means no extra limit
+	Timeout time.Duration
+	// Encoder converts segments to the upload format; defaults to WAV
+	Encoder audio.Encoder
+}
+
+// DefaultSplitConfig returns the settings used by the application
+func DefaultSplitConfig() SplitConfig {
+	return SplitConfig{
+		MaxBytes:    DefaultMaxUploadBytes,
+		Concurrency: 1,
+		Timeout:     30 * time.Second,
+		Encoder:     audio.WAVEncoder{},
+	}
+}
+
+// maxSamples is the longest segment whose uncompressed 16-bit audio fits in
+// MaxBytes with 1% left for container overhead. Compressed encodings only
+// come out smaller.
+func (cfg SplitConfig) maxSamples() int {
+	if cfg.MaxBytes <= 0 {
+		return 0
+	}
+	return cfg.MaxBytes / 100 * 99 / 2
+}
+
+// TranscribeRecording transcribes a finished recording. One whose upload
+// would exceed cfg.MaxBytes is split at pauses and the segment texts are
+// joined in order. Segments run one after another, each prompted with the
+// end of the previous segment's text so sentences carry across the cut,
+// unless cfg.Concurrency allows them to run in parallel.
+func TranscribeRecording(ctx context.Context, t stt.Transcriber, samples []int16, cfg SplitConfig) (string, error) {
+	if cfg.Encoder == nil {
+		cfg.Encoder = audio.WAVEncoder{}
+	}
+	streamCfg := StreamConfig{Concurrency: cfg.Concurrency, Timeout: cfg.Timeout, Encoder: cfg.Encoder}
+
+	pieces := audio.SplitAtSilence(samples, cfg.maxSamples(), audio.SampleRate)
+	if len(pieces) == 1 {
+		segment := transcribeChunk(ctx, t, 0, pieces[0], streamCfg)
+		return strings.TrimSpace(segment.Text), segment.Err
+	}
+
+	if cfg.Concurrency > 1 {
+		return transcribeParallel(ctx, t, pieces, streamCfg)
+	}
+
+	var texts []string
+	for i, piece := range pieces {
+		segmentCtx := ctx
+		if len(texts) > 0 {
+			segmentCtx = stt.WithPrompt(ctx, promptTail(texts[len(texts)-1]))
+		}
+
+		segment := transcribeChunk(segmentCtx, t, i, piece, streamCfg)
+		if segment.Err != nil {
+			return "", fmt.Errorf("segment %d of %d: %w", i+1, len(pieces), segment.Err)
+		}
+		if text := strings.TrimSpace(segment.Text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, " "), nil
+}
+
+// transcribeParallel transcribes pieces through TranscribeStream and joins
+// the results, failing if any segment failed
+func transcribeParallel(ctx context.Context, t stt.Transcriber, pieces [][]int16, cfg StreamConfig) (string, error) {
+	chunks := make(chan []int16, len(pieces))
+	for _, piece := range pieces {
+		chunks <- piece
+	}
+	close(chunks)
+
+	var texts []string
+	var firstErr error
+	for segment := range TranscribeStream(ctx, t, chunks, cfg) {
+		if segment.Err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("segment %d of %d: %w", segment.Index+1, len(pieces), segment.Err)
+			}
+			continue
+		}
+		if text := strings.TrimSpace(segment.Text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return strings.Join(texts, " "), nil
+}
+
+// promptTail returns roughly the last promptTailChars characters of text,
+// starting at a word boundary
+func promptTail(text string) string {
+	runes := []rune(text)
+	if len(runes) <= promptTailChars {
+		return text
+	}
+
+	tail := string(runes[len(runes)-promptTailChars:])
+	if i := strings.IndexByte(tail, ' '); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return tail
+}
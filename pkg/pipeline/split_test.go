@@ -0,0 +1,165 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"speech-to-clipboard/pkg/audio"
+	"speech-to-clipboard/pkg/stt"
+)
+
+// peakEncoder writes the loudest sample of a segment as decimal text
+type peakEncoder struct{}
+
+func (peakEncoder) Encode(data []int16, format audio.Format, w io.Writer) error {
+	var peak int16
+	for _, s := range data {
+		if s > peak {
+			peak = s
+		}
+	}
+	_, err := fmt.Fprint(w, peak)
+	return err
+}
+
+func (peakEncoder) Extension() string   { return "txt" }
+func (peakEncoder) ContentType() string { return "text/plain" }
+
+// promptRecorder answers with "v" and the encoded peak, remembering the
+// prompt each request carried, and fails for the peak in failOn
+type promptRecorder struct {
+	mu      sync.Mutex
+	prompts map[string]string
+	failOn  string
+}
+
+func (p *promptRecorder) Transcribe(ctx context.Context, audioData io.Reader) (string, error) {
+	data, _ := io.ReadAll(audioData)
+	text := "v" + string(data)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.prompts == nil {
+		p.prompts = make(map[string]string)
+	}
+	p.prompts[text] = stt.PromptFromContext(ctx)
+
+	if string(data) == p.failOn {
+		return "", stt.ErrRateLimited
+	}
+	return text, nil
+}
+
+// threePhrases is 16000 samples: three loud phrases of 300 ms with
+// different levels, separated by 50 ms pauses
+func threePhrases() []int16 {
+	var recording []int16
+	for i, level := range []int16{1000, 2000, 3000} {
+		if i > 0 {
+			recording = append(recording, make([]int16, 800)...)
+		}
+		for j := 0; j < 4800; j++ {
+			recording = append(recording, level)
+		}
+	}
+	return recording
+}
+
+// splitTestConfig allows about 8000 samples per segment
+func splitTestConfig(concurrency int) SplitConfig {
+	return SplitConfig{MaxBytes: 16200, Concurrency: concurrency, Encoder: peakEncoder{}}
+}
+
+func TestTranscribeRecording_Sequential(t *testing.T) {
+	recorder := &promptRecorder{}
+	text, err := TranscribeRecording(context.Background(), recorder, threePhrases(), splitTestConfig(1))
+	if err != nil {
+		t.Fatalf("TranscribeRecording() error = %v", err)
+	}
+
+	if text != "v1000 v2000 v3000" {
+		t.Errorf("TranscribeRecording() = %q, want %q", text, "v1000 v2000 v3000")
+	}
+
+	wantPrompts := map[string]string{"v1000": "", "v2000": "v1000", "v3000": "v2000"}
+	for segment, want := range wantPrompts {
+		if got := recorder.prompts[segment]; got != want {
+			t.Errorf("prompt for %s = %q, want %q", segment, got, want)
+		}
+	}
+}
+
+func TestTranscribeRecording_Parallel(t *testing.T) {
+	recorder := &promptRecorder{}
+	text, err := TranscribeRecording(context.Background(), recorder, threePhrases(), splitTestConfig(3))
+	if err != nil {
+		t.Fatalf("TranscribeRecording() error = %v", err)
+	}
+
+	if text != "v1000 v2000 v3000" {
+		t.Errorf("TranscribeRecording() = %q, want %q", text, "v1000 v2000 v3000")
+	}
+	for segment, prompt := range recorder.prompts {
+		if prompt != "" {
+			t.Errorf("prompt for %s = %q, want none in parallel mode", segment, prompt)
+		}
+	}
+}
+
+func TestTranscribeRecording_Fits(t *testing.T) {
+	recorder := &promptRecorder{}
+	cfg := splitTestConfig(1)
+	cfg.MaxBytes = DefaultMaxUploadBytes
+
+	text, err := TranscribeRecording(context.Background(), recorder, threePhrases(), cfg)
+	if err != nil {
+		t.Fatalf("TranscribeRecording() error = %v", err)
+	}
+	if text != "v3000" {
+		t.Errorf("TranscribeRecording() = %q, want a single segment %q", text, "v3000")
+	}
+}
+
+func TestTranscribeRecording_SegmentError(t *testing.T) {
+	for _, concurrency := range []int{1, 3} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			recorder := &promptRecorder{failOn: "2000"}
+			_, err := TranscribeRecording(context.Background(), recorder, threePhrases(), splitTestConfig(concurrency))
+			if err == nil {
+				t.Fatal("TranscribeRecording() error = nil, want error")
+			}
+			if !strings.Contains(err.Error(), "segment 2 of 3") {
+				t.Errorf("error = %v, want it to name segment 2 of 3", err)
+			}
+			if !errors.Is(err, stt.ErrRateLimited) {
+				t.Errorf("errors.Is(err, ErrRateLimited) = false for %v", err)
+			}
+		})
+	}
+}
+
+func TestPromptTail(t *testing.T) {
+	long := strings.Repeat("word ", 60) + "end"
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "short text kept", text: "hello there", want: "hello there"},
+		{name: "long text starts at a word", text: long, want: strings.Repeat("word ", 39) + "end"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promptTail(tt.text); got != tt.want {
+				t.Errorf("promptTail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Package pipeline connects audio capture to transcription for recordings
+// that are processed in pieces rather than as a single upload.
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"speech-to-clipboard/pkg/audio"
+	"speech-to-clipboard/pkg/stt"
+)
+
+// Segment is the transcript of one chunk of audio
+type Segment struct {
+	// Index is the position of the chunk in the recording, starting at 0
+	Index int
+	Text  string
+	Err   error
+}
+
+// StreamConfig controls how chunks are transcribed
+type StreamConfig struct {
+	// Concurrency is the number of chunks transcribed at the same time
+	Concurrency int
+	// Timeout bounds each chunk's transcription; zero means no extra limit
+	Timeout time.Duration
+	// Encoder converts a chunk to the upload format; defaults to WAV
+	Encoder audio.Encoder
+}
+
+// DefaultStreamConfig returns the settings used by the application
+func DefaultStreamConfig() StreamConfig {
+	return StreamConfig{
+		Concurrency: 2,
+		Timeout:     30 * time.Second,
+		Encoder:     audio.WAVEncoder{},
+	}
+}
+
+// TranscribeStream transcribes chunks as they arrive, up to cfg.Concurrency at
+// a time, and delivers the segments in chunk order. The returned channel is
+// closed once chunks is closed and every segment has been delivered.
+func TranscribeStream(ctx context.Context, t stt.Transcriber, chunks <-chan []int16, cfg StreamConfig) <-chan Segment {
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Encoder == nil {
+		cfg.Encoder = audio.WAVEncoder{}
+	}
+
+	// Each chunk gets its own result channel; queuing those channels in chunk
+	// order lets the collector emit in order while workers finish in any order
+	pending := make(chan chan Segment, cfg.Concurrency)
+	slots := make(chan struct{}, cfg.Concurrency)
+	out := make(chan Segment)
+
+	go func() {
+		defer close(pending)
+
+		index := 0
+		for chunk := range chunks {
+			result := make(chan Segment, 1)
+			pending <- result
+
+			slots <- struct{}{}
+			go func(index int, chunk []int16) {
+				defer func() { <-slots }()
+				result <- transcribeChunk(ctx, t, index, chunk, cfg)
+			}(index, chunk)
+			index++
+		}
+	}()
+
+	go func() {
+		defer close(out)
+
+		for result := range pending {
+			out <- <-result
+		}
+	}()
+
+	return out
+}
+
+func transcribeChunk(ctx context.Context, t stt.Transcriber, index int, chunk []int16, cfg StreamConfig) Segment {
+	segment := Segment{Index: index}
+
+	file, err := EncodeAudio(cfg.Encoder, chunk)
+	if err != nil {
+		segment.Err = fmt.Errorf("failed to encode chunk %d: %w", index, err)
+		return segment
+	}
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	segment.Text, segment.Err = t.Transcribe(ctx, file)
+	return segment
+}
+
+// EncodeAudio encodes captured audio for upload, labelled with the
+// encoder's file name and MIME type
+func EncodeAudio(enc audio.Encoder, data []int16) (stt.AudioFile, error) {
+	buf := new(bytes.Buffer)
+	if err := enc.Encode(data, audio.TargetFormat(), buf); err != nil {
+		return nil, err
+	}
+	return stt.NewAudioFile(buf, "audio."+enc.Extension(), enc.ContentType()), nil
+}
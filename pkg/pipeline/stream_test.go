@@ -0,0 +1,143 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"speech-to-clipboard/pkg/audio"
+	"speech-to-clipboard/pkg/stt"
+)
+
+// echoTranscriber returns the first byte of the encoded chunk as text, taking
+// longer for earlier chunks so that completions arrive out of order
+type echoTranscriber struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	failOn   byte
+}
+
+func (e *echoTranscriber) Transcribe(ctx context.Context, audioData io.Reader) (string, error) {
+	e.mu.Lock()
+	e.inFlight++
+	if e.inFlight > e.peak {
+		e.peak = e.inFlight
+	}
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.inFlight--
+		e.mu.Unlock()
+	}()
+
+	data, _ := io.ReadAll(audioData)
+	id := data[0]
+	time.Sleep(time.Duration(10-id) * 3 * time.Millisecond)
+
+	if id == e.failOn {
+		return "", errors.New("chunk failed")
+	}
+	return fmt.Sprintf("chunk-%d", id), nil
+}
+
+// firstSampleEncoder writes only the low byte of the first sample, or fails
+// with err when it is set
+type firstSampleEncoder struct {
+	err error
+}
+
+func (e firstSampleEncoder) Encode(data []int16, format audio.Format, w io.Writer) error {
+	if e.err != nil {
+		return e.err
+	}
+	_, err := w.Write([]byte{byte(data[0])})
+	return err
+}
+
+func (firstSampleEncoder) Extension() string   { return "raw" }
+func (firstSampleEncoder) ContentType() string { return "application/octet-stream" }
+
+func feed(n int) <-chan []int16 {
+	chunks := make(chan []int16)
+	go func() {
+		defer close(chunks)
+		for i := 0; i < n; i++ {
+			chunks <- []int16{int16(i)}
+		}
+	}()
+	return chunks
+}
+
+func TestTranscribeStream_Ordered(t *testing.T) {
+	transcriber := &echoTranscriber{failOn: 255}
+	cfg := StreamConfig{Concurrency: 3, Encoder: firstSampleEncoder{}}
+
+	var got []Segment
+	for segment := range TranscribeStream(context.Background(), transcriber, feed(6), cfg) {
+		got = append(got, segment)
+	}
+
+	if len(got) != 6 {
+		t.Fatalf("TranscribeStream() produced %d segments, want 6", len(got))
+	}
+
+	for i, segment := range got {
+		if segment.Index != i {
+			t.Errorf("segment %d Index = %d", i, segment.Index)
+		}
+		if segment.Err != nil {
+			t.Errorf("segment %d Err = %v", i, segment.Err)
+		}
+		if want := fmt.Sprintf("chunk-%d", i); segment.Text != want {
+			t.Errorf("segment %d Text = %q, want %q", i, segment.Text, want)
+		}
+	}
+
+	if transcriber.peak > 3 {
+		t.Errorf("peak concurrency = %d, want at most 3", transcriber.peak)
+	}
+}
+
+func TestTranscribeStream_SegmentErrors(t *testing.T) {
+	transcriber := &echoTranscriber{failOn: 1}
+	cfg := StreamConfig{Concurrency: 2, Encoder: firstSampleEncoder{}}
+
+	var got []Segment
+	for segment := range TranscribeStream(context.Background(), transcriber, feed(3), cfg) {
+		got = append(got, segment)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("TranscribeStream() produced %d segments, want 3", len(got))
+	}
+
+	if got[1].Err == nil {
+		t.Error("segment 1 Err = nil, want error")
+	}
+
+	if got[0].Err != nil || got[2].Err != nil {
+		t.Errorf("unexpected errors in neighbouring segments: %v, %v", got[0].Err, got[2].Err)
+	}
+}
+
+func TestTranscribeStream_EncodeError(t *testing.T) {
+	cfg := StreamConfig{
+		Concurrency: 1,
+		Encoder:     firstSampleEncoder{err: errors.New("encoder broke")},
+	}
+
+	segments := TranscribeStream(context.Background(), stt.NewMockTranscriber("unused", nil), feed(1), cfg)
+	segment := <-segments
+	if segment.Err == nil {
+		t.Error("segment Err = nil, want encode error")
+	}
+
+	if _, ok := <-segments; ok {
+		t.Error("channel not closed after last segment")
+	}
+}
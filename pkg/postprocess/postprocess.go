@@ -0,0 +1,429 @@
+// Package postprocess cleans up transcripts before they reach the clipboard:
+// whitespace normalization, a replacement dictionary, filler-word removal,
+// capitalization and profanity masking, combined into a chain.
+package postprocess
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Processor transforms a transcript
+type Processor interface {
+	Process(text string) string
+}
+
+// Func adapts a function to the Processor interface
+type Func func(text string) string
+
+// Process calls f
+func (f Func) Process(text string) string {
+	return f(text)
+}
+
+// Chain runs processors in order, each on the output of the one before
+type Chain []Processor
+
+// Process runs the chain
+func (c Chain) Process(text string) string {
+	for _, p := range c {
+		text = p.Process(text)
+	}
+	return text
+}
+
+// Options selects the steps of the standard chain
+type Options struct {
+	Replacements  []Replacement
+	RemoveFillers bool
+	Fillers       []string // DefaultFillers when empty
+	// AggressiveFillers also removes AggressiveFillers, which are sometimes
+	// real words
+	AggressiveFillers   bool
+	MaskProfanity       bool
+	ProfanityWords      []string // DefaultProfanity when empty
+	NormalizeWhitespace bool
+	Case                Case
+}
+
+// New builds the standard chain: replacements, filler removal and profanity
+// masking first, then whitespace cleanup of what they leave behind, and
+// capitalization last so it sees the final sentences
+func New(opts Options) Chain {
+	var chain Chain
+	if len(opts.Replacements) > 0 {
+		chain = append(chain, Replace(opts.Replacements))
+	}
+	if opts.RemoveFillers {
+		fillers := opts.Fillers
+		if len(fillers) == 0 {
+			fillers = DefaultFillers
+		}
+		if opts.AggressiveFillers {
+			fillers = append(fillers[:len(fillers):len(fillers)], AggressiveFillers...)
+		}
+		chain = append(chain, RemoveFillers(fillers))
+	}
+	if opts.MaskProfanity {
+		words := opts.ProfanityWords
+		if len(words) == 0 {
+			words = DefaultProfanity
+		}
+		chain = append(chain, MaskProfanity(words))
+	}
+	if opts.NormalizeWhitespace {
+		chain = append(chain, NormalizeWhitespace())
+	}
+	if opts.Case != CaseUnchanged {
+		chain = append(chain, Capitalize(opts.Case))
+	}
+	return chain
+}
+
+var (
+	horizontalSpace   = regexp.MustCompile(`[ \t\p{Zs}]+`)
+	spaceAroundBreak  = regexp.MustCompile(` *\n *`)
+	spaceBeforePunct  = regexp.MustCompile(` +([,.;:!?)\]}])`)
+	spaceAfterOpening = regexp.MustCompile(`([(\[{]) +`)
+	repeatedComma     = regexp.MustCompile(`,(\s*,)+`)
+)
+
+// NormalizeWhitespace collapses runs of spaces, drops spaces before
+// punctuation and around line breaks, and trims the ends
+func NormalizeWhitespace() Processor {
+	return Func(func(text string) string {
+		text = horizontalSpace.ReplaceAllString(text, " ")
+		text = spaceAroundBreak.ReplaceAllString(text, "\n")
+		text = spaceBeforePunct.ReplaceAllString(text, "$1")
+		text = spaceAfterOpening.ReplaceAllString(text, "$1")
+		text = repeatedComma.ReplaceAllString(text, ",")
+		return strings.TrimSpace(text)
+	})
+}
+
+// Replacement swaps a spoken phrase for text, such as "new line" for "\n"
+type Replacement struct {
+	From string
+	To   string
+}
+
+// Replace substitutes whole-word phrases, ignoring case. Longer phrases are
+// tried first so "new paragraph" is not consumed by a shorter "new" entry.
+// Punctuation the model put right after a phrase is dropped along with it
+// when the replacement is itself punctuation or a line break.
+func Replace(replacements []Replacement) Processor {
+	if len(replacements) == 0 {
+		return Func(func(text string) string { return text })
+	}
+
+	sorted := append([]Replacement(nil), replacements...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].From) > len(sorted[j].From) })
+
+	alternatives := make([]string, len(sorted))
+	to := make(map[string]string, len(sorted))
+	for i, r := range sorted {
+		alternatives[i] = phrasePattern(r.From)
+		to[normalizePhrase(r.From)] = r.To
+	}
+	re := regexp.MustCompile(`(?i)(` + strings.Join(alternatives, "|") + `)([,.;:!?]?)`)
+
+	return Func(func(text string) string {
+		var b strings.Builder
+		last := 0
+		for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+			phrase, trailing := text[m[2]:m[3]], text[m[4]:m[5]]
+
+			replacement := to[normalizePhrase(phrase)]
+			if !isPunctuationOrBreak(replacement) {
+				replacement += trailing
+			}
+			b.WriteString(text[last:m[0]])
+			b.WriteString(replacement)
+			last = m[1]
+		}
+		b.WriteString(text[last:])
+		return b.String()
+	})
+}
+
+// phrasePattern matches a phrase with any whitespace between its words.
+// Word boundaries are only required on sides that end in a word character,
+// so phrases such as "c++" match too.
+func phrasePattern(phrase string) string {
+	words := strings.Fields(phrase)
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	pattern := strings.Join(words, `\s+`)
+
+	if first, _ := utf8.DecodeRuneInString(phrase); isWordRune(first) {
+		pattern = `\b` + pattern
+	}
+	if last, _ := utf8.DecodeLastRuneInString(phrase); isWordRune(last) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+// isWordRune reports whether \b treats r as part of a word
+func isWordRune(r rune) bool {
+	return r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
+
+func normalizePhrase(phrase string) string {
+	return strings.ToLower(strings.Join(strings.Fields(phrase), " "))
+}
+
+func isPunctuationOrBreak(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPunct(r) && r != '\n' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseReplacements reads "from=to" entries separated by semicolons or
+// newlines. In the replacement, \n, \t and \s stand for a line break, a tab
+// and a space, and a backslash escapes any other character such as ; or =.
+// Entries starting with # are comments.
+func ParseReplacements(spec string) ([]Replacement, error) {
+	var out []Replacement
+	for _, entry := range splitUnescaped(spec) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		from, to, ok := strings.Cut(entry, "=")
+		from = strings.TrimSpace(from)
+		if !ok || from == "" {
+			return nil, fmt.Errorf("invalid replacement %q, want phrase=text", entry)
+		}
+		out = append(out, Replacement{From: from, To: Unescape(strings.TrimSpace(to))})
+	}
+	return out, nil
+}
+
+// splitUnescaped splits at semicolons and newlines not preceded by a backslash
+func splitUnescaped(s string) []string {
+	var parts []string
+	var current strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			current.WriteByte(s[i])
+			current.WriteByte(s[i+1])
+			i++
+		case s[i] == ';' || s[i] == '\n':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(s[i])
+		}
+	}
+	return append(parts, current.String())
+}
+
+// Unescape replaces \n, \t and \s with a line break, a tab and a space;
+// a backslash before any other character stands for that character
+func Unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 's':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// DefaultFillers are hesitation sounds that carry no meaning
+var DefaultFillers = []string{"um", "umm", "uh", "uhh", "erm", "hmm"}
+
+// AggressiveFillers are hesitation sounds that also occur as words or
+// answers, such as "ah" in "ah, I see" or "er" for an emergency room
+var AggressiveFillers = []string{"er", "ah", "mm"}
+
+// RemoveFillers deletes filler words along with the comma or ellipsis the
+// model often writes after them
+func RemoveFillers(fillers []string) Processor {
+	if len(fillers) == 0 {
+		return Func(func(text string) string { return text })
+	}
+
+	alternatives := make([]string, len(fillers))
+	for i, f := range fillers {
+		alternatives[i] = phrasePattern(f)
+	}
+	re := regexp.MustCompile(`(?i)[ \t]*\b(?:` + strings.Join(alternatives, "|") + `)\b(?:\.\.\.|…|,)?`)
+
+	return Func(func(text string) string {
+		text = re.ReplaceAllString(text, "")
+		// "I think, um." leaves a comma before the full stop
+		text = commaBeforeStop.ReplaceAllString(text, "$1")
+		return strings.TrimLeft(text, " ,")
+	})
+}
+
+var commaBeforeStop = regexp.MustCompile(`,+([.!?])`)
+
+// Case is a capitalization style
+type Case string
+
+const (
+	CaseUnchanged Case = ""
+	CaseSentence  Case = "sentence"
+	CaseLower     Case = "lower"
+	CaseUpper     Case = "upper"
+)
+
+// ParseCase validates a capitalization style name
+func ParseCase(s string) (Case, error) {
+	switch c := Case(strings.ToLower(s)); c {
+	case CaseUnchanged, CaseSentence, CaseLower, CaseUpper:
+		return c, nil
+	case "none":
+		return CaseUnchanged, nil
+	}
+	return "", fmt.Errorf("unknown case %q, want sentence, lower, upper or none", s)
+}
+
+// Capitalize applies a capitalization style. Sentence case only raises the
+// first letter of each sentence and leaves the rest, such as names, alone.
+func Capitalize(c Case) Processor {
+	return Func(func(text string) string {
+		switch c {
+		case CaseLower:
+			return strings.ToLower(text)
+		case CaseUpper:
+			return strings.ToUpper(text)
+		case CaseSentence:
+			return sentenceCase(text)
+		}
+		return text
+	})
+}
+
+// sentenceCase raises the first letter of the text, of each line and of
+// each word after a sentence terminator followed by whitespace, so URLs,
+// file names and abbreviations such as "e.g." are left alone
+func sentenceCase(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+
+	start := true
+	// ended is set when the word so far closes a sentence
+	ended := false
+	var word []rune
+	for _, r := range text {
+		switch {
+		case start && unicode.IsLetter(r):
+			r = unicode.ToUpper(r)
+			start = false
+		case r == '\n':
+			start = true
+		case unicode.IsSpace(r):
+			start = start || ended
+		case r == '.' || r == '!' || r == '?':
+			ended = r != '.' || !isAbbreviation(string(word))
+		case ended && isClosing(r):
+			// A quote or bracket may close the sentence as well
+		case start && !isOpening(r):
+			// A sentence starting with a digit or symbol keeps its case
+			start = false
+		default:
+			ended = false
+		}
+
+		if unicode.IsSpace(r) {
+			word, ended = word[:0], false
+		} else {
+			word = append(word, r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// abbreviations are words that end in a period without ending a sentence.
+// "No." is left out: as an answer it ends a sentence far more often.
+var abbreviations = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true,
+	"vs": true, "approx": true, "fig": true, "jr": true, "sr": true,
+}
+
+// isAbbreviation reports whether a word followed by a period is an
+// abbreviation: a listed one, or single letters between periods as in
+// "e.g" or "U.S"
+func isAbbreviation(word string) bool {
+	word = strings.ToLower(strings.TrimLeft(word, `"'(“‘[`))
+	if abbreviations[word] {
+		return true
+	}
+	parts := strings.Split(word, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	for _, p := range parts {
+		if utf8.RuneCountInString(p) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func isOpening(r rune) bool {
+	return strings.ContainsRune(`"'(“‘[`, r)
+}
+
+func isClosing(r rune) bool {
+	return strings.ContainsRune(`"')”’]`, r)
+}
+
+// DefaultProfanity is a short list of common English swear words
+var DefaultProfanity = []string{
+	"fuck", "fucks", "fucked", "fucker", "fucking",
+	"shit", "shits", "shitty", "bullshit",
+	"bitch", "bitches", "asshole", "assholes", "bastard", "bastards",
+	"dick", "dickhead", "cunt", "damn", "goddamn", "crap", "piss", "pissed",
+}
+
+// MaskProfanity keeps the first letter of each listed word and masks the
+// rest with asterisks
+func MaskProfanity(words []string) Processor {
+	if len(words) == 0 {
+		return Func(func(text string) string { return text })
+	}
+
+	alternatives := make([]string, len(words))
+	for i, w := range words {
+		alternatives[i] = regexp.QuoteMeta(w)
+	}
+	re := regexp.MustCompile(`(?i)\b(?:` + strings.Join(alternatives, "|") + `)\b`)
+
+	return Func(func(text string) string {
+		return re.ReplaceAllStringFunc(text, func(word string) string {
+			_, size := utf8.DecodeRuneInString(word)
+			return word[:size] + strings.Repeat("*", utf8.RuneCountInString(word)-1)
+		})
+	})
+}
@@ -0,0 +1,268 @@
+package postprocess
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	upper := Func(strings.ToUpper)
+	exclaim := Func(func(s string) string { return s + "!" })
+
+	if got := (Chain{upper, exclaim}).Process("hi"); got != "HI!" {
+		t.Errorf("Chain.Process() = %q, want %q", got, "HI!")
+	}
+	if got := (Chain{}).Process("hi"); got != "hi" {
+		t.Errorf("empty Chain.Process() = %q, want %q", got, "hi")
+	}
+}
+
+func TestNormalizeWhitespace(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "trim", in: "  hello world \n", want: "hello world"},
+		{name: "collapse", in: "hello \t  world", want: "hello world"},
+		{name: "space before punctuation", in: "hello , world .", want: "hello, world."},
+		{name: "line breaks", in: "first line \n second line", want: "first line\nsecond line"},
+		{name: "brackets", in: "a ( note ) here", want: "a (note) here"},
+		{name: "repeated commas", in: "yes, , no", want: "yes, no"},
+		{name: "unchanged", in: "Already clean.", want: "Already clean."},
+	}
+
+	p := NormalizeWhitespace()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Process(tt.in); got != tt.want {
+				t.Errorf("Process(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	p := Replace([]Replacement{
+		{From: "new line", To: "\n"},
+		{From: "new paragraph", To: "\n\n"},
+		{From: "comma", To: ","},
+		{From: "gee pee tee", To: "GPT"},
+		{From: "c++", To: "C plus plus"},
+		{From: "etc.", To: "and so on"},
+	})
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "punctuation", in: "hello comma world", want: "hello , world"},
+		{name: "line break drops trailing punctuation", in: "Dear Sam, new line. Thanks", want: "Dear Sam, \n Thanks"},
+		{name: "longest phrase first", in: "end new paragraph start", want: "end \n\n start"},
+		{name: "case insensitive", in: "Comma", want: ","},
+		{name: "extra whitespace in phrase", in: "new   line", want: "\n"},
+		{name: "word keeps trailing punctuation", in: "ask gee pee tee.", want: "ask GPT."},
+		{name: "whole words only", in: "commander newline", want: "commander newline"},
+		{name: "phrase ending in a symbol", in: "I like c++ a lot", want: "I like C plus plus a lot"},
+		{name: "phrase ending in punctuation", in: "apples, pears etc. and more", want: "apples, pears and so on and more"},
+		{name: "symbol phrase inside a word", in: "abc++", want: "abc++"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Process(tt.in); got != tt.want {
+				t.Errorf("Process(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReplacements(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []Replacement
+		wantErr bool
+	}{
+		{
+			name: "semicolons",
+			spec: `new line=\n; comma = ,`,
+			want: []Replacement{{From: "new line", To: "\n"}, {From: "comma", To: ","}},
+		},
+		{
+			name: "lines and comments",
+			spec: "# dictation\nsemicolon=\\;\ntab=\\t\n\nspace=\\s\n",
+			want: []Replacement{{From: "semicolon", To: ";"}, {From: "tab", To: "\t"}, {From: "space", To: " "}},
+		},
+		{name: "empty", spec: "", want: nil},
+		{name: "missing separator", spec: "comma", wantErr: true},
+		{name: "missing phrase", spec: "=,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReplacements(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReplacements() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseReplacements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveFillers(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "So, um, I think so", want: "So, I think so"},
+		{in: "Um, hello there", want: "hello there"},
+		{in: "I was uh going", want: "I was going"},
+		{in: "I think, um.", want: "I think."},
+		{in: "Well uh... maybe", want: "Well maybe"},
+		{in: "Hmm that is UMM odd", want: "that is odd"},
+		{in: "the umbrella is here", want: "the umbrella is here"},
+		{in: "Ah, I see, take him to the er", want: "Ah, I see, take him to the er"},
+	}
+
+	p := RemoveFillers(DefaultFillers)
+	for _, tt := range tests {
+		if got := p.Process(tt.in); got != tt.want {
+			t.Errorf("Process(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCapitalize(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Case
+		in   string
+		want string
+	}{
+		{name: "sentence", c: CaseSentence, in: "hello. how are you? fine! ok", want: "Hello. How are you? Fine! Ok"},
+		{name: "sentence keeps names", c: CaseSentence, in: "ask Alice about NASA", want: "Ask Alice about NASA"},
+		{name: "sentence after line break", c: CaseSentence, in: "dear sam\nthanks", want: "Dear sam\nThanks"},
+		{name: "sentence after quote", c: CaseSentence, in: `"quoted" text`, want: `"Quoted" text`},
+		{name: "sentence starting with a number", c: CaseSentence, in: "3 apples", want: "3 apples"},
+		{name: "decimal", c: CaseSentence, in: "pi is 3.14 roughly", want: "Pi is 3.14 roughly"},
+		{name: "urls and file names", c: CaseSentence, in: "visit example.com and open main.go, e.g. this", want: "Visit example.com and open main.go, e.g. this"},
+		{name: "abbreviations", c: CaseSentence, in: "ask dr. smith i.e. the dentist. she knows", want: "Ask dr. smith i.e. the dentist. She knows"},
+		{name: "no as an answer", c: CaseSentence, in: "are you done? no. not yet", want: "Are you done? No. Not yet"},
+		{name: "url ending a sentence", c: CaseSentence, in: "see example.com. then call", want: "See example.com. Then call"},
+		{name: "no space after a terminator", c: CaseSentence, in: "wait!what? ok", want: "Wait!what? Ok"},
+		{name: "terminator inside a quote", c: CaseSentence, in: `he said "stop." then left`, want: `He said "stop." Then left`},
+		{name: "lower", c: CaseLower, in: "Hello World", want: "hello world"},
+		{name: "upper", c: CaseUpper, in: "Hello World", want: "HELLO WORLD"},
+		{name: "unchanged", c: CaseUnchanged, in: "hello World", want: "hello World"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Capitalize(tt.c).Process(tt.in); got != tt.want {
+				t.Errorf("Process(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCase(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Case
+		wantErr bool
+	}{
+		{in: "", want: CaseUnchanged},
+		{in: "none", want: CaseUnchanged},
+		{in: "Sentence", want: CaseSentence},
+		{in: "lower", want: CaseLower},
+		{in: "upper", want: CaseUpper},
+		{in: "title", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCase(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCase(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCase(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMaskProfanity(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "well shit, that broke", want: "well s***, that broke"},
+		{in: "Damn it", want: "D*** it"},
+		{in: "the shitake and Dickens", want: "the shitake and Dickens"},
+		{in: "clean text", want: "clean text"},
+	}
+
+	p := MaskProfanity(DefaultProfanity)
+	for _, tt := range tests {
+		if got := p.Process(tt.in); got != tt.want {
+			t.Errorf("Process(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	replacements, err := ParseReplacements(`new line=\n;comma=,`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		in   string
+		want string
+	}{
+		{
+			name: "nothing enabled",
+			in:   "  um, hello comma world  ",
+			want: "  um, hello comma world  ",
+		},
+		{
+			name: "everything",
+			opts: Options{
+				Replacements:        replacements,
+				RemoveFillers:       true,
+				MaskProfanity:       true,
+				NormalizeWhitespace: true,
+				Case:                CaseSentence,
+			},
+			in:   " um, hello comma damn world new line. uh, second line ",
+			want: "Hello, d*** world\nSecond line",
+		},
+		{
+			name: "custom word lists",
+			opts: Options{RemoveFillers: true, Fillers: []string{"like"}, MaskProfanity: true, ProfanityWords: []string{"heck"}},
+			in:   "it was like heck um",
+			want: "it was h*** um",
+		},
+		{
+			name: "aggressive fillers",
+			opts: Options{RemoveFillers: true, AggressiveFillers: true},
+			in:   "so er, ah, it works um",
+			want: "so it works",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.opts).Process(tt.in); got != tt.want {
+				t.Errorf("Process(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package stt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrRateLimited is matched by errors for requests rejected with 429
+	ErrRateLimited = errors.New("rate limited by transcription API")
+	// ErrAuth is matched by errors for requests rejected with 401 or 403
+	ErrAuth = errors.New("transcription API rejected credentials")
+	// ErrBadAudio is matched by errors for audio the API refused to process
+	ErrBadAudio = errors.New("transcription API rejected audio")
+)
+
+// APIError is returned when the transcription API responds with a non-200 status.
+// Use errors.Is with ErrRateLimited, ErrAuth or ErrBadAudio to classify it.
+type APIError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay requested by the server, or zero if none was sent
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// Is reports whether the status code belongs to the class named by target
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrAuth:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrBadAudio:
+		return e.StatusCode == http.StatusBadRequest ||
+			e.StatusCode == http.StatusRequestEntityTooLarge ||
+			e.StatusCode == http.StatusUnsupportedMediaType
+	}
+	return false
+}
+
+// IsRetryable reports whether a failed transcription is worth repeating:
+// rate limiting, server errors and network failures are, everything else is not.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// parseRetryAfter reads a Retry-After header given either as delay seconds or
+// as an HTTP date. It returns zero for missing or malformed values.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultWhisperBinary is the whisper.cpp command line tool looked up on PATH
+const DefaultWhisperBinary = "whisper-cli"
+
+// execCommand is swapped out in tests
+var execCommand = exec.CommandContext
+
+// LocalTranscriber runs a whisper.cpp GGML model on the CPU through the
+// whisper.cpp command line tool, so no audio leaves the machine
+type LocalTranscriber struct {
+	binary    string
+	modelPath string
+	language  string
+	prompt    string
+	threads   int
+}
+
+// NewLocalTranscriber creates a transcriber for the model at cfg.ModelPath.
+// cfg.BinaryPath defaults to whisper-cli on PATH.
+func NewLocalTranscriber(cfg TranscriberConfig) (Transcriber, error) {
+	if cfg.ModelPath == "" {
+		return nil, fmt.Errorf("model path is required for local transcription")
+	}
+	if _, err := os.Stat(cfg.ModelPath); err != nil {
+		return nil, fmt.Errorf("failed to open model: %w", err)
+	}
+
+	binary := cfg.BinaryPath
+	if binary == "" {
+		binary = DefaultWhisperBinary
+	}
+	resolved, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("whisper.cpp binary %q not found: %w", binary, err)
+	}
+
+	return &LocalTranscriber{
+		binary:    resolved,
+		modelPath: cfg.ModelPath,
+		language:  cfg.Language,
+		prompt:    cfg.Prompt,
+		threads:   cfg.Threads,
+	}, nil
+}
+
+// Transcribe writes the audio to a temporary file, named after the audio's
+// file type, and runs whisper.cpp on it
+func (l *LocalTranscriber) Transcribe(ctx context.Context, audioData io.Reader) (string, error) {
+	name, _ := audioFileInfo(audioData)
+	tmp, err := os.CreateTemp("", "speech-to-clipboard-*"+filepath.Ext(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, audioData); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write audio data: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write audio data: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := execCommand(ctx, l.binary, l.args(tmp.Name(), requestPrompt(ctx, l.prompt))...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("whisper.cpp failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return cleanLocalOutput(stdout.String()), nil
+}
+
+// args builds the whisper.cpp command line for an input file
+func (l *LocalTranscriber) args(inputPath, prompt string) []string {
+	args := []string{
+		"--model", l.modelPath,
+		"--file", inputPath,
+		"--no-timestamps",
+		"--no-prints",
+	}
+	if l.language != "" {
+		args = append(args, "--language", l.language)
+	}
+	if prompt != "" {
+		args = append(args, "--prompt", prompt)
+	}
+	if l.threads > 0 {
+		args = append(args, "--threads", strconv.Itoa(l.threads))
+	}
+	return args
+}
+
+// cleanLocalOutput joins the segment lines printed by whisper.cpp and drops
+// the markers it emits for silence
+func cleanLocalOutput(output string) string {
+	var parts []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "[BLANK_AUDIO]" {
+			continue
+		}
+		parts = append(parts, line)
+	}
+	return strings.Join(parts, " ")
+}
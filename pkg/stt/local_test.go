@@ -0,0 +1,153 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeWhisperCommand runs TestHelperProcess in place of whisper.cpp
+func fakeWhisperCommand(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmdArgs := append([]string{"-test.run=TestHelperProcess", "--"}, args...)
+	cmd := exec.CommandContext(ctx, os.Args[0], cmdArgs...)
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1")
+	return cmd
+}
+
+// TestHelperProcess is not a real test; it stands in for whisper-cli
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	args = args[1:]
+
+	flags := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		if i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+			flags[args[i]] = args[i+1]
+			i++
+		} else {
+			flags[args[i]] = ""
+		}
+	}
+
+	audio, err := os.ReadFile(flags["--file"])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot read input: %v", err)
+		os.Exit(1)
+	}
+
+	switch string(audio) {
+	case "fail":
+		fmt.Fprint(os.Stderr, "error: failed to load model")
+		os.Exit(2)
+	case "silence":
+		fmt.Println("[BLANK_AUDIO]")
+	default:
+		fmt.Printf(" lang=%s threads=%s\n", flags["--language"], flags["--threads"])
+		fmt.Printf(" model=%s\n", filepath.Base(flags["--model"]))
+	}
+	os.Exit(0)
+}
+
+func newTestLocalTranscriber(t *testing.T, cfg TranscriberConfig) Transcriber {
+	t.Helper()
+
+	original := execCommand
+	execCommand = fakeWhisperCommand
+	t.Cleanup(func() { execCommand = original })
+
+	cfg.ModelPath = filepath.Join(t.TempDir(), "ggml-base.en.bin")
+	if err := os.WriteFile(cfg.ModelPath, []byte("model"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	cfg.BinaryPath = os.Args[0]
+
+	transcriber, err := NewLocalTranscriber(cfg)
+	if err != nil {
+		t.Fatalf("NewLocalTranscriber() unexpected error = %v", err)
+	}
+	return transcriber
+}
+
+func TestLocalTranscriber_Transcribe(t *testing.T) {
+	transcriber := newTestLocalTranscriber(t, TranscriberConfig{Language: "de", Threads: 4})
+
+	got, err := transcriber.Transcribe(context.Background(), bytes.NewReader([]byte("speech")))
+	if err != nil {
+		t.Fatalf("Transcribe() unexpected error = %v", err)
+	}
+
+	want := "lang=de threads=4 model=ggml-base.en.bin"
+	if got != want {
+		t.Errorf("Transcribe() = %q, want %q", got, want)
+	}
+}
+
+func TestLocalTranscriber_BlankAudio(t *testing.T) {
+	transcriber := newTestLocalTranscriber(t, TranscriberConfig{})
+
+	got, err := transcriber.Transcribe(context.Background(), bytes.NewReader([]byte("silence")))
+	if err != nil {
+		t.Fatalf("Transcribe() unexpected error = %v", err)
+	}
+
+	if got != "" {
+		t.Errorf("Transcribe() = %q, want empty", got)
+	}
+}
+
+func TestLocalTranscriber_CommandFails(t *testing.T) {
+	transcriber := newTestLocalTranscriber(t, TranscriberConfig{})
+
+	_, err := transcriber.Transcribe(context.Background(), bytes.NewReader([]byte("fail")))
+	if err == nil {
+		t.Fatal("Transcribe() expected error, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "failed to load model") {
+		t.Errorf("Transcribe() error = %v, want stderr included", err)
+	}
+}
+
+func TestNewLocalTranscriber_Validation(t *testing.T) {
+	model := filepath.Join(t.TempDir(), "model.bin")
+	if err := os.WriteFile(model, []byte("model"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  TranscriberConfig
+	}{
+		{name: "missing model path", cfg: TranscriberConfig{BinaryPath: os.Args[0]}},
+		{name: "model does not exist", cfg: TranscriberConfig{ModelPath: model + ".missing", BinaryPath: os.Args[0]}},
+		{name: "binary not found", cfg: TranscriberConfig{ModelPath: model, BinaryPath: "no-such-whisper-binary"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLocalTranscriber(tt.cfg); err == nil {
+				t.Error("NewLocalTranscriber() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestCleanLocalOutput(t *testing.T) {
+	got := cleanLocalOutput(" Hello there.\n\n General Kenobi.\n[BLANK_AUDIO]\n")
+	want := "Hello there. General Kenobi."
+	if got != want {
+		t.Errorf("cleanLocalOutput() = %q, want %q", got, want)
+	}
+}
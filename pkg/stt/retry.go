@@ -0,0 +1,117 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+)
+
+// RetryConfig controls how a retrying transcriber backs off between attempts
+type RetryConfig struct {
+	// MaxRetries is the number of attempts made after the first one fails
+	MaxRetries int
+	// BaseDelay is the backoff ceiling for the first retry; it doubles per attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff ceiling
+	MaxDelay time.Duration
+}
+
+// DefaultRetryConfig returns the retry settings used by the application
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+	}
+}
+
+type retryingTranscriber struct {
+	next   Transcriber
+	cfg    RetryConfig
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func(max time.Duration) time.Duration
+}
+
+// NewRetryingTranscriber wraps a transcriber so that retryable failures (see
+// IsRetryable) are repeated with jittered exponential backoff. A Retry-After
+// delay from the server takes precedence over the computed backoff, and no
+// retry is attempted if its delay would run past the context deadline.
+func NewRetryingTranscriber(next Transcriber, cfg RetryConfig) Transcriber {
+	return &retryingTranscriber{
+		next:   next,
+		cfg:    cfg,
+		sleep:  sleepContext,
+		jitter: fullJitter,
+	}
+}
+
+// Transcribe buffers the audio so each attempt can replay it, then calls the
+// wrapped transcriber until it succeeds, fails permanently or retries run out.
+func (r *retryingTranscriber) Transcribe(ctx context.Context, audioData io.Reader) (string, error) {
+	audio, err := io.ReadAll(audioData)
+	if err != nil {
+		return "", fmt.Errorf("failed to read audio data: %w", err)
+	}
+	name, contentType := audioFileInfo(audioData)
+
+	for attempt := 0; ; attempt++ {
+		text, err := r.next.Transcribe(ctx, NewAudioFile(bytes.NewReader(audio), name, contentType))
+		if err == nil {
+			return text, nil
+		}
+
+		if !IsRetryable(err) || attempt >= r.cfg.MaxRetries {
+			return "", err
+		}
+
+		delay := r.backoff(attempt, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return "", fmt.Errorf("giving up after %d attempts, next retry would exceed deadline: %w", attempt+1, err)
+		}
+
+		if sleepErr := r.sleep(ctx, delay); sleepErr != nil {
+			return "", fmt.Errorf("%w (last error: %w)", sleepErr, err)
+		}
+	}
+}
+
+// backoff returns the delay before the retry following the given attempt
+func (r *retryingTranscriber) backoff(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	ceiling := r.cfg.BaseDelay
+	for i := 0; i < attempt && ceiling < r.cfg.MaxDelay; i++ {
+		ceiling *= 2
+	}
+	if r.cfg.MaxDelay > 0 && ceiling > r.cfg.MaxDelay {
+		ceiling = r.cfg.MaxDelay
+	}
+	return r.jitter(ceiling)
+}
+
+// fullJitter picks a uniformly random delay in [0, max]
+func fullJitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
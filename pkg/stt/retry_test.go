@@ -0,0 +1,262 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// sequenceTranscriber returns the queued errors in order, then succeeds
+type sequenceTranscriber struct {
+	errs   []error
+	calls  int
+	audios []string
+	names  []string
+}
+
+func (s *sequenceTranscriber) Transcribe(ctx context.Context, audioData io.Reader) (string, error) {
+	data, _ := io.ReadAll(audioData)
+	s.audios = append(s.audios, string(data))
+	name, _ := audioFileInfo(audioData)
+	s.names = append(s.names, name)
+	s.calls++
+	if s.calls <= len(s.errs) {
+		return "", s.errs[s.calls-1]
+	}
+	return "done", nil
+}
+
+func newTestRetrier(next Transcriber, maxRetries int) (*retryingTranscriber, *[]time.Duration) {
+	var delays []time.Duration
+	r := NewRetryingTranscriber(next, RetryConfig{
+		MaxRetries: maxRetries,
+		BaseDelay:  100 * time.Millisecond,
+		MaxDelay:   300 * time.Millisecond,
+	}).(*retryingTranscriber)
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	r.jitter = func(max time.Duration) time.Duration { return max }
+	return r, &delays
+}
+
+func TestRetryingTranscriber_RetriesRetryableErrors(t *testing.T) {
+	next := &sequenceTranscriber{errs: []error{
+		&APIError{StatusCode: http.StatusServiceUnavailable},
+		&net.OpError{Op: "dial", Err: errors.New("connection refused")},
+		&APIError{StatusCode: http.StatusTooManyRequests},
+	}}
+	r, delays := newTestRetrier(next, 3)
+
+	got, err := r.Transcribe(context.Background(), bytes.NewReader([]byte("audio")))
+	if err != nil {
+		t.Fatalf("Transcribe() unexpected error = %v", err)
+	}
+
+	if got != "done" {
+		t.Errorf("Transcribe() = %v, want %v", got, "done")
+	}
+
+	if next.calls != 4 {
+		t.Errorf("calls = %d, want 4", next.calls)
+	}
+
+	for i, audio := range next.audios {
+		if audio != "audio" {
+			t.Errorf("attempt %d audio = %q, want replayed %q", i, audio, "audio")
+		}
+	}
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	if fmt.Sprint(*delays) != fmt.Sprint(want) {
+		t.Errorf("delays = %v, want %v", *delays, want)
+	}
+}
+
+func TestRetryingTranscriber_KeepsFileLabels(t *testing.T) {
+	next := &sequenceTranscriber{errs: []error{&APIError{StatusCode: 503}}}
+	r, _ := newTestRetrier(next, 3)
+
+	audio := NewAudioFile(bytes.NewReader([]byte("flac bytes")), "audio.flac", "audio/flac")
+	if _, err := r.Transcribe(context.Background(), audio); err != nil {
+		t.Fatalf("Transcribe() unexpected error = %v", err)
+	}
+
+	for i, name := range next.names {
+		if name != "audio.flac" {
+			t.Errorf("attempt %d file name = %v, want %v", i, name, "audio.flac")
+		}
+	}
+}
+
+func TestRetryingTranscriber_PermanentErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{name: "unauthorized", err: &APIError{StatusCode: http.StatusUnauthorized}, target: ErrAuth},
+		{name: "forbidden", err: &APIError{StatusCode: http.StatusForbidden}, target: ErrAuth},
+		{name: "bad audio", err: &APIError{StatusCode: http.StatusBadRequest}, target: ErrBadAudio},
+		{name: "too large", err: &APIError{StatusCode: http.StatusRequestEntityTooLarge}, target: ErrBadAudio},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &sequenceTranscriber{errs: []error{tt.err}}
+			r, _ := newTestRetrier(next, 3)
+
+			_, err := r.Transcribe(context.Background(), bytes.NewReader([]byte("audio")))
+			if !errors.Is(err, tt.target) {
+				t.Errorf("Transcribe() error = %v, want errors.Is %v", err, tt.target)
+			}
+
+			if next.calls != 1 {
+				t.Errorf("calls = %d, want 1", next.calls)
+			}
+		})
+	}
+}
+
+func TestRetryingTranscriber_GivesUp(t *testing.T) {
+	rateLimited := &APIError{StatusCode: http.StatusTooManyRequests}
+	next := &sequenceTranscriber{errs: []error{rateLimited, rateLimited, rateLimited}}
+	r, _ := newTestRetrier(next, 2)
+
+	_, err := r.Transcribe(context.Background(), bytes.NewReader([]byte("audio")))
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Transcribe() error = %v, want ErrRateLimited", err)
+	}
+
+	if next.calls != 3 {
+		t.Errorf("calls = %d, want 3", next.calls)
+	}
+}
+
+func TestRetryingTranscriber_RetryAfter(t *testing.T) {
+	next := &sequenceTranscriber{errs: []error{
+		&APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 2 * time.Second},
+	}}
+	r, delays := newTestRetrier(next, 3)
+
+	if _, err := r.Transcribe(context.Background(), bytes.NewReader([]byte("audio"))); err != nil {
+		t.Fatalf("Transcribe() unexpected error = %v", err)
+	}
+
+	if len(*delays) != 1 || (*delays)[0] != 2*time.Second {
+		t.Errorf("delays = %v, want [2s]", *delays)
+	}
+}
+
+func TestRetryingTranscriber_RespectsDeadline(t *testing.T) {
+	next := &sequenceTranscriber{errs: []error{
+		&APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute},
+	}}
+	r, delays := newTestRetrier(next, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := r.Transcribe(ctx, bytes.NewReader([]byte("audio")))
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Transcribe() error = %v, want ErrRateLimited", err)
+	}
+
+	if len(*delays) != 0 {
+		t.Errorf("slept %v past the deadline", *delays)
+	}
+}
+
+func TestRetryingTranscriber_WhisperRetryAfterHeader(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", strconv.Itoa(0))
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		if attempts == 2 {
+			http.Error(w, "overloaded", http.StatusBadGateway)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("ParseMultipartForm() error = %v", err)
+		}
+		_, _ = w.Write([]byte(`{"text":"recovered"}`))
+	}))
+	defer server.Close()
+
+	r := NewRetryingTranscriber(
+		NewWhisperTranscriberWithConfig(TranscriberConfig{BaseURL: server.URL}),
+		RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+	)
+
+	got, err := r.Transcribe(context.Background(), bytes.NewReader([]byte("audio")))
+	if err != nil {
+		t.Fatalf("Transcribe() unexpected error = %v", err)
+	}
+
+	if got != "recovered" {
+		t.Errorf("Transcribe() = %v, want %v", got, "recovered")
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "rate limited", err: &APIError{StatusCode: 429}, want: true},
+		{name: "server error", err: fmt.Errorf("wrapped: %w", &APIError{StatusCode: 502}), want: true},
+		{name: "bad request", err: &APIError{StatusCode: 400}, want: false},
+		{name: "network", err: &net.OpError{Op: "read", Err: errors.New("reset")}, want: true},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "deadline", err: fmt.Errorf("send: %w", context.DeadlineExceeded), want: false},
+		{name: "other", err: errors.New("boom"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "empty", value: "", want: 0},
+		{name: "seconds", value: "7", want: 7 * time.Second},
+		{name: "negative", value: "-1", want: 0},
+		{name: "http date", value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second},
+		{name: "past date", value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0},
+		{name: "garbage", value: "soon", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+
+	"speech-to-clipboard/pkg/audio/wav"
 )
 
 // Transcriber converts audio data to text
@@ -12,11 +14,96 @@ type Transcriber interface {
 	Transcribe(ctx context.Context, audioData io.Reader) (string, error)
 }
 
+// AudioFile is audio that knows its file name and MIME type. Transcribers
+// that upload audio label it with these; plain readers are treated as WAV.
+type AudioFile interface {
+	io.Reader
+	Name() string
+	ContentType() string
+}
+
+// NewAudioFile labels audio data with a file name and MIME type
+func NewAudioFile(r io.Reader, name, contentType string) AudioFile {
+	return &audioFile{Reader: r, name: name, contentType: contentType}
+}
+
+type audioFile struct {
+	io.Reader
+	name        string
+	contentType string
+}
+
+func (f *audioFile) Name() string        { return f.name }
+func (f *audioFile) ContentType() string { return f.contentType }
+
+// audioFileInfo returns the file name and MIME type of audio data, falling
+// back to WAV for readers that carry neither
+func audioFileInfo(r io.Reader) (name, contentType string) {
+	if f, ok := r.(AudioFile); ok {
+		return f.Name(), f.ContentType()
+	}
+	return "audio.wav", "audio/wav"
+}
+
+type promptKey struct{}
+
+// WithPrompt returns a context carrying prompt text for a single request,
+// such as the end of the previous segment's transcript. Transcribers append
+// it to their configured prompt.
+func WithPrompt(ctx context.Context, prompt string) context.Context {
+	return context.WithValue(ctx, promptKey{}, prompt)
+}
+
+// PromptFromContext returns the prompt set with WithPrompt, if any
+func PromptFromContext(ctx context.Context) string {
+	prompt, _ := ctx.Value(promptKey{}).(string)
+	return prompt
+}
+
+// requestPrompt combines a configured prompt with the one carried by ctx
+func requestPrompt(ctx context.Context, configured string) string {
+	extra := PromptFromContext(ctx)
+	switch {
+	case extra == "":
+		return configured
+	case configured == "":
+		return extra
+	}
+	return configured + " " + extra
+}
+
 // TranscriberConfig holds configuration for transcription services
 type TranscriberConfig struct {
 	APIKey   string
 	Model    string
 	Language string
+
+	// Prompt is optional text that guides the model's style or vocabulary
+	Prompt string
+	// Temperature is the sampling temperature between 0 and 1; 0 uses the API default
+	Temperature float64
+	// ResponseFormat is one of json, text, srt, verbose_json or vtt; empty means json
+	ResponseFormat string
+	// BaseURL is the API root, e.g. https://api.openai.com/v1
+	BaseURL string
+
+	// Organization and Project are sent as OpenAI-Organization and OpenAI-Project headers
+	Organization string
+	Project      string
+	// Headers are extra HTTP headers added to every request
+	Headers map[string]string
+
+	// AzureDeployment switches to the Azure OpenAI URL layout and api-key header
+	AzureDeployment string
+	// APIVersion is the api-version query parameter required by Azure OpenAI
+	APIVersion string
+
+	// ModelPath is the GGML model file used by the local whisper.cpp backend
+	ModelPath string
+	// BinaryPath is the whisper.cpp command line tool; empty looks up whisper-cli
+	BinaryPath string
+	// Threads is the number of CPU threads for local transcription; 0 lets whisper.cpp decide
+	Threads int
 }
 
 // MockTranscriber is a mock implementation for testing
@@ -41,20 +128,21 @@ func (m *MockTranscriber) Transcribe(ctx context.Context, audioData io.Reader) (
 	return m.Response, nil
 }
 
-// ProcessAudioBuffer is a helper function that converts int16 audio to a format ready for transcription
+// ProcessAudioBuffer encodes 16 kHz mono 16-bit audio as a WAV file ready
+// for transcription
 func ProcessAudioBuffer(data []int16) (*bytes.Buffer, error) {
 	if len(data) == 0 {
 		return nil, fmt.Errorf("empty audio data")
 	}
 
-	buf := new(bytes.Buffer)
-
-	// Write WAV header (simplified for demonstration)
-	// In production, you'd use a proper WAV encoder
-	for _, sample := range data {
-		buf.WriteByte(byte(sample))
-		buf.WriteByte(byte(sample >> 8))
+	f, err := wav.NewInt16(data, 16000, 1, wav.PCM16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audio: %w", err)
 	}
 
+	buf := new(bytes.Buffer)
+	if err := f.Encode(buf); err != nil {
+		return nil, fmt.Errorf("failed to encode audio: %w", err)
+	}
 	return buf, nil
 }
@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
+
+	"speech-to-clipboard/pkg/audio/wav"
 )
 
 func TestMockTranscriber_Transcribe(t *testing.T) {
@@ -77,6 +80,32 @@ func TestMockTranscriber_WithContext(t *testing.T) {
 	}
 }
 
+func TestRequestPrompt(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		request    string
+		want       string
+	}{
+		{name: "neither", want: ""},
+		{name: "configured only", configured: "Kubernetes, gRPC", want: "Kubernetes, gRPC"},
+		{name: "request only", request: "and then we", want: "and then we"},
+		{name: "both", configured: "Kubernetes, gRPC", request: "and then we", want: "Kubernetes, gRPC and then we"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.request != "" {
+				ctx = WithPrompt(ctx, tt.request)
+			}
+			if got := requestPrompt(ctx, tt.configured); got != tt.want {
+				t.Errorf("requestPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestProcessAudioBuffer(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -112,6 +141,21 @@ func TestProcessAudioBuffer(t *testing.T) {
 			if !tt.wantErr && buf.Len() == 0 {
 				t.Error("ProcessAudioBuffer() returned empty buffer")
 			}
+			if tt.wantErr {
+				return
+			}
+
+			f, err := wav.Decode(buf)
+			if err != nil {
+				t.Fatalf("ProcessAudioBuffer() did not produce a WAV file: %v", err)
+			}
+			want := wav.Format{SampleRate: 16000, Channels: 1, Encoding: wav.PCM16}
+			if f.Format != want {
+				t.Errorf("ProcessAudioBuffer() format = %+v, want %+v", f.Format, want)
+			}
+			if !reflect.DeepEqual(f.Int16(), tt.data) {
+				t.Errorf("ProcessAudioBuffer() samples = %v, want %v", f.Int16(), tt.data)
+			}
 		})
 	}
 }
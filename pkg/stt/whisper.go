@@ -8,24 +8,96 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the OpenAI API root used when no base URL is configured
+	DefaultBaseURL = "https://api.openai.com/v1"
+	// DefaultModel is the Whisper model used when no model is configured
+	DefaultModel = "whisper-1"
+	// DefaultAzureAPIVersion is the api-version used for Azure deployments
+	DefaultAzureAPIVersion = "2024-06-01"
 )
 
 // WhisperTranscriber uses OpenAI's Whisper API
 type WhisperTranscriber struct {
-	apiKey string
-	model  string
-	client *http.Client
+	model          string
+	language       string
+	prompt         string
+	temperature    float64
+	responseFormat string
+	endpoint       string
+	headers        http.Header
+	client         *http.Client
 }
 
 // NewWhisperTranscriber creates a new Whisper API transcriber
 func NewWhisperTranscriber(apiKey string) Transcriber {
+	return NewWhisperTranscriberWithConfig(TranscriberConfig{APIKey: apiKey})
+}
+
+// NewWhisperTranscriberWithConfig creates a Whisper API transcriber from a full
+// configuration. Empty fields fall back to the API defaults.
+func NewWhisperTranscriberWithConfig(cfg TranscriberConfig) Transcriber {
+	model := cfg.Model
+	if model == "" {
+		model = DefaultModel
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	baseURL = strings.TrimRight(baseURL, "/")
+	endpoint := baseURL + "/audio/transcriptions"
+	headers := http.Header{}
+
+	if cfg.AzureDeployment != "" {
+		apiVersion := cfg.APIVersion
+		if apiVersion == "" {
+			apiVersion = DefaultAzureAPIVersion
+		}
+		endpoint = fmt.Sprintf("%s/openai/deployments/%s/audio/transcriptions?api-version=%s",
+			baseURL, url.PathEscape(cfg.AzureDeployment), url.QueryEscape(apiVersion))
+		if cfg.APIKey != "" {
+			headers.Set("api-key", cfg.APIKey)
+		}
+	} else if cfg.APIKey != "" {
+		headers.Set("Authorization", "Bearer "+cfg.APIKey)
+	}
+
+	if cfg.Organization != "" {
+		headers.Set("OpenAI-Organization", cfg.Organization)
+	}
+	if cfg.Project != "" {
+		headers.Set("OpenAI-Project", cfg.Project)
+	}
+	for key, value := range cfg.Headers {
+		headers.Set(key, value)
+	}
+
 	return &WhisperTranscriber{
-		apiKey: apiKey,
-		model:  "whisper-1",
-		client: &http.Client{},
+		model:          model,
+		language:       cfg.Language,
+		prompt:         cfg.Prompt,
+		temperature:    cfg.Temperature,
+		responseFormat: cfg.ResponseFormat,
+		endpoint:       endpoint,
+		headers:        headers,
+		client:         &http.Client{},
 	}
 }
 
+// quoteEscaper escapes a file name for a Content-Disposition header, as
+// multipart.Writer.CreateFormFile does
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
 type whisperResponse struct {
 	Text string `json:"text"`
 }
@@ -35,8 +107,12 @@ func (w *WhisperTranscriber) Transcribe(ctx context.Context, audioData io.Reader
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
 
-	// Add file field
-	part, err := writer.CreateFormFile("file", "audio.wav")
+	// Add file field, labelled so the API can tell the encoding apart
+	name, contentType := audioFileInfo(audioData)
+	fileHeader := make(textproto.MIMEHeader)
+	fileHeader.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(name)))
+	fileHeader.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(fileHeader)
 	if err != nil {
 		return "", fmt.Errorf("failed to create form file: %w", err)
 	}
@@ -45,9 +121,25 @@ func (w *WhisperTranscriber) Transcribe(ctx context.Context, audioData io.Reader
 		return "", fmt.Errorf("failed to copy audio data: %w", err)
 	}
 
-	// Add model field
-	if err := writer.WriteField("model", w.model); err != nil {
-		return "", fmt.Errorf("failed to write model field: %w", err)
+	// Add model and optional fields; empty values are left to the API defaults
+	temperature := ""
+	if w.temperature != 0 {
+		temperature = strconv.FormatFloat(w.temperature, 'f', -1, 64)
+	}
+	fields := [][2]string{
+		{"model", w.model},
+		{"language", w.language},
+		{"prompt", requestPrompt(ctx, w.prompt)},
+		{"temperature", temperature},
+		{"response_format", w.responseFormat},
+	}
+	for _, f := range fields {
+		if f[1] == "" {
+			continue
+		}
+		if err := writer.WriteField(f[0], f[1]); err != nil {
+			return "", fmt.Errorf("failed to write %s field: %w", f[0], err)
+		}
 	}
 
 	if err := writer.Close(); err != nil {
@@ -55,12 +147,14 @@ func (w *WhisperTranscriber) Transcribe(ctx context.Context, audioData io.Reader
 	}
 
 	// Create request
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/audio/transcriptions", body)
+	req, err := http.NewRequestWithContext(ctx, "POST", w.endpoint, body)
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+w.apiKey)
+	for key, values := range w.headers {
+		req.Header[key] = values
+	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	// Send request
@@ -72,14 +166,32 @@ func (w *WhisperTranscriber) Transcribe(ctx context.Context, audioData io.Reader
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return "", &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       string(bodyBytes),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
-	// Parse response
-	var result whisperResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
-	}
+	return parseResponse(resp.Body, w.responseFormat)
+}
 
-	return result.Text, nil
+// parseResponse extracts the transcript from a response body. The json and
+// verbose_json formats wrap the text in an object; text, srt and vtt return
+// the body as-is.
+func parseResponse(body io.Reader, format string) (string, error) {
+	switch format {
+	case "", "json", "verbose_json":
+		var result whisperResponse
+		if err := json.NewDecoder(body).Decode(&result); err != nil {
+			return "", fmt.Errorf("failed to decode response: %w", err)
+		}
+		return result.Text, nil
+	default:
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			return "", fmt.Errorf("failed to read response: %w", err)
+		}
+		return strings.TrimSpace(string(raw)), nil
+	}
 }
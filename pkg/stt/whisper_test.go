@@ -0,0 +1,340 @@
+package stt
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWhisperTranscriber_SendsConfig(t *testing.T) {
+	var gotPath, gotAuth string
+	gotFields := map[string]string{}
+	var gotFile []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("ParseMultipartForm() error = %v", err)
+			return
+		}
+		for key, values := range r.MultipartForm.Value {
+			gotFields[key] = values[0]
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("FormFile() error = %v", err)
+			return
+		}
+		defer file.Close()
+		buf := new(bytes.Buffer)
+		_, _ = buf.ReadFrom(file)
+		gotFile = buf.Bytes()
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"text":"hola mundo"}`))
+	}))
+	defer server.Close()
+
+	transcriber := NewWhisperTranscriberWithConfig(TranscriberConfig{
+		APIKey:      "test-key",
+		Model:       "custom-model",
+		Language:    "es",
+		Prompt:      "Saludos",
+		Temperature: 0.3,
+		BaseURL:     server.URL + "/v1/",
+	})
+
+	got, err := transcriber.Transcribe(context.Background(), bytes.NewReader([]byte("fake audio data")))
+	if err != nil {
+		t.Fatalf("Transcribe() unexpected error = %v", err)
+	}
+
+	if got != "hola mundo" {
+		t.Errorf("Transcribe() = %v, want %v", got, "hola mundo")
+	}
+
+	if gotPath != "/v1/audio/transcriptions" {
+		t.Errorf("request path = %v, want %v", gotPath, "/v1/audio/transcriptions")
+	}
+
+	if gotAuth != "Bearer test-key" {
+		t.Errorf("Authorization = %v, want %v", gotAuth, "Bearer test-key")
+	}
+
+	wantFields := map[string]string{
+		"model":       "custom-model",
+		"language":    "es",
+		"prompt":      "Saludos",
+		"temperature": "0.3",
+	}
+	for key, want := range wantFields {
+		if gotFields[key] != want {
+			t.Errorf("field %s = %q, want %q", key, gotFields[key], want)
+		}
+	}
+
+	if _, ok := gotFields["response_format"]; ok {
+		t.Errorf("response_format sent without being configured")
+	}
+
+	if string(gotFile) != "fake audio data" {
+		t.Errorf("file = %q, want %q", gotFile, "fake audio data")
+	}
+}
+
+func TestWhisperTranscriber_FileLabels(t *testing.T) {
+	tests := []struct {
+		name            string
+		audio           func() io.Reader
+		wantFilename    string
+		wantContentType string
+	}{
+		{
+			name:            "plain reader is sent as wav",
+			audio:           func() io.Reader { return bytes.NewReader([]byte("audio")) },
+			wantFilename:    "audio.wav",
+			wantContentType: "audio/wav",
+		},
+		{
+			name:            "labelled audio keeps its name and type",
+			audio:           func() io.Reader { return NewAudioFile(bytes.NewReader([]byte("audio")), "audio.flac", "audio/flac") },
+			wantFilename:    "audio.flac",
+			wantContentType: "audio/flac",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotFilename, gotContentType string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, header, err := r.FormFile("file")
+				if err != nil {
+					t.Errorf("FormFile() error = %v", err)
+				} else {
+					gotFilename = header.Filename
+					gotContentType = header.Header.Get("Content-Type")
+				}
+				_, _ = w.Write([]byte(`{"text":"ok"}`))
+			}))
+			defer server.Close()
+
+			transcriber := NewWhisperTranscriberWithConfig(TranscriberConfig{BaseURL: server.URL})
+			if _, err := transcriber.Transcribe(context.Background(), tt.audio()); err != nil {
+				t.Fatalf("Transcribe() unexpected error = %v", err)
+			}
+
+			if gotFilename != tt.wantFilename {
+				t.Errorf("filename = %v, want %v", gotFilename, tt.wantFilename)
+			}
+			if gotContentType != tt.wantContentType {
+				t.Errorf("Content-Type = %v, want %v", gotContentType, tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestWhisperTranscriber_RequestPrompt(t *testing.T) {
+	var gotPrompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPrompt = r.FormValue("prompt")
+		_, _ = w.Write([]byte(`{"text":"ok"}`))
+	}))
+	defer server.Close()
+
+	transcriber := NewWhisperTranscriberWithConfig(TranscriberConfig{BaseURL: server.URL, Prompt: "Glossary: gRPC."})
+	ctx := WithPrompt(context.Background(), "the previous sentence")
+	if _, err := transcriber.Transcribe(ctx, bytes.NewReader([]byte("audio"))); err != nil {
+		t.Fatalf("Transcribe() unexpected error = %v", err)
+	}
+
+	if want := "Glossary: gRPC. the previous sentence"; gotPrompt != want {
+		t.Errorf("prompt = %q, want %q", gotPrompt, want)
+	}
+}
+
+func TestWhisperTranscriber_DefaultModel(t *testing.T) {
+	var gotModel string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotModel = r.FormValue("model")
+		_, _ = w.Write([]byte(`{"text":"ok"}`))
+	}))
+	defer server.Close()
+
+	transcriber := NewWhisperTranscriberWithConfig(TranscriberConfig{BaseURL: server.URL})
+	if _, err := transcriber.Transcribe(context.Background(), bytes.NewReader([]byte("audio"))); err != nil {
+		t.Fatalf("Transcribe() unexpected error = %v", err)
+	}
+
+	if gotModel != DefaultModel {
+		t.Errorf("model = %v, want %v", gotModel, DefaultModel)
+	}
+}
+
+func TestWhisperTranscriber_Endpoints(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         TranscriberConfig
+		wantPath    string
+		wantQuery   string
+		wantHeaders map[string]string
+		noHeaders   []string
+	}{
+		{
+			name:     "openai with organization and project",
+			cfg:      TranscriberConfig{APIKey: "sk-test", Organization: "org-1", Project: "proj-1"},
+			wantPath: "/audio/transcriptions",
+			wantHeaders: map[string]string{
+				"Authorization":       "Bearer sk-test",
+				"OpenAI-Organization": "org-1",
+				"OpenAI-Project":      "proj-1",
+			},
+		},
+		{
+			name:     "custom headers",
+			cfg:      TranscriberConfig{APIKey: "sk-test", Headers: map[string]string{"X-Team": "audio"}},
+			wantPath: "/audio/transcriptions",
+			wantHeaders: map[string]string{
+				"Authorization": "Bearer sk-test",
+				"X-Team":        "audio",
+			},
+			noHeaders: []string{"OpenAI-Organization", "OpenAI-Project"},
+		},
+		{
+			name:        "self-hosted without key",
+			cfg:         TranscriberConfig{},
+			wantPath:    "/audio/transcriptions",
+			wantHeaders: map[string]string{},
+			noHeaders:   []string{"Authorization", "api-key"},
+		},
+		{
+			name:      "azure deployment",
+			cfg:       TranscriberConfig{APIKey: "azure-key", AzureDeployment: "my whisper"},
+			wantPath:  "/openai/deployments/my whisper/audio/transcriptions",
+			wantQuery: "api-version=" + DefaultAzureAPIVersion,
+			wantHeaders: map[string]string{
+				"api-key": "azure-key",
+			},
+			noHeaders: []string{"Authorization"},
+		},
+		{
+			name:      "azure explicit api version",
+			cfg:       TranscriberConfig{APIKey: "azure-key", AzureDeployment: "whisper", APIVersion: "2025-01-01"},
+			wantPath:  "/openai/deployments/whisper/audio/transcriptions",
+			wantQuery: "api-version=2025-01-01",
+			wantHeaders: map[string]string{
+				"api-key": "azure-key",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				_, _ = w.Write([]byte(`{"text":"ok"}`))
+			}))
+			defer server.Close()
+
+			tt.cfg.BaseURL = server.URL
+			transcriber := NewWhisperTranscriberWithConfig(tt.cfg)
+			if _, err := transcriber.Transcribe(context.Background(), bytes.NewReader([]byte("audio"))); err != nil {
+				t.Fatalf("Transcribe() unexpected error = %v", err)
+			}
+
+			if got.URL.Path != tt.wantPath {
+				t.Errorf("path = %v, want %v", got.URL.Path, tt.wantPath)
+			}
+
+			if got.URL.RawQuery != tt.wantQuery {
+				t.Errorf("query = %v, want %v", got.URL.RawQuery, tt.wantQuery)
+			}
+
+			for key, want := range tt.wantHeaders {
+				if got.Header.Get(key) != want {
+					t.Errorf("header %s = %q, want %q", key, got.Header.Get(key), want)
+				}
+			}
+
+			for _, key := range tt.noHeaders {
+				if got.Header.Get(key) != "" {
+					t.Errorf("header %s = %q, want it unset", key, got.Header.Get(key))
+				}
+			}
+
+			if !strings.HasPrefix(got.Header.Get("Content-Type"), "multipart/form-data; boundary=") {
+				t.Errorf("Content-Type = %v, want multipart/form-data", got.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestWhisperTranscriber_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid file format"}}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	transcriber := NewWhisperTranscriberWithConfig(TranscriberConfig{BaseURL: server.URL})
+	_, err := transcriber.Transcribe(context.Background(), bytes.NewReader([]byte("audio")))
+	if err == nil {
+		t.Fatal("Transcribe() expected error, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "invalid file format") {
+		t.Errorf("Transcribe() error = %v, want status and body", err)
+	}
+}
+
+func TestWhisperTranscriber_ContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	transcriber := NewWhisperTranscriberWithConfig(TranscriberConfig{BaseURL: server.URL})
+	if _, err := transcriber.Transcribe(ctx, bytes.NewReader([]byte("audio"))); err == nil {
+		t.Fatal("Transcribe() expected error after deadline, got nil")
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{name: "default json", format: "", body: `{"text":"hello"}`, want: "hello"},
+		{name: "verbose json", format: "verbose_json", body: `{"text":"hello","segments":[]}`, want: "hello"},
+		{name: "plain text", format: "text", body: "hello\n", want: "hello"},
+		{name: "subtitles", format: "srt", body: "1\n00:00:00,000 --> 00:00:01,000\nhello\n", want: "1\n00:00:00,000 --> 00:00:01,000\nhello"},
+		{name: "invalid json", format: "json", body: "not json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseResponse(bytes.NewReader([]byte(tt.body)), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseResponse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("parseResponse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}